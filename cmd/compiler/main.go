@@ -2,37 +2,364 @@ package main
 
 import (
 	stdcontext "context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/module/diff"
+	"github.com/iskorotkov/compiler/internal/module/fixer"
+	"github.com/iskorotkov/compiler/internal/module/formatter"
+	"github.com/iskorotkov/compiler/internal/module/lsp"
 	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/report"
 	"github.com/iskorotkov/compiler/internal/module/scanner"
 	"github.com/iskorotkov/compiler/internal/module/syntax_analyzer"
 	"github.com/iskorotkov/compiler/internal/module/typechecker"
+	"github.com/iskorotkov/compiler/internal/module/vm"
 	"github.com/iskorotkov/compiler/internal/module/wasm"
 )
 
+// Exit codes let scripts and Makefiles distinguish errors in programs from wrong usage of the compiler.
+const (
+	// exitErrors means that the program has errors (compile errors, syntax errors or runtime errors).
+	exitErrors = 1
+	// exitUsage means that the compiler was called with wrong arguments or files couldn't be read or written.
+	exitUsage = 2
+)
+
+const usage = `Usage: compiler <command> [flags] [file]
+
+Commands:
+  build   compile the program to a WASM module
+  check   check the program for errors without generating code
+  run     compile the program and execute it with the built-in VM
+  emit    print the result of a compilation stage: tokens, ast, symbols, wat or wasm
+  fix     apply suggested fixes of misspelled keywords and names to the source file
+  fmt     format programs
+  lsp     run the language server over stdin and stdout
+
+Programs are read from stdin if the file isn't specified, and diagnostics are written to stderr.
+Run 'compiler <command> -h' to see flags of the command.
+`
+
+// Stages of the compilation which can be emitted.
+const (
+	stageTokens  = "tokens"
+	stageAST     = "ast"
+	stageSymbols = "symbols"
+	stageWAT     = "wat"
+	stageWASM    = "wasm"
+)
+
 func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+
+	switch command {
+	case "build":
+		build(args)
+	case "check":
+		check(args)
+	case "run":
+		run(args)
+	case "emit":
+		emit(args)
+	case "fix":
+		fix(args)
+	case "fmt":
+		formatFiles(args)
+	case "lsp":
+		// In LSP mode stdin and stdout are used by the protocol.
+		err := lsp.New().Serve(os.Stdin, os.Stdout)
+		switch {
+		case errors.Is(err, lsp.ErrExitWithoutShutdown):
+			os.Exit(exitErrors)
+		case err != nil:
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(exitUsage)
+	}
+}
+
+// newFlags returns flags of the command with the flag of the diagnostics format, as all commands which compile programs report diagnostics.
+func newFlags(command, args string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("compiler "+command, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: compiler %s [flags] %s\n\nFlags:\n", command, args)
+		flags.PrintDefaults()
+	}
+
+	diagnostics := flags.String("diagnostics", string(report.FormatText), "format of diagnostics: text, json or sarif")
+
+	return flags, diagnostics
+}
+
+// newChecksFlag adds the flag disabling runtime checks of the generated code.
+func newChecksFlag(flags *flag.FlagSet) *bool {
+	return flags.Bool("no-checks", false, "disable runtime checks of array bounds and subrange values")
+}
+
+// parseFlags parses flags of the command and returns the format of diagnostics and the source file.
+// The source file is empty if the program is read from stdin.
+func parseFlags(flags *flag.FlagSet, diagnostics *string, args []string) (report.Format, string) {
+	_ = flags.Parse(args)
+
+	format := report.Format(*diagnostics)
+	if _, err := report.New(format, "", nil, false); err != nil {
+		usageError(err.Error())
+	}
+
+	switch flags.NArg() {
+	case 0:
+		return format, ""
+	case 1:
+		return format, flags.Arg(0)
+	default:
+		usageError(fmt.Sprintf("%s accepts only one source file", flags.Name()))
+		return "", ""
+	}
+}
+
+func usageError(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(exitUsage)
+}
+
+func ioError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(exitUsage)
+}
+
+// openInput opens the source file or returns stdin if the file is empty.
+// Name of the input is used in diagnostics.
+func openInput(filename string) (io.Reader, string) {
+	if filename == "" {
+		return os.Stdin, "<stdin>"
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		ioError(err)
+	}
+
+	return file, filename
+}
+
+// build compiles the program to the WASM binary module.
+// By default, the module is written next to the source file with .wasm extension.
+func build(args []string) {
+	flags, diagnostics := newFlags("build", "[file]")
+	output := flags.String("o", "", "output file, or - for stdout (default: source file with .wasm extension)")
+	noChecks := newChecksFlag(flags)
+	format, filename := parseFlags(flags, diagnostics, args)
+
+	if *output == "" {
+		if filename == "" {
+			usageError("-o is required when the program is read from stdin")
+		}
+
+		*output = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".wasm"
+	}
+
 	ctx := context.NewEnvContext(stdcontext.Background())
 
-	if len(os.Args) > 1 {
-		file, err := os.OpenFile(os.Args[1], os.O_RDONLY, 0666)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+	r, name := openInput(filename)
+	m, ok := compile(ctx, r, !*noChecks)
+	writeReport(ctx, name, format)
+
+	if !ok || len(ctx.Errors()) != 0 {
+		os.Exit(exitErrors)
+	}
+
+	b, err := m.Binary()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitErrors)
+	}
+
+	if err := writeOutput(*output, b); err != nil {
+		ioError(err)
+	}
+}
+
+// check runs all modules of the compiler except the code generator and only reports diagnostics.
+func check(args []string) {
+	flags, diagnostics := newFlags("check", "[file]")
+	format, filename := parseFlags(flags, diagnostics, args)
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	r, name := openInput(filename)
+	for range analyze(ctx, r) {
+	}
+
+	writeReport(ctx, name, format)
+
+	if len(ctx.Errors()) != 0 {
+		os.Exit(exitErrors)
+	}
+}
+
+// run compiles the program and executes it, so program output is the only output on stdout.
+func run(args []string) {
+	flags, diagnostics := newFlags("run", "[file]")
+	noChecks := newChecksFlag(flags)
+	format, filename := parseFlags(flags, diagnostics, args)
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	r, name := openInput(filename)
+	m, ok := compile(ctx, r, !*noChecks)
+	writeReport(ctx, name, format)
+
+	if !ok || len(ctx.Errors()) != 0 {
+		os.Exit(exitErrors)
+	}
+
+	if err := vm.New(vm.ConsoleImports(os.Stdout)).Run(m, "main"); err != nil {
+		fmt.Fprintf(os.Stderr, "runtime error: %s\n", err)
+		os.Exit(exitErrors)
+	}
+}
+
+// emit writes the result of the compilation stage, so stages can be inspected separately.
+// Results of earlier stages are written even if the program has errors, as they show how the program was understood.
+func emit(args []string) {
+	flags, diagnostics := newFlags("emit", "[file]")
+	stage := flags.String("emit", stageWAT, "stage to emit: tokens, ast, symbols, wat or wasm")
+	output := flags.String("o", "-", "output file, or - for stdout")
+	noChecks := newChecksFlag(flags)
+	format, filename := parseFlags(flags, diagnostics, args)
+
+	switch *stage {
+	case stageTokens, stageAST, stageSymbols, stageWAT, stageWASM:
+	default:
+		usageError(fmt.Sprintf("unknown stage %q: expected tokens, ast, symbols, wat or wasm", *stage))
+	}
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	r, name := openInput(filename)
+	buffer := 0
+
+	var (
+		sb  strings.Builder
+		b   []byte
+		err error
+	)
+
+	switch *stage {
+	case stageTokens:
+		literals := reader.New(buffer).Read(ctx, r)
+		for t := range scanner.New(buffer).Scan(ctx, literals) {
+			fmt.Fprintln(&sb, t)
+		}
+	case stageAST:
+		literals := reader.New(buffer).Read(ctx, r)
+		tokens := scanner.New(buffer).Scan(ctx, literals)
+		for program := range syntax_analyzer.New(buffer).Analyze(ctx, tokens) {
+			err = ast.Fprint(&sb, program)
+		}
+	case stageSymbols:
+		for result := range analyze(ctx, r) {
+			writeSymbols(&sb, "program", result)
+		}
+	case stageWAT, stageWASM:
+		m, ok := compile(ctx, r, !*noChecks)
+		if !ok {
+			break
 		}
 
-		compile(ctx, file)
+		if *stage == stageWAT {
+			sb.WriteString(m.String())
+			sb.WriteString("\n")
+		} else {
+			b, err = m.Binary()
+		}
+	}
+
+	writeReport(ctx, name, format)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitErrors)
+	}
+
+	// Generated module isn't written if the program has errors, as it doesn't exist.
+	if sb.Len() != 0 || b != nil {
+		if b == nil {
+			b = []byte(sb.String())
+		}
+
+		if err := writeOutput(*output, b); err != nil {
+			ioError(err)
+		}
+	}
+
+	if len(ctx.Errors()) != 0 {
+		os.Exit(exitErrors)
+	}
+}
+
+// writeSymbols writes symbols declared in the block in order of declaration, and then symbols of its functions.
+// Builtin symbols aren't declared in the source code, so they are skipped.
+func writeSymbols(w io.Writer, title string, result typechecker.Result) {
+	var symbols []symbol.Symbol
+	for _, s := range result.Scope.Symbols() {
+		if symbol.Position(s) != (literal.Position{}) {
+			symbols = append(symbols, s)
+		}
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbol.Position(symbols[i]).Before(symbol.Position(symbols[j]))
+	})
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, s := range symbols {
+		fmt.Fprintf(w, "  %v: %s\n", symbol.Position(s), symbol.Describe(s))
+	}
 
-		return
+	for _, f := range result.Funcs {
+		kind := "function"
+		if f.Symbol.IsProcedure() {
+			kind = "procedure"
+		}
+
+		writeSymbols(w, kind+" "+f.Symbol.Value, f.Result)
+	}
+}
+
+// writeOutput writes the result to the file, or to stdout if the file is -.
+func writeOutput(filename string, b []byte) error {
+	if filename == "-" {
+		_, err := os.Stdout.Write(b)
+		return err
 	}
 
-	compile(ctx, os.Stdin)
+	return os.WriteFile(filename, b, 0666)
 }
 
-func compile(ctx context.FullContext, r io.Reader) {
+// analyze runs all modules of the compiler except the code generator.
+func analyze(ctx context.FullContext, r io.Reader) <-chan typechecker.Result {
 	buffer := 0
 
 	rd := reader.New(buffer)
@@ -45,42 +372,179 @@ func compile(ctx context.FullContext, r io.Reader) {
 	programs := syn.Analyze(ctx, tokens)
 
 	checker := typechecker.NewTypeChecker(buffer)
-	results := checker.Check(ctx, programs)
+	return checker.Check(ctx, programs)
+}
 
+func compile(ctx context.FullContext, r io.Reader, checks bool) (wasm.Module, bool) {
 	generator := wasm.NewGenerator()
-	if m, ok := <-generator.Generate(ctx, results); ok {
-		if len(os.Args) > 2 {
-			file, err := os.Create(os.Args[2])
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
+	generator.BoundsChecks = checks
+	generator.RangeChecks = checks
+
+	m, ok := <-generator.Generate(ctx, analyze(ctx, r))
+	return m, ok
+}
+
+// maxFixPasses limits the number of times fixes are applied, so suggestions which undo each other can't loop forever.
+const maxFixPasses = 10
+
+// fix applies suggestions of the neutralizer to the file and recompiles the fixed source to confirm the result.
+// In dry run mode the file isn't changed, and the unified diff is printed instead.
+func fix(args []string) {
+	flags, diagnostics := newFlags("fix", "file")
+	dryRun := flags.Bool("dry-run", false, "print the unified diff instead of changing the file")
+	format, filename := parseFlags(flags, diagnostics, args)
+
+	if filename == "" {
+		usageError("fix requires a source file")
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		ioError(err)
+	}
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	source := string(b)
+	_, _ = compile(ctx, strings.NewReader(source), true)
+
+	// Some misspellings are found only after previous ones are fixed, so fixes are applied until there is nothing to fix.
+	fixed, applied := source, 0
+	for i := 0; i < maxFixPasses; i++ {
+		edits := fixer.Edits(ctx.Source(), ctx.Diagnostics())
+		if len(edits) == 0 {
+			break
+		}
 
-			if _, err = file.WriteString(m.String()); err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+		fixed, err = fixer.Apply(fixed, edits)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitErrors)
+		}
+
+		applied += len(edits)
+
+		// Fixed source is compiled with a new context, so only diagnostics of the fixed source are reported.
+		ctx = context.NewEnvContext(stdcontext.Background())
+		_, _ = compile(ctx, strings.NewReader(fixed), true)
+	}
+
+	switch {
+	case applied == 0:
+		fmt.Fprintln(os.Stderr, "nothing to fix")
+	case *dryRun:
+		fmt.Print(diff.Unified(filename, source, fixed))
+		fmt.Fprintf(os.Stderr, "%d fix(es) can be applied\n", applied)
+	default:
+		if err := os.WriteFile(filename, []byte(fixed), 0666); err != nil {
+			ioError(err)
+		}
+
+		fmt.Fprintf(os.Stderr, "applied %d fix(es)\n", applied)
+	}
+
+	// Remaining errors can't be fixed automatically.
+	writeReport(ctx, filename, format)
+
+	if len(ctx.Errors()) != 0 {
+		os.Exit(exitErrors)
+	}
+}
+
+// formatFiles formats files passed as arguments, or stdin if there are no files.
+// Formatted programs are printed to stdout unless they are written back to files or shown as diffs.
+// Files with syntax errors aren't changed, and their errors are printed to stderr.
+func formatFiles(args []string) {
+	flags := flag.NewFlagSet("compiler fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the formatted program to the source file instead of stdout")
+	showDiff := flags.Bool("d", false, "print the unified diff instead of the formatted program")
+	_ = flags.Parse(args)
+	files := flags.Args()
+
+	if len(files) == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "-w can't be used with stdin")
+			os.Exit(exitUsage)
+		}
+
+		files = []string{""}
+	}
+
+	failed := false
+	for _, filename := range files {
+		var (
+			b   []byte
+			err error
+		)
+
+		if filename == "" {
+			filename = "<stdin>"
+			b, err = io.ReadAll(os.Stdin)
+		} else {
+			b, err = os.ReadFile(filename)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+
+		// Each file is formatted with a new context, so diagnostics of files aren't mixed.
+		ctx := context.NewEnvContext(stdcontext.Background())
+
+		source := string(b)
+		formatted, ok := formatter.New(0).Format(ctx, strings.NewReader(source))
+		if !ok {
+			w, _ := report.New(report.FormatText, filename, ctx.Source(), isTerminal(os.Stderr))
+			if err := w.Write(os.Stderr, ctx.Diagnostics()); err != nil {
+				fmt.Fprintln(os.Stderr, err)
 			}
 
-			if _, err := generator.WAT2WASM(file.Name()); err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+			failed = true
+			continue
+		}
+
+		if *showDiff {
+			fmt.Print(diff.Unified(filename, source, formatted))
+		}
+
+		if *write && formatted != source {
+			if err := os.WriteFile(filename, []byte(formatted), 0666); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(exitUsage)
 			}
-		} else {
-			fmt.Println(m.String())
+		}
+
+		if !*showDiff && !*write {
+			fmt.Print(formatted)
 		}
 	}
 
-	writeReport(ctx)
+	if failed {
+		os.Exit(exitErrors)
+	}
 }
 
-func writeReport(ctx context.FullContext) {
-	if len(ctx.Errors()) == 0 {
-		fmt.Println("compiled successfully")
-		return
+// writeReport writes all diagnostics to stderr, so they aren't mixed with the generated module or program output.
+// Warnings are reported even if the program was compiled successfully.
+func writeReport(ctx context.FullContext, filename string, format report.Format) {
+	w, _ := report.New(format, filename, ctx.Source(), format == report.FormatText && isTerminal(os.Stderr))
+	if err := w.Write(os.Stderr, ctx.Diagnostics()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
 
-	fmt.Printf("finished with %d error(s)\n", len(ctx.Errors()))
-	for _, err := range ctx.Errors() {
-		fmt.Println(err)
+	if format == report.FormatText && len(ctx.Errors()) != 0 {
+		fmt.Fprintf(os.Stderr, "finished with %d error(s)\n", len(ctx.Errors()))
 	}
 }
+
+// isTerminal checks whether the file is a terminal, so colors can be used.
+// Colors are disabled if NO_COLOR environment variable is set.
+func isTerminal(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
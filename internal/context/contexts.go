@@ -14,6 +14,7 @@ type FullContext interface {
 	LoggerContext
 	ErrorsContext
 	NeutralizerContext
+	SourceContext
 }
 
 type LoggerContext interface {
@@ -23,9 +24,18 @@ type LoggerContext interface {
 
 type ErrorsContext interface {
 	AddError(source ErrorSource, position literal.Position, err error)
+	AddWarning(source ErrorSource, position literal.Position, err error)
+	AddNote(source ErrorSource, position literal.Position, err error)
 	Errors() []Error
+	Warnings() []Error
+	Diagnostics() []Error
 }
 
 type NeutralizerContext interface {
 	Neutralizer() neutralizer.Neutralizer
 }
+
+// SourceContext provides lines of the program text read by the reader.
+type SourceContext interface {
+	Source() *literal.Source
+}
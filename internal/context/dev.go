@@ -6,6 +6,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/iskorotkov/compiler/internal/data/literal"
 	"github.com/iskorotkov/compiler/internal/module/neutralizer"
 )
 
@@ -15,6 +16,7 @@ type devContext struct {
 	context.Context
 	errorsContext
 	neutralizerContext
+	sourceContext
 	logger *zap.SugaredLogger
 }
 
@@ -63,5 +65,6 @@ func NewDevContext(ctx context.Context) FullContext {
 		}(),
 		errorsContext:      errorsContext{},
 		neutralizerContext: neutralizerContext{neutralizer: neutralizer.New(1)},
+		sourceContext:      sourceContext{source: &literal.Source{}},
 	}
 }
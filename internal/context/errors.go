@@ -18,43 +18,110 @@ const (
 	ErrorSourceInternal  ErrorSource = "internal"
 )
 
+const (
+	// SeverityError is the default severity, so diagnostics without severity fail the build.
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
 type ErrorSource string
 
+// Severity of the diagnostic. Only errors prevent the program from being compiled.
+type Severity int
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
 var _ ErrorsContext = (*errorsContext)(nil)
 
+// Error is a diagnostic of any severity.
 type Error struct {
+	Severity Severity
 	Source   ErrorSource
 	Position literal.Position
 	Err      error
 }
 
+// Error returns the diagnostic in the short form. Warnings and notes are prefixed with the severity.
 func (e Error) Error() string {
-	return fmt.Sprintf("%s %s: %v", strings.ToUpper(string(e.Source)), e.Position, e.Err)
+	s := fmt.Sprintf("%s %s: %v", strings.ToUpper(string(e.Source)), e.Position, e.Err)
+	if e.Severity != SeverityError {
+		return fmt.Sprintf("%s: %s", e.Severity, s)
+	}
+
+	return s
 }
 
 type errorsContext struct {
-	errors []Error
-	m      sync.Mutex
+	diagnostics []Error
+	m           sync.Mutex
 }
 
 func (e *errorsContext) AddError(source ErrorSource, position literal.Position, err error) {
+	e.add(SeverityError, source, position, err)
+}
+
+// AddWarning adds a diagnostic which doesn't prevent the program from being compiled.
+func (e *errorsContext) AddWarning(source ErrorSource, position literal.Position, err error) {
+	e.add(SeverityWarning, source, position, err)
+}
+
+// AddNote adds a diagnostic with additional information for other diagnostics.
+func (e *errorsContext) AddNote(source ErrorSource, position literal.Position, err error) {
+	e.add(SeverityNote, source, position, err)
+}
+
+func (e *errorsContext) add(severity Severity, source ErrorSource, position literal.Position, err error) {
 	e.m.Lock()
 	defer e.m.Unlock()
 
-	e.errors = append(e.errors, Error{
+	e.diagnostics = append(e.diagnostics, Error{
+		Severity: severity,
 		Source:   source,
 		Position: position,
 		Err:      err,
 	})
 }
 
+// Errors returns diagnostics with error severity, so the program can't be compiled if there are any.
 func (e *errorsContext) Errors() []Error {
+	return e.filter(SeverityError)
+}
+
+func (e *errorsContext) Warnings() []Error {
+	return e.filter(SeverityWarning)
+}
+
+// Diagnostics returns diagnostics of all severities sorted by position.
+func (e *errorsContext) Diagnostics() []Error {
 	e.m.Lock()
 	defer e.m.Unlock()
 
-	sort.SliceStable(e.errors, func(i, j int) bool {
-		return e.errors[i].Position.Before(e.errors[j].Position)
+	sort.SliceStable(e.diagnostics, func(i, j int) bool {
+		return e.diagnostics[i].Position.Before(e.diagnostics[j].Position)
 	})
 
-	return e.errors
+	return append([]Error(nil), e.diagnostics...)
+}
+
+func (e *errorsContext) filter(severity Severity) []Error {
+	var res []Error
+	for _, d := range e.Diagnostics() {
+		if d.Severity == severity {
+			res = append(res, d)
+		}
+	}
+
+	return res
 }
@@ -5,6 +5,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/iskorotkov/compiler/internal/data/literal"
 	"github.com/iskorotkov/compiler/internal/module/neutralizer"
 )
 
@@ -14,6 +15,7 @@ type prodContext struct {
 	context.Context
 	errorsContext
 	neutralizerContext
+	sourceContext
 	logger *zap.SugaredLogger
 }
 
@@ -31,5 +33,6 @@ func NewProdContext(ctx context.Context) FullContext {
 		logger:             zap.NewNop().Sugar(),
 		errorsContext:      errorsContext{},
 		neutralizerContext: neutralizerContext{neutralizer: neutralizer.New(1)},
+		sourceContext:      sourceContext{source: &literal.Source{}},
 	}
 }
@@ -0,0 +1,15 @@
+package context
+
+import (
+	"github.com/iskorotkov/compiler/internal/data/literal"
+)
+
+var _ SourceContext = (*sourceContext)(nil)
+
+type sourceContext struct {
+	source *literal.Source
+}
+
+func (s *sourceContext) Source() *literal.Source {
+	return s.source
+}
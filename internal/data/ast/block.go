@@ -0,0 +1,92 @@
+package ast
+
+import "github.com/iskorotkov/compiler/internal/data/token"
+
+// QueryBlock finds top nodes with given markers in the block.
+// Unlike Query, it doesn't look into nested function declarations,
+// so only declarations and operators of the block itself are returned.
+func QueryBlock(block Node, markers ...Marker) []Node {
+	queryMarkers := append([]Marker{MarkerFuncDecl}, markers...)
+
+	var res []Node
+	for _, node := range block.Query(QueryTypeTop, queryMarkers...) {
+		for _, marker := range markers {
+			if node.Has(marker) {
+				res = append(res, node)
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+// Operators returns operators of the program or function block.
+func Operators(node Node) Node {
+	block := node.Query(QueryTypeOne, MarkerFunctionBlock)[0]
+	return QueryBlock(block, MarkerOperators)[0]
+}
+
+// Body returns the block of the loop or with operator, or nil if the body is empty.
+func Body(operator Node) Node {
+	if blocks := Blocks(operator); len(blocks) != 0 {
+		return blocks[0]
+	}
+
+	return nil
+}
+
+// Blocks returns blocks of the operator (bodies of loops or branches of if) in order of appearance.
+// The operator itself is skipped as it may be the only operator in the block of another operator.
+func Blocks(operator Node) []Node {
+	branch, ok := operator.(*Branch)
+	if !ok {
+		return nil
+	}
+
+	var blocks []Node
+	for _, item := range branch.Items {
+		blocks = append(blocks, item.Query(QueryTypeTop, MarkerBlock)...)
+	}
+
+	return blocks
+}
+
+// IfBlocks returns the then and else blocks of the if operator.
+// Empty branches have no blocks, so the branches are told apart by the else keyword, and nil is returned for empty ones.
+func IfBlocks(operator Node) (then, otherwise Node) {
+	branch, ok := operator.(*Branch)
+	if !ok {
+		return nil, nil
+	}
+
+	afterElse := false
+	for _, item := range branch.Items {
+		if leaf := firstLeaf(item); leaf != nil && leaf.ID == token.Else {
+			afterElse = true
+		}
+
+		for _, block := range item.Query(QueryTypeTop, MarkerBlock) {
+			if afterElse {
+				otherwise = block
+			} else {
+				then = block
+			}
+		}
+	}
+
+	return then, otherwise
+}
+
+func firstLeaf(node Node) *Leaf {
+	switch node := node.(type) {
+	case *Leaf:
+		return node
+	case *Branch:
+		if len(node.Items) != 0 {
+			return firstLeaf(node.Items[0])
+		}
+	}
+
+	return nil
+}
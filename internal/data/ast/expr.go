@@ -0,0 +1,302 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+// binaryOpsByPriority lists binary operators from the lowest priority to the highest.
+var binaryOpsByPriority = []map[token.ID]bool{
+	{
+		token.Xor: true,
+		token.Or:  true,
+	},
+	{
+		token.And: true,
+	},
+	{
+		token.Eq:  true,
+		token.Ne:  true,
+		token.Lt:  true,
+		token.Lte: true,
+		token.Gt:  true,
+		token.Gte: true,
+	},
+	{
+		token.Plus:  true,
+		token.Minus: true,
+	},
+	{
+		token.Multiply: true,
+		token.Divide:   true,
+		token.Div:      true,
+		token.Mod:      true,
+	},
+}
+
+// Expr is an expression tree built from an expression node.
+//
+// Leaf nodes of the tree are operands (literals, identifiers, variables with selectors and function calls),
+// inner nodes are unary (only Right is set) or binary operations.
+type Expr struct {
+	// Leaf is an operator token, an operand token or a name of a function or a variable (for calls and variables with selectors).
+	Leaf *Leaf
+	// Call is a function call node (only for function calls).
+	Call Node
+	// Variable is a variable node with selectors (only for variables with selectors, for example, array elements).
+	Variable Node
+	Left     *Expr
+	Right    *Expr
+}
+
+func (e *Expr) IsOperand() bool {
+	return e.Left == nil && e.Right == nil
+}
+
+func (e *Expr) IsCall() bool {
+	return e.Call != nil
+}
+
+func (e *Expr) HasSelectors() bool {
+	return e.Variable != nil
+}
+
+func (e *Expr) IsUnary() bool {
+	return e.Left == nil && e.Right != nil
+}
+
+// IsNegatedIntLiteral returns true for the int literal with unary minus.
+// Minus is folded into such literals, so the smallest int can be written even though it has no positive counterpart.
+func (e *Expr) IsNegatedIntLiteral() bool {
+	return e.IsUnary() && e.Leaf.ID == token.Minus && e.Right.IsOperand() && e.Right.Leaf.ID == token.IntLiteral
+}
+
+func (e *Expr) Position() literal.Position {
+	switch {
+	case e.IsCall():
+		return e.Call.Position()
+	case e.HasSelectors():
+		return e.Variable.Position()
+	case e.IsOperand():
+		return e.Leaf.Position()
+	case e.IsUnary():
+		return e.Leaf.Position().Join(e.Right.Position())
+	default:
+		return e.Left.Position().Join(e.Right.Position())
+	}
+}
+
+func (e *Expr) String() string {
+	switch {
+	case e.IsCall():
+		return fmt.Sprintf("%s(...)", e.Leaf.Value)
+	case e.HasSelectors():
+		return variableString(e.Leaf.Value, e.Variable)
+	case e.IsOperand():
+		return e.Leaf.Value
+	case e.IsUnary():
+		return fmt.Sprintf("(%s %v)", e.Leaf.Value, e.Right)
+	default:
+		return fmt.Sprintf("(%v %s %v)", e.Left, e.Leaf.Value, e.Right)
+	}
+}
+
+// NewExpr builds an expression tree from an expression node.
+func NewExpr(node Node) (*Expr, error) {
+	items, err := linearizeExpr(node, true)
+	if err != nil {
+		return nil, err
+	}
+
+	p := exprParser{items: items}
+
+	expr, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.items) {
+		return nil, fmt.Errorf("unexpected %q in expression", p.items[p.pos].Leaf.Value)
+	}
+
+	return expr, nil
+}
+
+// CallArgs returns arguments of a function call node.
+func CallArgs(call Node) []Node {
+	branch, ok := call.(*Branch)
+	if !ok {
+		return nil
+	}
+
+	// Skip the call node itself as it may be marked as an argument of another call.
+	var args []Node
+	for _, item := range branch.Items {
+		args = append(args, item.Query(QueryTypeTop, MarkerFuncArg)...)
+	}
+
+	return args
+}
+
+// Selectors returns index expressions and field names of a variable with selectors in order of application.
+// Multidimensional indexes are returned one by one, so a[i, j] has the same selectors as a[i][j].
+// Field names are leaves marked with MarkerField.
+func Selectors(variable Node) []Node {
+	branch, ok := variable.(*Branch)
+	if !ok {
+		return nil
+	}
+
+	// Skip the variable node itself as it may be marked as an index of another variable.
+	var selectors []Node
+	for _, item := range branch.Items {
+		selectors = append(selectors, item.Query(QueryTypeTop, MarkerIndex, MarkerField)...)
+	}
+
+	return selectors
+}
+
+// variableString returns a short form of the variable with selectors, where consecutive indexes are collapsed.
+func variableString(name string, variable Node) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+
+	index := false
+	for _, selector := range Selectors(variable) {
+		if selector.Has(MarkerField) {
+			sb.WriteString(".")
+			sb.WriteString(selector.(*Leaf).Value)
+			index = false
+			continue
+		}
+
+		if !index {
+			sb.WriteString("[...]")
+			index = true
+		}
+	}
+
+	return sb.String()
+}
+
+// linearizeExpr returns operators and operands of the expression in order of appearance.
+// Function calls, variables with selectors and expressions in parentheses are returned as single operands.
+func linearizeExpr(node Node, root bool) ([]*Expr, error) {
+	if node.Has(MarkerFuncCall) {
+		name := node.Query(QueryTypeOne, MarkerFuncName)[0].(*Leaf)
+		return []*Expr{{Leaf: name, Call: node}}, nil
+	}
+
+	// Variable name is the first item of the variable node.
+	if branch, ok := node.(*Branch); ok && node.Has(MarkerVariable) {
+		return []*Expr{{Leaf: branch.Items[0].(*Leaf), Variable: node}}, nil
+	}
+
+	if !root && node.Has(MarkerExpr) {
+		expr, err := NewExpr(node)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Expr{expr}, nil
+	}
+
+	switch node := node.(type) {
+	case *Leaf:
+		if node.ID == token.OpeningParenthesis || node.ID == token.ClosingParenthesis {
+			return nil, nil
+		}
+
+		return []*Expr{{Leaf: node}}, nil
+	case *Branch:
+		var res []*Expr
+		for _, item := range node.Items {
+			items, err := linearizeExpr(item, false)
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, items...)
+		}
+
+		return res, nil
+	default:
+		panic(fmt.Sprintf("unknown node type %T", node))
+	}
+}
+
+type exprParser struct {
+	items []*Expr
+	pos   int
+}
+
+func (p *exprParser) parse(priority int) (*Expr, error) {
+	if priority == len(binaryOpsByPriority) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parse(priority + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.pos < len(p.items) && p.isOperator(p.items[p.pos]) && binaryOpsByPriority[priority][p.items[p.pos].Leaf.ID] {
+		op := p.items[p.pos]
+		p.pos++
+
+		right, err := p.parse(priority + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Expr{Leaf: op.Leaf, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (*Expr, error) {
+	if p.pos == len(p.items) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	item := p.items[p.pos]
+	p.pos++
+
+	if !p.isOperator(item) {
+		return item, nil
+	}
+
+	switch item.Leaf.ID {
+	case token.Not, token.Plus, token.Minus:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Expr{Leaf: item.Leaf, Right: operand}, nil
+	default:
+		return nil, fmt.Errorf("unexpected operator %q", item.Leaf.Value)
+	}
+}
+
+func (p *exprParser) isOperator(item *Expr) bool {
+	if !item.IsOperand() || item.IsCall() || item.HasSelectors() {
+		return false
+	}
+
+	if item.Leaf.ID == token.Not {
+		return true
+	}
+
+	for _, ops := range binaryOpsByPriority {
+		if ops[item.Leaf.ID] {
+			return true
+		}
+	}
+
+	return false
+}
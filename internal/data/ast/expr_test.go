@@ -0,0 +1,140 @@
+package ast_test
+
+import (
+	stdcontext "context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/bnf"
+	"github.com/iskorotkov/compiler/internal/fn/channel"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
+)
+
+func TestNewExpr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "single operand",
+			input:    "x",
+			expected: "x",
+		},
+		{
+			name:     "operator priority",
+			input:    "1 + 2 * 3",
+			expected: "(1 + (2 * 3))",
+		},
+		{
+			name:     "left associativity",
+			input:    "10 - 2 - 3",
+			expected: "((10 - 2) - 3)",
+		},
+		{
+			name:     "parentheses",
+			input:    "(1 + 2) * 3",
+			expected: "((1 + 2) * 3)",
+		},
+		{
+			name:     "unary operators",
+			input:    "-x + 1 < 5",
+			expected: "(((- x) + 1) < 5)",
+		},
+		{
+			name:     "function calls",
+			input:    "f(x, g(1)) * 2",
+			expected: "(f(...) * 2)",
+		},
+		{
+			name:     "array elements",
+			input:    "a[i + 1] * m[1, a[2]]",
+			expected: "(a[...] * m[...])",
+		},
+		{
+			name:     "record fields",
+			input:    "p.x + s[1].y - r.a[i].b",
+			expected: "((p.x + s[...].y) - r.a[...].b)",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.NewEnvContext(stdcontext.Background())
+
+			literals := reader.New(0).Read(ctx, strings.NewReader(test.input))
+			tokens := scanner.New(0).Scan(ctx, literals)
+
+			node, err := bnf.Expression.Build(ctx, channel.NewTxChannel(tokens))
+			assert.NoError(t, err)
+
+			expr, err := ast.NewExpr(node)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, expr.String())
+		})
+	}
+}
+
+func TestCallArgs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	literals := reader.New(0).Read(ctx, strings.NewReader("f(x + 1, g(2), 3)"))
+	tokens := scanner.New(0).Scan(ctx, literals)
+
+	node, err := bnf.Expression.Build(ctx, channel.NewTxChannel(tokens))
+	assert.NoError(t, err)
+
+	expr, err := ast.NewExpr(node)
+	assert.NoError(t, err)
+	assert.True(t, expr.IsCall())
+
+	var args []string
+	for _, arg := range ast.CallArgs(expr.Call) {
+		argExpr, err := ast.NewExpr(arg)
+		assert.NoError(t, err)
+
+		args = append(args, argExpr.String())
+	}
+
+	assert.Equal(t, []string{"(x + 1)", "g(...)", "3"}, args)
+}
+
+func TestSelectors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	literals := reader.New(0).Read(ctx, strings.NewReader("m[i, j + 1][a[k]].p.q[r.z]"))
+	tokens := scanner.New(0).Scan(ctx, literals)
+
+	node, err := bnf.Expression.Build(ctx, channel.NewTxChannel(tokens))
+	assert.NoError(t, err)
+
+	expr, err := ast.NewExpr(node)
+	assert.NoError(t, err)
+	assert.True(t, expr.HasSelectors())
+	assert.Equal(t, "m", expr.Leaf.Value)
+	assert.Equal(t, "m[...].p.q[...]", expr.String())
+
+	var selectors []string
+	for _, selector := range ast.Selectors(expr.Variable) {
+		selectorExpr, err := ast.NewExpr(selector)
+		assert.NoError(t, err)
+
+		selectors = append(selectors, selectorExpr.String())
+	}
+
+	assert.Equal(t, []string{"i", "(j + 1)", "a[...]", "p", "q", "r.z"}, selectors)
+}
@@ -1,5 +1,7 @@
 package ast
 
+import "fmt"
+
 const (
 	// Declarations.
 
@@ -8,8 +10,18 @@ const (
 	MarkerTypeDecl
 	MarkerFuncDecl
 	MarkerFuncName
+	MarkerFuncHeader
 	MarkerParamGroupDecl
+	MarkerByRef
 	MarkerReturnType
+	MarkerArrayType
+	MarkerIndexRange
+	MarkerRecordType
+	MarkerFieldDecl
+	MarkerEnumType
+	// MarkerEnumValue marks names of enum values, so they aren't confused with names of variables and types.
+	MarkerEnumValue
+	MarkerSubrangeType
 
 	// Expressions.
 
@@ -26,6 +38,14 @@ const (
 	MarkerLeftSide
 	MarkerRightSide
 
+	// Variables.
+
+	// MarkerVariable marks a variable with selectors (for example, indexes of array elements).
+	MarkerVariable
+	MarkerIndex
+	// MarkerField marks a field name in record type declarations and field selectors.
+	MarkerField
+
 	// Functions.
 
 	MarkerFuncCall
@@ -37,11 +57,21 @@ const (
 	MarkerFor
 	MarkerWhile
 	MarkerRepeat
+	MarkerWith
+	MarkerCase
 
 	MarkerIfExpr
 	MarkerForHeader
+	MarkerForVar
+	MarkerForDirection
 	MarkerWhileExpr
 	MarkerRepeatExpr
+	MarkerWithVar
+	MarkerCaseExpr
+	MarkerCaseBranch
+	// MarkerCaseLabel marks a single value or a range of values in a case branch.
+	MarkerCaseLabel
+	MarkerCaseElse
 
 	// Blocks.
 
@@ -56,10 +86,79 @@ const (
 	MarkerName
 	MarkerType
 	MarkerValue
+	// MarkerError marks a node that couldn't be parsed because of syntax errors.
+	MarkerError
 )
 
 type Marker int
 
+var markerNames = map[Marker]string{
+	MarkerVarDecl:            "var-decl",
+	MarkerConstDecl:          "const-decl",
+	MarkerTypeDecl:           "type-decl",
+	MarkerFuncDecl:           "func-decl",
+	MarkerFuncName:           "func-name",
+	MarkerFuncHeader:         "func-header",
+	MarkerParamGroupDecl:     "param-group-decl",
+	MarkerByRef:              "by-ref",
+	MarkerReturnType:         "return-type",
+	MarkerArrayType:          "array-type",
+	MarkerIndexRange:         "index-range",
+	MarkerRecordType:         "record-type",
+	MarkerFieldDecl:          "field-decl",
+	MarkerEnumType:           "enum-type",
+	MarkerEnumValue:          "enum-value",
+	MarkerSubrangeType:       "subrange-type",
+	MarkerExpr:               "expr",
+	MarkerAdditionalOperands: "additional-operands",
+	MarkerMultiplicativeOp:   "multiplicative-op",
+	MarkerAdditiveOp:         "additive-op",
+	MarkerLogicOp:            "logic-op",
+	MarkerCompareOp:          "compare-op",
+	MarkerAssign:             "assign",
+	MarkerLeftSide:           "left-side",
+	MarkerRightSide:          "right-side",
+	MarkerVariable:           "variable",
+	MarkerIndex:              "index",
+	MarkerField:              "field",
+	MarkerFuncCall:           "func-call",
+	MarkerFuncArg:            "func-arg",
+	MarkerIf:                 "if",
+	MarkerFor:                "for",
+	MarkerWhile:              "while",
+	MarkerRepeat:             "repeat",
+	MarkerWith:               "with",
+	MarkerCase:               "case",
+	MarkerIfExpr:             "if-expr",
+	MarkerForHeader:          "for-header",
+	MarkerForVar:             "for-var",
+	MarkerForDirection:       "for-direction",
+	MarkerWhileExpr:          "while-expr",
+	MarkerRepeatExpr:         "repeat-expr",
+	MarkerWithVar:            "with-var",
+	MarkerCaseExpr:           "case-expr",
+	MarkerCaseBranch:         "case-branch",
+	MarkerCaseLabel:          "case-label",
+	MarkerCaseElse:           "case-else",
+	MarkerFunctionBlock:      "function-block",
+	MarkerProgramBlock:       "program-block",
+	MarkerDeclarations:       "declarations",
+	MarkerOperators:          "operators",
+	MarkerBlock:              "block",
+	MarkerName:               "name",
+	MarkerType:               "type",
+	MarkerValue:              "value",
+	MarkerError:              "error",
+}
+
+func (m Marker) String() string {
+	if name, ok := markerNames[m]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("marker(%d)", int(m))
+}
+
 type Markers map[Marker]bool
 
 func (ms Markers) Has(m Marker) bool {
@@ -0,0 +1,65 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Fprint writes the tree to w, one node per line. Children are indented under their parents, and markers follow nodes in brackets.
+func Fprint(w io.Writer, node Node) error {
+	return fprint(w, node, 0)
+}
+
+func fprint(w io.Writer, node Node, depth int) error {
+	if node == nil {
+		return nil
+	}
+
+	var markers Markers
+	switch node := node.(type) {
+	case *Leaf:
+		markers = node.Markers
+	case *Branch:
+		markers = node.Markers
+	}
+
+	line := strings.Repeat("  ", depth) + node.String()
+	if len(markers) != 0 {
+		line += " " + markerList(markers)
+	}
+
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+
+	if b, ok := node.(*Branch); ok {
+		for _, item := range b.Items {
+			if err := fprint(w, item, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// markerList returns names of markers sorted in order of declaration.
+func markerList(ms Markers) string {
+	var sorted []Marker
+	for m := range ms {
+		sorted = append(sorted, m)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	names := make([]string, len(sorted))
+	for i, m := range sorted {
+		names[i] = m.String()
+	}
+
+	return "[" + strings.Join(names, " ") + "]"
+}
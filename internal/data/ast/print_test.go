@@ -0,0 +1,30 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+func TestFprint(t *testing.T) {
+	t.Parallel()
+
+	node := ast.WrapSlice([]ast.Node{
+		ast.Token(token.New(token.UserDefined, literal.New("x", 1, 1, 2)), ast.Markers{ast.MarkerLeftSide: true}),
+		ast.Token(token.New(token.Assign, literal.New(":=", 1, 3, 5)), nil),
+		ast.Token(token.New(token.IntLiteral, literal.New("1", 1, 6, 7)), ast.Markers{ast.MarkerRightSide: true, ast.MarkerExpr: true}),
+	}, ast.Markers{ast.MarkerAssign: true})
+
+	var sb strings.Builder
+	assert.NoError(t, ast.Fprint(&sb, node))
+	assert.Equal(t, `list of 3 items [assign]
+  user defined "x" at 1:1 [left-side]
+  ":=" at 1:3-5
+  int literal "1" at 1:6 [expr right-side]
+`, sb.String())
+}
@@ -8,7 +8,13 @@ import (
 // Conditions.
 
 var (
-	If Sequence
+	If          Sequence
+	Case        Sequence
+	CaseBranch  Sequence
+	CaseLabel   Either
+	CaseValue   Sequence
+	CaseElse    Sequence
+	ElseKeyword Either
 )
 
 func init() {
@@ -22,24 +28,88 @@ func init() {
 			&Block,
 		}}},
 	}, Markers: ast.Markers{ast.MarkerIf: true}}
+
+	// Semicolons after the last branch and after the last operator of the else branch are optional.
+	Case = Sequence{Name: "case", BNFs: []BNF{
+		Token{ID: token.Case},
+		Sequence{BNFs: []BNF{&Expression}, Markers: ast.Markers{ast.MarkerCaseExpr: true}},
+		Token{ID: token.Of},
+		&CaseBranch,
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Semicolon},
+			&CaseBranch,
+		}}},
+		Optional{BNF: Token{ID: token.Semicolon}},
+		Optional{BNF: &CaseElse},
+		Token{ID: token.End},
+	}, Markers: ast.Markers{ast.MarkerCase: true}}
+
+	CaseBranch = Sequence{Name: "case-branch", BNFs: []BNF{
+		&CaseLabel,
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Comma},
+			&CaseLabel,
+		}}},
+		Token{ID: token.Colon},
+		&Block,
+	}, Markers: ast.Markers{ast.MarkerCaseBranch: true}}
+
+	// Range must be checked before a single value as both of them start with a value.
+	CaseLabel = Either{Name: "case-label", BNFs: []BNF{
+		Sequence{BNFs: []BNF{
+			&CaseValue,
+			Token{ID: token.Range},
+			&CaseValue,
+		}},
+		&CaseValue,
+	}, Markers: ast.Markers{ast.MarkerCaseLabel: true}}
+
+	CaseValue = Sequence{Name: "case-value", BNFs: []BNF{
+		&Sign,
+		Either{BNFs: []BNF{
+			&IntLiteral,
+			&StringLiteral,
+			&BoolLiteral,
+			Token{ID: token.UserDefined},
+		}},
+	}, Markers: ast.Markers{ast.MarkerValue: true}}
+
+	CaseElse = Sequence{Name: "case-else", BNFs: []BNF{
+		&ElseKeyword,
+		Sequence{BNFs: []BNF{
+			&RecoverableOperator,
+			Several{BNF: Sequence{BNFs: []BNF{
+				Token{ID: token.Semicolon},
+				&RecoverableOperator,
+			}}},
+		}, Markers: ast.Markers{ast.MarkerBlock: true}},
+	}, Markers: ast.Markers{ast.MarkerCaseElse: true}}
+
+	ElseKeyword = Either{Name: "else-keyword", BNFs: []BNF{
+		Token{ID: token.Else},
+		Token{ID: token.Otherwise},
+	}}
 }
 
 // Constants.
 
 var (
-	IntLiteral         Token
-	DoubleLiteral      Token
-	BoolLiteral        Token
-	Constant           Either
-	ConstantDefinition Sequence
-	Constants          Optional
-	Sign               Optional
+	IntLiteral          Token
+	DoubleLiteral       Token
+	BoolLiteral         Token
+	StringLiteral       Token
+	Constant            Either
+	ConstantDefinition  Sequence
+	ConstantDeclaration Recover
+	Constants           Optional
+	Sign                Optional
 )
 
 func init() {
 	IntLiteral = Token{ID: token.IntLiteral}
 	DoubleLiteral = Token{ID: token.DoubleLiteral}
 	BoolLiteral = Token{ID: token.BoolLiteral}
+	StringLiteral = Token{ID: token.StringLiteral}
 
 	Sign = Optional{Name: "sign", BNF: Either{BNFs: []BNF{
 		Token{ID: token.Plus},
@@ -58,22 +128,25 @@ func init() {
 			}},
 		}},
 		&BoolLiteral,
+		&StringLiteral,
 	}, Markers: ast.Markers{ast.MarkerValue: true}}
 
+	// Value of a constant is an expression of literals and other constants, and it's computed by the type checker.
 	ConstantDefinition = Sequence{Name: "constant-definition", BNFs: []BNF{
 		Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerName: true}},
 		Token{ID: token.Eq},
-		&Constant,
+		Sequence{BNFs: []BNF{&Expression}, Markers: ast.Markers{ast.MarkerValue: true}},
 	}, Markers: ast.Markers{ast.MarkerConstDecl: true}}
 
-	Constants = Optional{Name: "constants", BNF: Sequence{BNFs: []BNF{
-		Token{ID: token.Const},
+	ConstantDeclaration = Recover{Name: "constant-declaration", BNF: Sequence{BNFs: []BNF{
 		&ConstantDefinition,
 		Token{ID: token.Semicolon},
-		Several{BNF: Sequence{BNFs: []BNF{
-			&ConstantDefinition,
-			Token{ID: token.Semicolon},
-		}}},
+	}}, Sync: declarationsSync, Terminators: []token.ID{token.Semicolon}}
+
+	Constants = Optional{Name: "constants", BNF: Sequence{BNFs: []BNF{
+		Token{ID: token.Const},
+		&ConstantDeclaration,
+		Several{BNF: &ConstantDeclaration},
 	}}}
 }
 
@@ -139,10 +212,11 @@ func init() {
 		Token{ID: token.And, Markers: ast.Markers{ast.MarkerLogicOp: true}},
 	}}
 
-	MultiplicativeOperand = Either{Name: "multiplicative-operand", BNFs: []BNF{
+	// Function call must be checked before variable as both of them start with identifier.
+	MultiplicativeOperand = Either{Name: "multiplicative-operand", Expected: "expression", BNFs: []BNF{
+		&FunctionCall,
 		&Variable,
 		&Constant,
-		&FunctionCall,
 		Sequence{BNFs: []BNF{
 			Token{ID: token.OpeningParenthesis},
 			&Expression,
@@ -158,15 +232,19 @@ func init() {
 // Functions.
 
 var (
-	FunctionName       Token
-	ParameterGroup     Sequence
-	FormalParameters   Either
-	FactualParameter   Either
-	FunctionHeader     Sequence
-	FunctionDefinition Sequence
-	Functions          Several
-	FunctionCall       Sequence
-	FunctionReturnType Sequence
+	FunctionName         Token
+	ParameterGroup       Sequence
+	FormalParameters     Either
+	FactualParameter     Either
+	FormalParametersList Optional
+	FunctionHeader       Sequence
+	FunctionSignature    Recover
+	ProcedureHeader      Sequence
+	ProcedureSignature   Recover
+	FunctionDefinition   Sequence
+	Functions            Several
+	FunctionCall         Sequence
+	FunctionReturnType   Sequence
 )
 
 func init() {
@@ -193,7 +271,7 @@ func init() {
 		Sequence{BNFs: []BNF{
 			Token{ID: token.Var},
 			&ParameterGroup,
-		}},
+		}, Markers: ast.Markers{ast.MarkerParamGroupDecl: true, ast.MarkerByRef: true}},
 		Sequence{BNFs: []BNF{
 			Token{ID: token.Function},
 			&ParameterGroup,
@@ -208,25 +286,56 @@ func init() {
 
 	FunctionHeader = Sequence{Name: "function-header", BNFs: []BNF{
 		Token{ID: token.Function},
-		&FunctionName,
+		&FunctionSignature,
+	}}
+
+	FormalParametersList = Optional{Name: "formal-parameters-list", BNF: Sequence{BNFs: []BNF{
+		Token{ID: token.OpeningParenthesis},
 		Optional{BNF: Sequence{BNFs: []BNF{
-			Token{ID: token.OpeningParenthesis},
-			Optional{BNF: Sequence{BNFs: []BNF{
+			&FormalParameters,
+			Several{BNF: Sequence{BNFs: []BNF{
+				Token{ID: token.Semicolon},
 				&FormalParameters,
-				Several{BNF: Sequence{BNFs: []BNF{
-					Token{ID: token.Semicolon},
-					&FormalParameters,
-				}}},
 			}}},
-			Token{ID: token.ClosingParenthesis},
 		}}},
+		Token{ID: token.ClosingParenthesis},
+	}}}
+
+	FunctionSignature = Recover{Name: "function-signature", BNF: Sequence{BNFs: []BNF{
+		&FunctionName,
+		&FormalParametersList,
 		Token{ID: token.Colon},
 		&FunctionReturnType,
 		Token{ID: token.Semicolon},
+	}},
+		Sync:        declarationsSync,
+		Terminators: []token.ID{token.Semicolon},
+		Nesting:     map[token.ID]token.ID{token.OpeningParenthesis: token.ClosingParenthesis},
+		Markers:     ast.Markers{ast.MarkerFuncHeader: true},
+	}
+
+	// Procedure is a function without return type.
+	ProcedureHeader = Sequence{Name: "procedure-header", BNFs: []BNF{
+		Token{ID: token.Procedure},
+		&ProcedureSignature,
 	}}
 
+	ProcedureSignature = Recover{Name: "procedure-signature", BNF: Sequence{BNFs: []BNF{
+		&FunctionName,
+		&FormalParametersList,
+		Token{ID: token.Semicolon},
+	}},
+		Sync:        declarationsSync,
+		Terminators: []token.ID{token.Semicolon},
+		Nesting:     map[token.ID]token.ID{token.OpeningParenthesis: token.ClosingParenthesis},
+		Markers:     ast.Markers{ast.MarkerFuncHeader: true},
+	}
+
 	FunctionDefinition = Sequence{Name: "function-definition", BNFs: []BNF{
-		&FunctionHeader,
+		Either{BNFs: []BNF{
+			&FunctionHeader,
+			&ProcedureHeader,
+		}},
 		&FunctionBlock,
 	}, Markers: ast.Markers{ast.MarkerFuncDecl: true}}
 
@@ -271,12 +380,12 @@ func init() {
 	Direction = Either{Name: "direction", BNFs: []BNF{
 		Token{ID: token.To},
 		Token{ID: token.Downto},
-	}}
+	}, Markers: ast.Markers{ast.MarkerForDirection: true}}
 
 	For = Sequence{Name: "for", BNFs: []BNF{
 		Sequence{BNFs: []BNF{
 			Token{ID: token.For},
-			Token{ID: token.UserDefined},
+			Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerForVar: true}},
 			Token{ID: token.Assign},
 			&Expression,
 			&Direction,
@@ -290,25 +399,30 @@ func init() {
 // Operators.
 
 var (
-	Operator           Optional
-	Block              Sequence
-	SimpleOperator     Either
-	CompositeOperator  Sequence
-	ComplexOperator    Either
-	ConditionOperator  Either
-	LoopOperator       Either
-	Operators          Sequence
-	AssignmentOperator Sequence
+	Operator            Optional
+	RecoverableOperator Recover
+	Block               Sequence
+	SimpleOperator      Either
+	ProcedureCall       Sequence
+	CompositeOperator   Sequence
+	ComplexOperator     Either
+	ConditionOperator   Either
+	LoopOperator        Either
+	Operators           Sequence
+	AssignmentOperator  Sequence
+	With                Sequence
 )
 
 func init() {
-	ConditionOperator = Either{Name: "condition-operator", BNFs: []BNF{&If}}
+	ConditionOperator = Either{Name: "condition-operator", BNFs: []BNF{&If, &Case}}
 	Operators = Sequence{Name: "operators", BNFs: []BNF{&CompositeOperator}}
 	Block = Sequence{Name: "block", BNFs: []BNF{&Operator}, Markers: ast.Markers{ast.MarkerBlock: true}}
 
+	// Procedure call without parentheses must be checked after assignment as both of them start with identifier.
 	SimpleOperator = Either{Name: "simple-operator", BNFs: []BNF{
 		&FunctionCall,
 		&AssignmentOperator,
+		&ProcedureCall,
 	}}
 
 	// TODO: Syntax analyzer is very sensitive to extra semicolons.
@@ -317,12 +431,22 @@ func init() {
 		&ComplexOperator,
 	}}}
 
+	// Syntax errors in operators are skipped up to the end of the operator,
+	// so the rest of the composite operator is still analyzed.
+	RecoverableOperator = Recover{Name: "recoverable-operator", BNF: &Operator,
+		Sync:    []token.ID{token.Semicolon, token.End},
+		Follow:  []token.ID{token.Semicolon, token.End},
+		Nesting: map[token.ID]token.ID{token.Begin: token.End, token.Case: token.End, token.Repeat: token.Until},
+	}
+
+	ProcedureCall = Sequence{Name: "procedure-call", BNFs: []BNF{&FunctionName}, Markers: ast.Markers{ast.MarkerFuncCall: true}}
+
 	CompositeOperator = Sequence{Name: "composite-operator", BNFs: []BNF{
 		Token{ID: token.Begin},
-		&Operator,
+		&RecoverableOperator,
 		Several{BNF: Sequence{BNFs: []BNF{
 			Token{ID: token.Semicolon},
-			&Operator,
+			&RecoverableOperator,
 		}}},
 		Token{ID: token.End},
 	}}
@@ -332,6 +456,7 @@ func init() {
 		&AssignmentOperator,
 		&ConditionOperator,
 		&LoopOperator,
+		&With,
 	}}
 
 	LoopOperator = Either{Name: "loop-operator", BNFs: []BNF{
@@ -348,18 +473,115 @@ func init() {
 		Token{ID: token.Assign},
 		Sequence{BNFs: []BNF{&Expression}, Markers: ast.Markers{ast.MarkerRightSide: true}},
 	}, Markers: ast.Markers{ast.MarkerAssign: true}}
+
+	// Each record variable is marked, so fields of all of them are available in the block.
+	With = Sequence{Name: "with", BNFs: []BNF{
+		Token{ID: token.With},
+		Sequence{BNFs: []BNF{&Variable}, Markers: ast.Markers{ast.MarkerWithVar: true}},
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Comma},
+			Sequence{BNFs: []BNF{&Variable}, Markers: ast.Markers{ast.MarkerWithVar: true}},
+		}}},
+		Token{ID: token.Do},
+		&Block,
+	}, Markers: ast.Markers{ast.MarkerWith: true}}
 }
 
 // Types.
 
 var (
-	TypeDefinition Sequence
-	Types          Optional
-	Type           Token
+	TypeDefinition  Sequence
+	TypeDeclaration Recover
+	Types           Optional
+	Type            Either
+	ArrayType       Sequence
+	IndexRange      Sequence
+	IndexBound      Sequence
+	RecordType      Sequence
+	FieldDefinition Sequence
+	EnumType        Sequence
+	SubrangeType    Sequence
 )
 
 func init() {
-	Type = Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerType: true}}
+	// Type name must be checked before array and record types, so identifiers aren't neutralized to keywords.
+	// Subrange must be checked before type name as its lower bound may be a name of a constant.
+	Type = Either{Name: "type", BNFs: []BNF{
+		&SubrangeType,
+		Token{ID: token.UserDefined},
+		&ArrayType,
+		&RecordType,
+		&EnumType,
+	}, Markers: ast.Markers{ast.MarkerType: true}}
+
+	// Array with several index ranges is an array of arrays,
+	// so array[1..2, 1..3] of T is the same as array[1..2] of array[1..3] of T.
+	ArrayType = Sequence{Name: "array-type", BNFs: []BNF{
+		Token{ID: token.Array},
+		Token{ID: token.OpeningSquareBrace},
+		&IndexRange,
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Comma},
+			&IndexRange,
+		}}},
+		Token{ID: token.ClosingSquareBrace},
+		Token{ID: token.Of},
+		&Type,
+	}, Markers: ast.Markers{ast.MarkerArrayType: true}}
+
+	IndexRange = Sequence{Name: "index-range", BNFs: []BNF{
+		&IndexBound,
+		Token{ID: token.Range},
+		&IndexBound,
+	}, Markers: ast.Markers{ast.MarkerIndexRange: true}}
+
+	IndexBound = Sequence{Name: "index-bound", BNFs: []BNF{
+		&Sign,
+		Either{BNFs: []BNF{
+			&IntLiteral,
+			Token{ID: token.UserDefined},
+		}},
+	}, Markers: ast.Markers{ast.MarkerValue: true}}
+
+	// Semicolon after the last field is optional.
+	RecordType = Sequence{Name: "record-type", BNFs: []BNF{
+		Token{ID: token.Record},
+		&FieldDefinition,
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Semicolon},
+			&FieldDefinition,
+		}}},
+		Optional{BNF: Token{ID: token.Semicolon}},
+		Token{ID: token.End},
+	}, Markers: ast.Markers{ast.MarkerRecordType: true}}
+
+	// Field names aren't marked as names, so they aren't confused with names of variables and types.
+	FieldDefinition = Sequence{Name: "field-definition", BNFs: []BNF{
+		Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerField: true}},
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Comma},
+			Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerField: true}},
+		}}},
+		Token{ID: token.Colon},
+		&Type,
+	}, Markers: ast.Markers{ast.MarkerFieldDecl: true}}
+
+	EnumType = Sequence{Name: "enum-type", BNFs: []BNF{
+		Token{ID: token.OpeningParenthesis},
+		Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerEnumValue: true}},
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Comma},
+			Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerEnumValue: true}},
+		}}},
+		Token{ID: token.ClosingParenthesis},
+	}, Markers: ast.Markers{ast.MarkerEnumType: true}}
+
+	// Bounds of subranges are the same constants as case labels.
+	SubrangeType = Sequence{Name: "subrange-type", BNFs: []BNF{
+		&CaseValue,
+		Token{ID: token.Range},
+		&CaseValue,
+	}, Markers: ast.Markers{ast.MarkerSubrangeType: true}}
 
 	TypeDefinition = Sequence{Name: "type-definition", BNFs: []BNF{
 		Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerName: true}},
@@ -367,30 +589,67 @@ func init() {
 		&Type,
 	}, Markers: ast.Markers{ast.MarkerTypeDecl: true}}
 
-	Types = Optional{Name: "types", BNF: Sequence{BNFs: []BNF{
-		Token{ID: token.Type},
+	TypeDeclaration = Recover{Name: "type-declaration", BNF: Sequence{BNFs: []BNF{
 		&TypeDefinition,
 		Token{ID: token.Semicolon},
-		Several{BNF: Sequence{BNFs: []BNF{
-			&TypeDefinition,
-			Token{ID: token.Semicolon},
-		}}},
+	}}, Sync: declarationsSync, Terminators: []token.ID{token.Semicolon}}
+
+	Types = Optional{Name: "types", BNF: Sequence{BNFs: []BNF{
+		Token{ID: token.Type},
+		&TypeDeclaration,
+		Several{BNF: &TypeDeclaration},
 	}}}
 }
 
 // Variables.
 
 var (
-	VariableName      Sequence
-	FullVariable      Sequence
-	Variable          Sequence
-	SameTypeVariables Sequence
-	Variables         Optional
+	VariableName        Sequence
+	Index               Sequence
+	IndexSelector       Sequence
+	FieldSelector       Sequence
+	Selector            Either
+	FullVariable        Either
+	Variable            Sequence
+	SameTypeVariables   Sequence
+	VariableDeclaration Recover
+	Variables           Optional
 )
 
 func init() {
 	VariableName = Sequence{Name: "variable-name", BNFs: []BNF{Token{ID: token.UserDefined}}}
-	FullVariable = Sequence{Name: "full variable", BNFs: []BNF{&VariableName}}
+	Index = Sequence{Name: "index", BNFs: []BNF{&Expression}, Markers: ast.Markers{ast.MarkerIndex: true}}
+
+	IndexSelector = Sequence{Name: "index-selector", BNFs: []BNF{
+		Token{ID: token.OpeningSquareBrace},
+		&Index,
+		Several{BNF: Sequence{BNFs: []BNF{
+			Token{ID: token.Comma},
+			&Index,
+		}}},
+		Token{ID: token.ClosingSquareBrace},
+	}}
+
+	FieldSelector = Sequence{Name: "field-selector", BNFs: []BNF{
+		Token{ID: token.Period},
+		Token{ID: token.UserDefined, Markers: ast.Markers{ast.MarkerField: true}},
+	}}
+
+	Selector = Either{Name: "selector", BNFs: []BNF{
+		&IndexSelector,
+		&FieldSelector,
+	}}
+
+	// Variable with selectors is marked, so it's used as a single operand in expressions.
+	// Variable without selectors is a plain identifier.
+	FullVariable = Either{Name: "full variable", BNFs: []BNF{
+		Sequence{BNFs: []BNF{
+			&VariableName,
+			&Selector,
+			Several{BNF: &Selector},
+		}, Markers: ast.Markers{ast.MarkerVariable: true}},
+		&VariableName,
+	}}
 	Variable = Sequence{Name: "variable", BNFs: []BNF{&FullVariable}}
 
 	SameTypeVariables = Sequence{Name: "same-type-variables", BNFs: []BNF{
@@ -403,19 +662,23 @@ func init() {
 		&Type,
 	}, Markers: ast.Markers{ast.MarkerVarDecl: true}}
 
-	Variables = Optional{Name: "variables", BNF: Sequence{BNFs: []BNF{
-		Token{ID: token.Var},
+	VariableDeclaration = Recover{Name: "variable-declaration", BNF: Sequence{BNFs: []BNF{
 		&SameTypeVariables,
 		Token{ID: token.Semicolon},
-		Several{BNF: Sequence{BNFs: []BNF{
-			&SameTypeVariables,
-			Token{ID: token.Semicolon},
-		}}},
+	}}, Sync: declarationsSync, Terminators: []token.ID{token.Semicolon}}
+
+	Variables = Optional{Name: "variables", BNF: Sequence{BNFs: []BNF{
+		Token{ID: token.Var},
+		&VariableDeclaration,
+		Several{BNF: &VariableDeclaration},
 	}}}
 }
 
 // Special.
 
+// declarationsSync contains tokens which start a new declaration section or a block body.
+var declarationsSync = []token.ID{token.Begin, token.Var, token.Function, token.Procedure, token.Const, token.Type}
+
 var (
 	FunctionBlock Sequence
 	Program       Sequence
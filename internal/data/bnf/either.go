@@ -15,6 +15,8 @@ var _ BNF = Either{}
 type Either struct {
 	Name string
 	BNFs []BNF
+	// Expected describes the construct in errors if none of BNFs match, instead of the last BNF.
+	Expected string
 	ast.Markers
 }
 
@@ -54,6 +56,15 @@ func (e Either) Build(ctx interface {
 		return ast.Wrap(res, e.Markers), nil
 	}
 
+	var unexpectedTokenError *UnexpectedTokenError
+	if e.Expected != "" && errors.As(lastError, &unexpectedTokenError) {
+		lastError = &UnexpectedTokenError{
+			Expected:  token.Unknown,
+			Construct: e.Expected,
+			Actual:    unexpectedTokenError.Actual,
+		}
+	}
+
 	ctx.Logger().Errorf("the token is not in a list of expected tokens: %w", lastError)
 	return nil, fmt.Errorf("the token is not in a list of expected tokens: %w", lastError)
 }
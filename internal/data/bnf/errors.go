@@ -10,10 +10,20 @@ var _ error = (*UnexpectedTokenError)(nil)
 
 type UnexpectedTokenError struct {
 	Expected token.ID
-	Actual   token.Token
+	// Construct describes the expected construct if it can start with several tokens.
+	Construct string
+	Actual    token.Token
 }
 
 func (e *UnexpectedTokenError) Error() string {
+	if e.Construct != "" {
+		return fmt.Sprintf("unexpected token: expected %s, got %q", e.Construct, e.Actual.Value)
+	}
+
+	if e.Expected == token.Unknown {
+		return fmt.Sprintf("unexpected token %q", e.Actual.Value)
+	}
+
 	return fmt.Sprintf("unexpected token: expected %q, got %q", e.Expected, e.Actual.Value)
 }
 
@@ -0,0 +1,178 @@
+package bnf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/token"
+	"github.com/iskorotkov/compiler/internal/fn/channel"
+)
+
+var _ BNF = Recover{}
+
+// Recover builds BNF and recovers from syntax errors in it.
+//
+// If BNF can't be built, the error is reported, tokens are skipped up to the next Sync token
+// (which is left for the following rules) or up to the next Terminator token (which is consumed),
+// and a node with MarkerError is returned instead.
+// If the first token is a Sync token, there is nothing to recover from, so the error is returned as is.
+type Recover struct {
+	Name string
+	BNF  BNF
+	// Sync tokens stop skipping and aren't consumed.
+	Sync []token.ID
+	// Terminators stop skipping and are consumed.
+	Terminators []token.ID
+	// Follow tokens are the only tokens allowed after BNF if not empty.
+	Follow []token.ID
+	// Nesting maps opening tokens to closing ones. Sync tokens and Terminators inside nested pairs are skipped.
+	Nesting map[token.ID]token.ID
+	ast.Markers
+}
+
+func (r Recover) Build(ctx interface {
+	context.LoggerContext
+	context.NeutralizerContext
+	context.ErrorsContext
+}, ch *channel.TxChannel[token.Token]) (ast.Node, error) {
+	ctx, cancel := context.Scoped(ctx, r.String())
+	defer cancel()
+
+	ch = ch.StartTx()
+	first := ch.Read()
+	ch.Rollback()
+
+	start := ch.Pos()
+
+	ch = ch.StartTx()
+
+	res, err := r.BNF.Build(ctx, ch)
+	if err == nil {
+		if len(r.Follow) == 0 {
+			ctx.Logger().Debugf("ok, commit tx")
+			ch.Commit()
+
+			return r.wrap(res), nil
+		}
+
+		ch = ch.StartTx()
+		next := ch.Read()
+		ch.Rollback()
+
+		if next.ID == token.EOF || contains(r.Follow, next.ID) {
+			ctx.Logger().Debugf("ok, commit tx")
+			ch.Commit()
+
+			return r.wrap(res), nil
+		}
+
+		err = &UnexpectedTokenError{
+			Expected: token.Unknown,
+			Actual:   next,
+		}
+	}
+
+	ch.Rollback()
+
+	if !errors.Is(err, &UnexpectedTokenError{}) {
+		ctx.Logger().Debugf("%v in %v, returning", err, r)
+		return nil, err
+	}
+
+	if first.ID == token.EOF || contains(r.Sync, first.ID) {
+		ctx.Logger().Debugf("%v in %v, nothing to recover from", err, r)
+		return nil, err
+	}
+
+	// Report the error at the farthest token reached by BNF as it's the most likely place of the error.
+	actual := first
+	if farthest, pos := ch.Farthest(); pos > start {
+		actual = farthest
+	}
+
+	if actual.ID == token.EOF {
+		ctx.Logger().Debugf("%v in %v, unexpected end of file", err, r)
+		return nil, err
+	}
+
+	var unexpectedTokenError *UnexpectedTokenError
+	if !errors.As(err, &unexpectedTokenError) || unexpectedTokenError.Actual != actual {
+		unexpectedTokenError = &UnexpectedTokenError{
+			Expected: token.Unknown,
+			Actual:   actual,
+		}
+	}
+
+	r.report(ctx, unexpectedTokenError)
+
+	var closing []token.ID
+	for {
+		ch = ch.StartTx()
+		tk := ch.Read()
+
+		if tk.ID == token.EOF || len(closing) == 0 && contains(r.Sync, tk.ID) {
+			ch.Rollback()
+			break
+		}
+
+		ch.Commit()
+
+		if len(closing) > 0 && tk.ID == closing[len(closing)-1] {
+			closing = closing[:len(closing)-1]
+			continue
+		}
+
+		if id, ok := r.Nesting[tk.ID]; ok {
+			closing = append(closing, id)
+			continue
+		}
+
+		if len(closing) == 0 && contains(r.Terminators, tk.ID) {
+			break
+		}
+	}
+
+	ctx.Logger().Debugf("recovered from %v in %v", err, r)
+
+	return ast.Token(first, r.Markers.Merge(ast.Markers{ast.MarkerError: true})), nil
+}
+
+func (r Recover) wrap(res ast.Node) ast.Node {
+	if res == nil {
+		return nil
+	}
+
+	return ast.Wrap(res, r.Markers)
+}
+
+// report adds the error unless it was already reported.
+// The same tokens can be parsed several times when outer rules roll back.
+func (r Recover) report(ctx context.ErrorsContext, err *UnexpectedTokenError) {
+	for _, e := range ctx.Errors() {
+		if e.Position == err.Actual.Position && e.Err.Error() == err.Error() {
+			return
+		}
+	}
+
+	ctx.AddError(context.ErrorSourceSyntax, err.Actual.Position, err)
+}
+
+func (r Recover) String() string {
+	if r.Name != "" {
+		return r.Name
+	} else {
+		return fmt.Sprintf("recover")
+	}
+}
+
+func contains(ids []token.ID, id token.ID) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,29 @@
+package literal
+
+import "sync"
+
+// Source contains lines of the program text, so diagnostics can show the code they refer to.
+// Lines are added by the reader while other modules are running, so access is synchronized.
+type Source struct {
+	lines []string
+	m     sync.Mutex
+}
+
+func (s *Source) AddLine(line string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.lines = append(s.lines, line)
+}
+
+// Line returns the line with the given number (starting from 1).
+func (s *Source) Line(n LineNumber) (string, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if n < 1 || int(n) > len(s.lines) {
+		return "", false
+	}
+
+	return s.lines[n-1], true
+}
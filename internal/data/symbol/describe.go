@@ -0,0 +1,97 @@
+package symbol
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/data/literal"
+)
+
+// Describe returns the declaration of the symbol as it would be written in the source code.
+func Describe(s Symbol) string {
+	switch s := s.(type) {
+	case *Var:
+		if s.Param {
+			return "(param) " + describeParam(*s)
+		}
+
+		return fmt.Sprintf("var %s: %s", s.Value, s.Type.Name())
+	case *Const:
+		// Enum values are stored as their ordinal values.
+		if s.Type.Enum != nil {
+			return fmt.Sprintf("const %s: %s", s.Value, s.Type.Name())
+		}
+
+		return fmt.Sprintf("const %s: %s = %s", s.Value, s.Type.Name(), s.RawValue)
+	case *Type:
+		return describeType(s)
+	case *Func:
+		var params []string
+		for _, p := range s.Params {
+			params = append(params, describeParam(p))
+		}
+
+		signature := s.Value
+		if len(params) != 0 {
+			signature += "(" + strings.Join(params, "; ") + ")"
+		}
+
+		if s.IsProcedure() {
+			return "procedure " + signature
+		}
+
+		return fmt.Sprintf("function %s: %s", signature, s.ReturnType.Name())
+	default:
+		return s.String()
+	}
+}
+
+// Position returns the position of the symbol declaration, or zero position for builtin symbols.
+func Position(s Symbol) literal.Position {
+	switch s := s.(type) {
+	case *Var:
+		return s.Position
+	case *Const:
+		return s.Position
+	case *Type:
+		return s.Position
+	case *Func:
+		return s.Position
+	default:
+		return literal.Position{}
+	}
+}
+
+func describeParam(p Var) string {
+	if p.ByRef {
+		return fmt.Sprintf("var %s: %s", p.Value, p.Type.Name())
+	}
+
+	return fmt.Sprintf("%s: %s", p.Value, p.Type.Name())
+}
+
+func describeType(t *Type) string {
+	// Builtin types don't have definitions.
+	if t.Position == (literal.Position{}) {
+		return "type " + t.Value
+	}
+
+	if t.Alias != nil {
+		return fmt.Sprintf("type %s = %s", t.Value, t.Alias.Name())
+	}
+
+	if t.Record != nil {
+		var fields []string
+		for _, f := range t.Record.Fields {
+			fields = append(fields, fmt.Sprintf("%s: %s", f.Name, f.Type.Name()))
+		}
+
+		return fmt.Sprintf("type %s = record %s end", t.Value, strings.Join(fields, "; "))
+	}
+
+	// Name of the type without its own name is its definition.
+	definition := *t
+	definition.Value = ""
+
+	return fmt.Sprintf("type %s = %s", t.Value, definition.Name())
+}
@@ -19,20 +19,46 @@ func NewScope() Scope {
 	realSymbol := Type{Token: builtinToken("real"), BuiltinType: BuiltinTypeDouble}
 	booleanSymbol := Type{Token: builtinToken("boolean"), BuiltinType: BuiltinTypeBool}
 	stringSymbol := Type{Token: builtinToken("string"), BuiltinType: BuiltinTypeString}
+	charSymbol := Type{Token: builtinToken("char"), BuiltinType: BuiltinTypeChar}
 	voidSymbol := Type{Token: builtinToken("void"), BuiltinType: BuiltinTypeVoid}
+	// Params of builtin functions which accept values of several types have unknown type.
+	// Names of these types aren't declared in the scope, they only describe the params.
+	anyType := Type{Token: builtinToken("any"), BuiltinType: BuiltinTypeUnknown}
+	ordinalType := Type{Token: builtinToken("ordinal"), BuiltinType: BuiltinTypeUnknown}
 	writelnSymbol := Func{
 		Token: builtinToken("writeln"),
 		Params: []Var{
-			{Token: builtinToken("s"), Type: stringSymbol},
+			{Token: builtinToken("s"), Type: anyType},
 		},
 		ReturnType: voidSymbol,
 	}
+	// succ and pred return values of the same type as their argument, so it's resolved by the type checker.
+	ordSymbol := Func{
+		Token:      builtinToken("ord"),
+		Params:     []Var{{Token: builtinToken("x"), Type: ordinalType}},
+		ReturnType: integerSymbol,
+	}
+	succSymbol := Func{
+		Token:      builtinToken("succ"),
+		Params:     []Var{{Token: builtinToken("x"), Type: ordinalType}},
+		ReturnType: ordinalType,
+	}
+	predSymbol := Func{
+		Token:      builtinToken("pred"),
+		Params:     []Var{{Token: builtinToken("x"), Type: ordinalType}},
+		ReturnType: ordinalType,
+	}
 
 	_ = scope.Add(&integerSymbol)
 	_ = scope.Add(&realSymbol)
 	_ = scope.Add(&booleanSymbol)
+	_ = scope.Add(&stringSymbol)
+	_ = scope.Add(&charSymbol)
 	_ = scope.Add(&voidSymbol)
 	_ = scope.Add(&writelnSymbol)
+	_ = scope.Add(&ordSymbol)
+	_ = scope.Add(&succSymbol)
+	_ = scope.Add(&predSymbol)
 
 	return scope
 }
@@ -49,6 +75,39 @@ func (s Scope) Lookup(symbol Symbol) (Symbol, bool) {
 	return nil, false
 }
 
+// LookupLocal looks for a symbol in the current scope only, ignoring parent scopes.
+func (s Scope) LookupLocal(symbol Symbol) (Symbol, bool) {
+	symbol, ok := s.symbols[symbol.Hash()]
+	return symbol, ok
+}
+
+// IsGlobal checks whether the symbol visible in the current scope is declared in the global (program) scope.
+func (s Scope) IsGlobal(symbol Symbol) bool {
+	if _, ok := s.symbols[symbol.Hash()]; ok {
+		return s.parent == nil
+	}
+
+	if s.parent != nil {
+		return s.parent.IsGlobal(symbol)
+	}
+
+	return false
+}
+
+// LookupFunc looks for a function with the given name.
+// Unlike Lookup, it skips other symbols, so function result variables don't hide the function itself.
+func (s Scope) LookupFunc(name string) (*Func, bool) {
+	if f, ok := s.symbols[(&Name{Name: name}).Hash()].(*Func); ok {
+		return f, true
+	}
+
+	if s.parent != nil {
+		return s.parent.LookupFunc(name)
+	}
+
+	return nil, false
+}
+
 func (s Scope) Add(symbol Symbol) error {
 	if _, ok := s.symbols[symbol.Hash()]; ok {
 		return fmt.Errorf("%v was already declared in this scope", symbol)
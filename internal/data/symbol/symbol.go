@@ -2,6 +2,7 @@ package symbol
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/iskorotkov/compiler/internal/data/literal"
 	"github.com/iskorotkov/compiler/internal/data/token"
@@ -14,6 +15,10 @@ const (
 	BuiltinTypeDouble
 	BuiltinTypeBool
 	BuiltinTypeString
+	BuiltinTypeChar
+	BuiltinTypeArray
+	BuiltinTypeRecord
+	BuiltinTypeEnum
 )
 
 var (
@@ -30,12 +35,24 @@ func (t BuiltinType) String() string {
 	switch t {
 	case BuiltinTypeUnknown:
 		return "unknown"
+	case BuiltinTypeVoid:
+		return "void"
 	case BuiltinTypeInt:
 		return "int"
 	case BuiltinTypeDouble:
 		return "double"
 	case BuiltinTypeBool:
 		return "bool"
+	case BuiltinTypeString:
+		return "string"
+	case BuiltinTypeChar:
+		return "char"
+	case BuiltinTypeArray:
+		return "array"
+	case BuiltinTypeRecord:
+		return "record"
+	case BuiltinTypeEnum:
+		return "enum"
 	default:
 		panic(fmt.Sprintf("unknown builtin type: %d", t))
 	}
@@ -74,6 +91,10 @@ type Type struct {
 	hash        hasher
 	Alias       *Type // Only for user-defined types.
 	BuiltinType BuiltinType
+	Array       *Array  // Only for array types.
+	Record      *Record // Only for record types.
+	Enum        *Enum   // Only for enum types and subranges of enum types.
+	Range       *Range  // Only for subrange types.
 }
 
 func (t *Type) Hash() int {
@@ -84,11 +105,141 @@ func (t Type) String() string {
 	return fmt.Sprintf("type %v", t.Value)
 }
 
+// Same checks whether values of both types have the same representation,
+// so a variable of one type can be used in place of a variable of another type.
+func (t Type) Same(other Type) bool {
+	if t.BuiltinType != other.BuiltinType {
+		return false
+	}
+
+	// Records and enums are the same only if they are declared by the same type declaration.
+	if t.Record != nil || other.Record != nil {
+		return t.Record == other.Record
+	}
+
+	if t.Enum != other.Enum {
+		return false
+	}
+
+	if t.Range != nil || other.Range != nil {
+		return t.Range != nil && other.Range != nil && *t.Range == *other.Range
+	}
+
+	if t.Array == nil || other.Array == nil {
+		return t.Array == other.Array
+	}
+
+	return t.Array.Low == other.Array.Low &&
+		t.Array.High == other.Array.High &&
+		t.Array.Elem.Same(other.Array.Elem)
+}
+
+// IsComposite checks whether the type is an array or a record.
+// Values of composite types are stored in memory and can't be copied as a whole.
+func (t Type) IsComposite() bool {
+	return t.Array != nil || t.Record != nil
+}
+
+// Name returns a name of the type as it's written in the source code.
+func (t Type) Name() string {
+	if t.Record != nil && t.Value == "" {
+		return "record"
+	}
+
+	if t.Array != nil {
+		return fmt.Sprintf("array[%d..%d] of %s", t.Array.Low, t.Array.High, t.Array.Elem.Name())
+	}
+
+	if t.Value != "" {
+		return t.Value
+	}
+
+	if t.Range != nil {
+		return fmt.Sprintf("%s..%s", t.OrdinalName(t.Range.Low), t.OrdinalName(t.Range.High))
+	}
+
+	if t.Enum != nil && t.Enum.Name != "" {
+		return t.Enum.Name
+	}
+
+	if t.Enum != nil {
+		return fmt.Sprintf("(%s)", strings.Join(t.Enum.Values, ", "))
+	}
+
+	return t.BuiltinType.String()
+}
+
+// OrdinalName returns the value of the ordinal type with the given ordinal value as it's written in the source code.
+func (t Type) OrdinalName(v int) string {
+	switch {
+	case t.Enum != nil && v >= 0 && v < len(t.Enum.Values):
+		return t.Enum.Values[v]
+	case t.BuiltinType == BuiltinTypeChar:
+		return fmt.Sprintf("'%c'", byte(v))
+	case t.BuiltinType == BuiltinTypeBool:
+		return fmt.Sprint(v != 0)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Array describes bounds and element type of an array type.
+type Array struct {
+	Low  int
+	High int
+	Elem Type
+}
+
+// Len returns a number of elements in the array.
+func (a Array) Len() int {
+	return a.High - a.Low + 1
+}
+
+// Record describes fields of a record type in order of declaration.
+type Record struct {
+	Fields []Field
+}
+
+// Field returns the field with the given name.
+func (r Record) Field(name string) (Field, bool) {
+	for _, f := range r.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// Enum describes names of values of an enum type in order of declaration.
+// Ordinal value of each enum value is its index.
+type Enum struct {
+	Name   string // Empty if the enum isn't declared in a type declaration.
+	Values []string
+}
+
+// Range describes bounds of a subrange type as ordinal values of its base type.
+type Range struct {
+	Low  int
+	High int
+}
+
+// Contains checks whether the ordinal value is within the range.
+func (r Range) Contains(v int) bool {
+	return v >= r.Low && v <= r.High
+}
+
+type Field struct {
+	Name string
+	Type Type
+}
+
 type Var struct {
 	token.Token // Only for user-defined symbols.
 	hash        hasher
 	Type        Type
-	Initialized bool
+	Param       bool // Variable is a param of the function, so it has a value on entry to the block.
+	ByRef       bool // Only for params passed by reference (var params).
 }
 
 func (v *Var) Hash() int {
@@ -128,3 +279,8 @@ func (f *Func) Hash() int {
 func (f Func) String() string {
 	return fmt.Sprintf("func %v", f.Value)
 }
+
+// IsProcedure checks whether the function doesn't return a value.
+func (f Func) IsProcedure() bool {
+	return f.ReturnType.BuiltinType == BuiltinTypeVoid
+}
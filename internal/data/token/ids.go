@@ -1,5 +1,7 @@
 package token
 
+import "strings"
+
 type ID int
 
 const (
@@ -119,6 +121,7 @@ const (
 	Semicolon
 	Comma
 	Period
+	Range
 	Colon
 	OpeningParenthesis
 	ClosingParenthesis
@@ -126,6 +129,9 @@ const (
 	ClosingBrace
 	OpeningSquareBrace
 	ClosingSquareBrace
+	OpeningComment
+	ClosingComment
+	LineComment
 	punctuationEnd
 
 	whitespaceStart
@@ -139,6 +145,7 @@ const (
 	IntLiteral
 	DoubleLiteral
 	BoolLiteral
+	StringLiteral
 	literalsEnd
 )
 
@@ -251,6 +258,7 @@ var (
 		Semicolon:          ";",
 		Comma:              ",",
 		Period:             ".",
+		Range:              "..",
 		Colon:              ":",
 		OpeningParenthesis: "(",
 		ClosingParenthesis: ")",
@@ -258,6 +266,9 @@ var (
 		ClosingBrace:       "}",
 		OpeningSquareBrace: "[",
 		ClosingSquareBrace: "]",
+		OpeningComment:     "(*",
+		ClosingComment:     "*)",
+		LineComment:        "//",
 
 		Newline:     "\n",
 		Space:       " ",
@@ -290,6 +301,8 @@ func (i ID) String() string {
 		return "double literal"
 	case BoolLiteral:
 		return "bool literal"
+	case StringLiteral:
+		return "string literal"
 	default:
 		return tokens[i]
 	}
@@ -315,8 +328,10 @@ func (i ID) IsLiteral() bool {
 	return i > literalsStart && i < literalsEnd
 }
 
+// GetID returns the ID of the keyword, operator or punctuation token.
+// Keywords are case-insensitive as in Pascal, so "Begin" and "BEGIN" are the same keyword.
 func GetID(token string) ID {
-	return ids[token]
+	return ids[strings.ToLower(token)]
 }
 
 func ByID(id ID) string {
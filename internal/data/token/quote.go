@@ -0,0 +1,40 @@
+package token
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnterminatedString = errors.New("unterminated string literal")
+
+// Unquote returns the value of a string literal.
+// Literal is enclosed in single quotes, and a single quote inside it is escaped by doubling it.
+func Unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return "", ErrUnterminatedString
+	}
+
+	body := s[1 : len(s)-1]
+
+	var res strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] == '\'' {
+			// Quote must be followed by another quote.
+			if i+1 == len(body) || body[i+1] != '\'' {
+				return "", ErrUnterminatedString
+			}
+
+			i++
+		}
+
+		res.WriteByte(body[i])
+	}
+
+	return res.String(), nil
+}
+
+// Quote returns a string literal with the given value.
+// It's the inverse of Unquote.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -20,7 +20,7 @@ func New(id ID, lit literal.Literal) Token {
 
 func (t Token) String() string {
 	switch t.ID {
-	case UserDefined, IntLiteral, DoubleLiteral, BoolLiteral, EOF:
+	case UserDefined, IntLiteral, DoubleLiteral, BoolLiteral, StringLiteral, EOF:
 		return fmt.Sprintf("%v %v", t.ID, t.Literal)
 	default:
 		return t.Literal.String()
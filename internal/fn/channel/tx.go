@@ -11,6 +11,10 @@ type TxChannel[T any] struct {
 	rollback     []T
 	closed       bool
 	m            sync.Mutex
+
+	pos         int
+	farthest    T
+	farthestPos int
 }
 
 func NewTxChannel[T any](ch <-chan T) *TxChannel[T] {
@@ -20,6 +24,8 @@ func NewTxChannel[T any](ch <-chan T) *TxChannel[T] {
 }
 
 // Commit commits all reads and starts a new transaction.
+// Reads of a nested transaction become a part of the parent transaction,
+// so they are restored if the parent transaction is rolled back.
 func (c *TxChannel[T]) Commit() {
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -28,7 +34,12 @@ func (c *TxChannel[T]) Commit() {
 		return
 	}
 
+	top := c.transactions[len(c.transactions)-1]
 	c.transactions = c.transactions[:len(c.transactions)-1]
+
+	if len(c.transactions) > 0 {
+		c.transactions[len(c.transactions)-1] = append(c.transactions[len(c.transactions)-1], top...)
+	}
 }
 
 // Rollback adds values from ongoing transaction to the rollback list and starts a new transaction.
@@ -40,7 +51,10 @@ func (c *TxChannel[T]) Rollback() {
 		return
 	}
 
-	c.rollback = append(c.transactions[len(c.transactions)-1], c.rollback...)
+	top := c.transactions[len(c.transactions)-1]
+	c.pos -= len(top)
+
+	c.rollback = append(top, c.rollback...)
 	c.transactions = c.transactions[:len(c.transactions)-1]
 
 	if len(c.rollback) > 0 {
@@ -76,9 +90,31 @@ func (c *TxChannel[T]) Read() T {
 
 	c.transactions[len(c.transactions)-1] = append(c.transactions[len(c.transactions)-1], nextElement)
 
+	c.pos++
+	if c.pos > c.farthestPos {
+		c.farthest, c.farthestPos = nextElement, c.pos
+	}
+
 	return nextElement
 }
 
+// Pos returns a number of values read and not rolled back.
+func (c *TxChannel[T]) Pos() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.pos
+}
+
+// Farthest returns the farthest value read so far and its position.
+// Position of the value is the same as the result of Pos right after the value is read.
+func (c *TxChannel[T]) Farthest() (T, int) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.farthest, c.farthestPos
+}
+
 // Open checks whether the channel has rollback values or if the source channel is open.
 func (c *TxChannel[T]) Open() bool {
 	c.m.Lock()
@@ -128,3 +128,53 @@ func TestNestedTransactions(t *testing.T) {
 	ch.Commit()
 	assert.False(t, ch.Open())
 }
+
+func TestRollbackAfterNestedCommit(t *testing.T) {
+	t.Parallel()
+
+	ch := channel.NewTxChannel(channel.FromSlice([]int{1, 2, 3}))
+	ch = ch.StartTx()
+
+	assert.Equal(t, 1, ch.Read())
+
+	ch = ch.StartTx()
+
+	assert.Equal(t, 2, ch.Read())
+	assert.Equal(t, 3, ch.Read())
+
+	ch.Commit()
+	ch.Rollback()
+	assert.True(t, ch.Open())
+
+	assert.Equal(t, 1, ch.Read())
+	assert.Equal(t, 2, ch.Read())
+	assert.Equal(t, 3, ch.Read())
+	assert.False(t, ch.Open())
+}
+
+func TestPositions(t *testing.T) {
+	t.Parallel()
+
+	ch := channel.NewTxChannel(channel.FromSlice([]int{1, 2, 3}))
+	assert.Equal(t, 0, ch.Pos())
+
+	ch = ch.StartTx()
+
+	assert.Equal(t, 1, ch.Read())
+	assert.Equal(t, 2, ch.Read())
+	assert.Equal(t, 2, ch.Pos())
+
+	ch.Rollback()
+	assert.Equal(t, 0, ch.Pos())
+
+	farthest, pos := ch.Farthest()
+	assert.Equal(t, 2, farthest)
+	assert.Equal(t, 2, pos)
+
+	assert.Equal(t, 1, ch.Read())
+	assert.Equal(t, 1, ch.Pos())
+
+	farthest, pos = ch.Farthest()
+	assert.Equal(t, 2, farthest)
+	assert.Equal(t, 2, pos)
+}
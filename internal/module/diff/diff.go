@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines around changes in the diff, as in diff -u.
+const contextLines = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a line of the diff. Lines are numbered from 0 in both texts.
+type op struct {
+	kind     opKind
+	old, new int
+	line     string
+}
+
+// Unified returns the unified diff of texts, or an empty string if texts have the same lines.
+// Lines are matched by their longest common subsequence, so lines can be added and removed.
+func Unified(filename, before, after string) string {
+	ops := compare(splitLines(before), splitLines(after))
+
+	var changed []int
+	for i, o := range ops {
+		if o.kind != opEqual {
+			changed = append(changed, i)
+		}
+	}
+
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", filename, filename)
+
+	for i := 0; i < len(changed); {
+		// Changes close to each other are joined in one hunk, so context lines aren't repeated.
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*contextLines+1 {
+			j++
+		}
+
+		start := changed[i] - contextLines
+		if start < 0 {
+			start = 0
+		}
+
+		end := changed[j] + contextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(&sb, ops[start:end])
+
+		i = j + 1
+	}
+
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []op) {
+	oldStart, newStart := ops[0].old, ops[0].new
+	oldCount, newCount := 0, 0
+
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			oldCount++
+			newCount++
+		case opDelete:
+			oldCount++
+		case opInsert:
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			fmt.Fprintf(sb, " %s\n", o.line)
+		case opDelete:
+			fmt.Fprintf(sb, "-%s\n", o.line)
+		case opInsert:
+			fmt.Fprintf(sb, "+%s\n", o.line)
+		}
+	}
+}
+
+// hunkRange returns the range of lines in the hunk header. Empty range refers to the line before it, as in diff -u.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// compare returns lines of both texts in order of the diff.
+// Removed lines are placed before added lines, so replaced lines are shown as blocks.
+func compare(oldLines, newLines []string) []op {
+	// common[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:].
+	common := make([][]int, len(oldLines)+1)
+	for i := range common {
+		common[i] = make([]int, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			switch {
+			case oldLines[i] == newLines[j]:
+				common[i][j] = common[i+1][j+1] + 1
+			case common[i+1][j] >= common[i][j+1]:
+				common[i][j] = common[i+1][j]
+			default:
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var ops []op
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			ops = append(ops, op{kind: opEqual, old: i, new: j, line: oldLines[i]})
+			i++
+			j++
+		case j == len(newLines) || i < len(oldLines) && common[i+1][j] >= common[i][j+1]:
+			ops = append(ops, op{kind: opDelete, old: i, new: j, line: oldLines[i]})
+			i++
+		default:
+			ops = append(ops, op{kind: opInsert, old: i, new: j, line: newLines[j]})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// splitLines splits the text into lines without line endings.
+// Text usually ends with a line ending, and it doesn't start a new line.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	return lines
+}
@@ -0,0 +1,83 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/module/diff"
+)
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		expected string
+	}{
+		{
+			name:   "changed lines in separate hunks",
+			before: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n",
+			after:  "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\n",
+			expected: `--- main.pas
++++ main.pas
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -9,4 +9,4 @@
+ i
+ j
+ k
+-l
++L
+`,
+		},
+		{
+			name:   "added and removed lines",
+			before: "var i, j: integer;\nbegin\ni := 1;\nend.\n",
+			after:  "var\n  i, j: integer;\nbegin\n  i := 1;\nend.\n",
+			expected: `--- main.pas
++++ main.pas
+@@ -1,4 +1,5 @@
+-var i, j: integer;
++var
++  i, j: integer;
+ begin
+-i := 1;
++  i := 1;
+ end.
+`,
+		},
+		{
+			name:   "lines added to empty text",
+			before: "",
+			after:  "a\n",
+			expected: `--- main.pas
++++ main.pas
+@@ -0,0 +1,1 @@
++a
+`,
+		},
+		{
+			name:     "same lines with different line endings",
+			before:   "a\r\nb\r\n",
+			after:    "a\nb\n",
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, diff.Unified("main.pas", test.before, test.after))
+		})
+	}
+}
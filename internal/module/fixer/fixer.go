@@ -0,0 +1,66 @@
+package fixer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/module/report"
+)
+
+// Edits returns fixes for diagnostics of misspelled keywords and names sorted by position.
+// The same typo may be reported several times, and edits of the same text are applied once,
+// so overlapping edits are skipped except for the first one.
+func Edits(source *literal.Source, diagnostics []context.Error) []report.Edit {
+	var edits []report.Edit
+	for _, d := range diagnostics {
+		if edit, ok := report.Fix(source, d); ok {
+			edits = append(edits, edit)
+		}
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Position.Before(edits[j].Position)
+	})
+
+	var res []report.Edit
+	for _, edit := range edits {
+		if len(res) != 0 {
+			last := res[len(res)-1]
+			if last.Position.Line == edit.Position.Line && edit.Position.StartCol < last.Position.EndCol {
+				continue
+			}
+		}
+
+		res = append(res, edit)
+	}
+
+	return res
+}
+
+// Apply applies edits sorted by position to the text.
+// Edits never span several lines, so line endings and the rest of formatting are kept as is.
+func Apply(text string, edits []report.Edit) (string, error) {
+	lines := strings.SplitAfter(text, "\n")
+
+	// Edits are applied from the end of the line, so positions of previous edits stay the same.
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+
+		n := int(edit.Position.Line) - 1
+		if n < 0 || n >= len(lines) {
+			return "", fmt.Errorf("edit at %v is out of the text", edit.Position)
+		}
+
+		line := strings.TrimRight(lines[n], "\r\n")
+		if edit.Position.StartCol < 1 || int(edit.Position.EndCol)-1 > len(line) || edit.Position.EndCol < edit.Position.StartCol {
+			return "", fmt.Errorf("edit at %v is out of the line", edit.Position)
+		}
+
+		lines[n] = edit.Apply(lines[n])
+	}
+
+	return strings.Join(lines, ""), nil
+}
@@ -0,0 +1,87 @@
+package fixer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/module/diff"
+	"github.com/iskorotkov/compiler/internal/module/fixer"
+	"github.com/iskorotkov/compiler/internal/module/neutralizer"
+)
+
+func TestFix(t *testing.T) {
+	t.Parallel()
+
+	text := "progrm p;\r\nvar aa: integer;\r\nbegin\r\n\taa := aa + bb;\r\nend.\r\n"
+
+	source := &literal.Source{}
+	source.AddLine("progrm p;")
+	source.AddLine("var aa: integer;")
+	source.AddLine("begin")
+	source.AddLine("\taa := aa + bb;")
+	source.AddLine("end.")
+
+	diagnostics := []context.Error{
+		{
+			Source:   context.ErrorSourceTypecheck,
+			Position: literal.New("", 4, 13, 15).Position,
+			Err:      &neutralizer.FixableNameError{Expected: "aa", Actual: "bb"},
+		},
+		{
+			Source:   context.ErrorSourceSyntax,
+			Position: literal.New("", 1, 1, 7).Position,
+			Err:      &neutralizer.FixableNameError{Expected: "program", Actual: "progrm"},
+		},
+		// The same typo reported twice is fixed once.
+		{
+			Source:   context.ErrorSourceTypecheck,
+			Position: literal.New("", 4, 13, 15).Position,
+			Err:      &neutralizer.FixableNameError{Expected: "aa", Actual: "bb"},
+		},
+		{
+			Severity: context.SeverityWarning,
+			Source:   context.ErrorSourceTypecheck,
+			Position: literal.New("", 2, 5, 7).Position,
+			Err:      errors.New("unused"),
+		},
+	}
+
+	edits := fixer.Edits(source, diagnostics)
+	assert.Len(t, edits, 2)
+
+	fixed, err := fixer.Apply(text, edits)
+	assert.NoError(t, err)
+	assert.Equal(t, "program p;\r\nvar aa: integer;\r\nbegin\r\n\taa := aa + aa;\r\nend.\r\n", fixed)
+
+	assert.Equal(t, `--- main.pas
++++ main.pas
+@@ -1,5 +1,5 @@
+-progrm p;
++program p;
+ var aa: integer;
+ begin
+-	aa := aa + bb;
++	aa := aa + aa;
+ end.
+`, diff.Unified("main.pas", text, fixed))
+}
+
+func TestApply_OutOfLine(t *testing.T) {
+	t.Parallel()
+
+	source := &literal.Source{}
+	source.AddLine("abc")
+
+	edits := fixer.Edits(source, []context.Error{{
+		Position: literal.New("", 1, 1, 4).Position,
+		Err:      &neutralizer.FixableNameError{Expected: "abd", Actual: "abc"},
+	}})
+
+	// File was changed after it was compiled, so the edit doesn't fit the line.
+	_, err := fixer.Apply("ab\n", edits)
+	assert.Error(t, err)
+}
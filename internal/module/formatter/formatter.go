@@ -0,0 +1,133 @@
+package formatter
+
+import (
+	"io"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/token"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
+	"github.com/iskorotkov/compiler/internal/module/syntax_analyzer"
+)
+
+// Formatter prints programs in the canonical form:
+//   - operators of blocks and declarations of sections are indented with 2 spaces, one per line;
+//   - begin of the block is on the same level as the operator it belongs to;
+//   - binary operators, := and : are surrounded by spaces;
+//   - comments and single blank lines are kept.
+//
+// Formatter only changes whitespace between tokens, so the formatted program has the same meaning.
+type Formatter struct {
+	buffer int
+}
+
+func New(buffer int) *Formatter {
+	return &Formatter{
+		buffer: buffer,
+	}
+}
+
+// Format returns the formatted program.
+// Programs with syntax errors can't be formatted, as tokens skipped by the syntax analyzer would be lost.
+func (f Formatter) Format(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+		context.SourceContext
+	},
+	r io.Reader,
+) (string, bool) {
+	literals := reader.New(f.buffer).Read(ctx, r)
+
+	// Scanner skips comments, so literals are saved to find comments later.
+	var saved []literal.Literal
+	forwarded := make(chan literal.Literal, f.buffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(forwarded)
+
+		for lit := range literals {
+			saved = append(saved, lit)
+			forwarded <- lit
+		}
+	}()
+
+	tokens := scanner.New(f.buffer).Scan(ctx, forwarded)
+	programs := syntax_analyzer.New(f.buffer).Analyze(ctx, tokens)
+
+	var program ast.Node
+	for p := range programs {
+		program = p
+	}
+
+	if program == nil || len(ctx.Errors()) != 0 {
+		return "", false
+	}
+
+	// Program ends with EOF, so all literals were read.
+	<-done
+
+	p := printer{comments: findComments(saved)}
+	p.collect(program, nil)
+
+	return p.print(), true
+}
+
+// comment is a comment with its delimiters as it's written in the source code.
+type comment struct {
+	start   literal.Position
+	endLine literal.LineNumber
+	text    string
+	// line is set for line comments, which are always followed by a new line.
+	line bool
+}
+
+// findComments returns comments in the same way as the scanner skips them.
+func findComments(literals []literal.Literal) []comment {
+	var (
+		res     []comment
+		current *comment
+		end     token.ID
+		sb      strings.Builder
+	)
+
+	for _, lit := range literals {
+		id := token.GetID(lit.Value)
+
+		if current == nil {
+			if e, ok := scanner.CommentDelimiters[id]; ok {
+				current = &comment{start: lit.Position, line: id == token.LineComment}
+				end = e
+				sb.Reset()
+				sb.WriteString(lit.Value)
+			}
+
+			continue
+		}
+
+		// Newline isn't a part of the line comment.
+		if id == end && current.line {
+			current.endLine = lit.Position.Line
+			current.text = strings.TrimRight(sb.String(), " \t")
+			res = append(res, *current)
+			current = nil
+			continue
+		}
+
+		sb.WriteString(lit.Value)
+
+		if id == end {
+			current.endLine = lit.Position.Line
+			current.text = sb.String()
+			res = append(res, *current)
+			current = nil
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,232 @@
+package formatter_test
+
+import (
+	stdcontext "context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/token"
+	"github.com/iskorotkov/compiler/internal/module/formatter"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
+)
+
+// Programs with syntax errors can't be formatted.
+var invalidPrograms = map[string]bool{
+	"if-else.pas":                     true,
+	"neutralizable-errors.pas":        true,
+	"neutralizable-syntax-errors.pas": true,
+}
+
+func TestFormatter_Format_Programs(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "..", "testdata", "programs", "*.pas"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		file := file
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			t.Parallel()
+
+			b, err := os.ReadFile(file)
+			assert.NoError(t, err)
+
+			formatted, ok := format(string(b))
+			if invalidPrograms[filepath.Base(file)] {
+				assert.False(t, ok)
+				return
+			}
+
+			assert.True(t, ok)
+
+			// Formatter only changes whitespace, so the program has the same tokens.
+			assert.Equal(t, scan(string(b)), scan(formatted))
+
+			again, ok := format(formatted)
+			assert.True(t, ok)
+			assert.Equal(t, formatted, again)
+		})
+	}
+}
+
+func TestFormatter_Format(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "declarations and operators",
+			input: "program p;\nconst N=10;M = 2;\nvar i,j:integer;\nbegin\ni:=-(N+M)*2;j:=i;if i>0 then writeln(i) else if i<0 then writeln(-i) else writeln(0);\nend.",
+			expected: `program p;
+const
+  N = 10;
+  M = 2;
+var
+  i, j: integer;
+begin
+  i := -(N + M) * 2;
+  j := i;
+  if i > 0 then
+    writeln(i)
+  else if i < 0 then
+    writeln(-i)
+  else
+    writeln(0);
+end.
+`,
+		},
+		{
+			name: "nested blocks",
+			input: `program p;
+type point = record x, y: integer end;
+var i: integer;
+procedure show(var q: point);
+begin
+writeln(q.x)
+end
+begin
+for i := 1 to 3 do begin
+while i < 2 do i := i + 1;
+repeat i := i - 1 until i < 0;
+case i of
+1: writeln(1);
+2: begin writeln(2) end;
+else writeln(0)
+end
+end
+end.
+`,
+			expected: `program p;
+type
+  point = record
+    x, y: integer
+  end;
+var
+  i: integer;
+procedure show(var q: point);
+begin
+  writeln(q.x)
+end
+begin
+  for i := 1 to 3 do
+  begin
+    while i < 2 do
+      i := i + 1;
+    repeat
+      i := i - 1
+    until i < 0;
+    case i of
+      1: writeln(1);
+      2:
+        begin
+          writeln(2)
+        end;
+    else
+      writeln(0)
+    end
+  end
+end.
+`,
+		},
+		{
+			name: "keyword casing",
+			input: `PROGRAM Casing;
+VAR Total: integer;
+Begin
+  IF Total > 0 Then Total := Total DIV 2 ELSE Total := 1;
+END.
+`,
+			expected: `program Casing;
+var
+  Total: integer;
+begin
+  if Total > 0 then
+    Total := Total div 2
+  else
+    Total := 1;
+end.
+`,
+		},
+		{
+			name: "comments and blank lines",
+			input: `{ header }
+program p; // name
+(* before var *)
+var i: integer; { inline }
+
+
+// before begin
+begin
+  i := 1; // after
+  { block
+    comment }
+end.
+// final
+`,
+			expected: `{ header }
+program p; // name
+(* before var *)
+var
+  i: integer; { inline }
+
+// before begin
+begin
+  i := 1; // after
+  { block
+    comment }
+end.
+// final
+`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			formatted, ok := format(test.input)
+			assert.True(t, ok)
+			assert.Equal(t, test.expected, formatted)
+			assert.Equal(t, scan(test.input), scan(formatted))
+		})
+	}
+}
+
+func format(input string) (string, bool) {
+	ctx := context.NewEnvContext(stdcontext.Background())
+	return formatter.New(0).Format(ctx, strings.NewReader(input))
+}
+
+// scan returns IDs and values of tokens without their positions.
+// Keywords are case-insensitive, so their canonical values are returned.
+func scan(input string) []token.Token {
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	literals := reader.New(0).Read(ctx, strings.NewReader(input))
+
+	var res []token.Token
+	for tok := range scanner.New(0).Scan(ctx, literals) {
+		value := tok.Value
+		if tok.ID.IsKeyword() {
+			value = token.ByID(tok.ID)
+		}
+
+		res = append(res, token.New(tok.ID, literal.Literal{Value: value}))
+	}
+
+	return res
+}
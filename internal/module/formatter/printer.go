@@ -0,0 +1,346 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+const indentation = "  "
+
+const (
+	// frameBegin is a composite operator closed by end.
+	frameBegin frameKind = iota
+	// frameRepeat is a body of the repeat loop closed by until.
+	frameRepeat
+	// frameCase is a list of case branches closed by end.
+	frameCase
+	// frameCaseElse is an else branch of the case operator closed by end of the case operator.
+	frameCaseElse
+	// frameRecord is a list of record fields closed by end.
+	frameRecord
+	// frameSection is a const, type or var section closed by the next section or block.
+	frameSection
+	// frameOperator is a single operator after then, else, do or a case label closed by semicolon.
+	frameOperator
+)
+
+type frameKind int
+
+// frame is a construct which changes indentation of lines inside it.
+type frame struct {
+	kind frameKind
+	// base is the indent of the line which opened the frame, and the closing token is printed with it.
+	base int
+	// indent is the indent of lines inside the frame.
+	indent int
+	// then is set for operators after then, so else is matched with the innermost if.
+	then bool
+}
+
+// leaf is a token of the program with markers of the node it belongs to.
+// Same tokens have different meaning in different nodes, for example, else in if and case operators.
+type leaf struct {
+	*ast.Leaf
+	parent ast.Markers
+}
+
+// printer prints tokens of the program and decides where lines are broken.
+// AST nodes with a single item are merged with their parents, so the layout is based on tokens and their parent nodes.
+type printer struct {
+	sb       strings.Builder
+	leaves   []leaf
+	comments []comment
+	frames   []frame
+	// lineIndent is the indent of the current line.
+	lineIndent int
+	// lastLine is the source line of the last printed token or comment, so blank lines between them are kept.
+	lastLine literal.LineNumber
+	// pendingBreak is set if the next token must be on a new line.
+	pendingBreak bool
+	// parens is the depth of parentheses, as semicolons inside them don't end operators or declarations.
+	parens int
+}
+
+func (p *printer) collect(node ast.Node, parent ast.Markers) {
+	switch node := node.(type) {
+	case *ast.Leaf:
+		if node.ID != token.EOF {
+			p.leaves = append(p.leaves, leaf{Leaf: node, parent: parent})
+		}
+	case *ast.Branch:
+		for _, item := range node.Items {
+			p.collect(item, node.Markers)
+		}
+	}
+}
+
+func (p *printer) print() string {
+	for i, cur := range p.leaves {
+		var prev, next *leaf
+		if i > 0 {
+			prev = &p.leaves[i-1]
+		}
+
+		if i+1 < len(p.leaves) {
+			next = &p.leaves[i+1]
+		}
+
+		// Comments before the next section or function belong to it, so the current section is closed first.
+		if p.top(frameSection) && p.parens == 0 && startsDeclaration(cur.ID) {
+			p.frames = p.frames[:len(p.frames)-1]
+		}
+
+		p.printComments(cur.Position())
+		p.printSeparator(prev, cur)
+		p.printToken(cur)
+		p.open(cur, next)
+	}
+
+	p.printComments(literal.Position{})
+	p.sb.WriteString("\n")
+
+	return p.sb.String()
+}
+
+// printComments prints comments located before the position.
+// Comments on the same line as the previous token stay on that line, and other comments are printed on separate lines.
+func (p *printer) printComments(pos literal.Position) {
+	for len(p.comments) != 0 && (p.comments[0].start.Before(pos) || pos.Line == 0) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if p.sb.Len() != 0 && c.start.Line == p.lastLine {
+			p.sb.WriteString(" ")
+		} else {
+			p.lineBreak(p.indent(), c.start.Line)
+		}
+
+		p.sb.WriteString(c.text)
+		p.lastLine = c.endLine
+
+		if c.line || pos.Line > c.endLine {
+			p.pendingBreak = true
+		}
+	}
+}
+
+// printSeparator closes frames ended by the token and prints a line break or a space before it.
+func (p *printer) printSeparator(prev *leaf, cur leaf) {
+	switch {
+	case cur.ID == token.End:
+		f := p.close(frameBegin, frameCase, frameRecord)
+		p.lineBreak(f.base, cur.Position().Line)
+	case cur.ID == token.Until:
+		f := p.close(frameRepeat)
+		p.lineBreak(f.base, cur.Position().Line)
+	case isCaseElse(cur):
+		// Else branch is printed on the same level as the case operator.
+		for len(p.frames) != 0 && !p.top(frameCase) {
+			p.frames = p.frames[:len(p.frames)-1]
+		}
+
+		p.lineBreak(p.indent()-1, cur.Position().Line)
+	case cur.ID == token.Else:
+		f := p.closeThen()
+		p.lineBreak(f.base, cur.Position().Line)
+	case p.pendingBreak,
+		cur.ID == token.Begin,
+		p.parens == 0 && startsDeclaration(cur.ID),
+		prev != nil && prev.ID == token.End && cur.ID != token.Semicolon && cur.ID != token.Period:
+		p.lineBreak(p.indent(), cur.Position().Line)
+	case prev != nil && needsSpace(*prev, cur):
+		p.sb.WriteString(" ")
+	}
+}
+
+func (p *printer) printToken(cur leaf) {
+	// Keywords, operators and punctuation are printed in their canonical form.
+	if cur.ID.IsKeyword() || cur.ID.IsOperator() || cur.ID.IsPunctuation() {
+		p.sb.WriteString(token.ByID(cur.ID))
+	} else {
+		p.sb.WriteString(cur.Value)
+	}
+
+	p.lastLine = cur.Position().Line
+
+	switch cur.ID {
+	case token.OpeningParenthesis:
+		p.parens++
+	case token.ClosingParenthesis:
+		p.parens--
+	}
+}
+
+// open opens frames started by the token and decides whether the next token is on a new line.
+func (p *printer) open(cur leaf, next *leaf) {
+	switch {
+	case cur.ID == token.Semicolon && p.parens == 0:
+		for p.top(frameOperator) {
+			p.frames = p.frames[:len(p.frames)-1]
+		}
+
+		p.pendingBreak = true
+	case cur.ID == token.Begin:
+		p.push(frameBegin, p.lineIndent+1, false)
+		p.pendingBreak = true
+	case cur.ID == token.Repeat:
+		p.push(frameRepeat, p.lineIndent+1, false)
+		p.pendingBreak = true
+	case cur.ID == token.Record:
+		p.push(frameRecord, p.lineIndent+1, false)
+		p.pendingBreak = true
+	case cur.ID == token.Of && cur.parent.Has(ast.MarkerCase):
+		p.push(frameCase, p.lineIndent+1, false)
+		p.pendingBreak = true
+	case p.parens == 0 && (cur.ID == token.Const || cur.ID == token.Type || cur.ID == token.Var):
+		p.push(frameSection, p.lineIndent+1, false)
+		p.pendingBreak = true
+	case isCaseElse(cur):
+		p.push(frameCaseElse, p.lineIndent+1, false)
+		p.pendingBreak = true
+	case cur.ID == token.Else && next != nil && next.ID == token.If:
+		// Else if chains are printed on the same level.
+		p.push(frameOperator, p.lineIndent, false)
+	case cur.ID == token.Then, cur.ID == token.Else, cur.ID == token.Do:
+		p.openOperator(next, cur.ID == token.Then, false)
+	case cur.ID == token.Colon && cur.parent.Has(ast.MarkerCaseBranch):
+		p.openOperator(next, false, true)
+	}
+}
+
+// openOperator opens the frame of the single operator after the token.
+// Block is printed on the same level as the operator it belongs to, and other operators are indented.
+// Simple operators after case labels stay on the same line.
+func (p *printer) openOperator(next *leaf, then, label bool) {
+	indent := p.lineIndent + 1
+	if next != nil && next.ID == token.Begin && !label {
+		indent = p.lineIndent
+	}
+
+	p.push(frameOperator, indent, then)
+
+	if next == nil {
+		return
+	}
+
+	switch next.ID {
+	case token.Semicolon, token.End, token.Else, token.Until:
+		// Operator is empty.
+	case token.Begin, token.If, token.Case, token.For, token.While, token.Repeat, token.With:
+		p.pendingBreak = true
+	default:
+		p.pendingBreak = !label
+	}
+}
+
+func (p *printer) push(kind frameKind, indent int, then bool) {
+	p.frames = append(p.frames, frame{kind: kind, base: p.lineIndent, indent: indent, then: then})
+}
+
+func (p *printer) top(kind frameKind) bool {
+	return len(p.frames) != 0 && p.frames[len(p.frames)-1].kind == kind
+}
+
+// close closes frames up to and including the innermost frame of one of the kinds.
+func (p *printer) close(kinds ...frameKind) frame {
+	for len(p.frames) != 0 {
+		f := p.frames[len(p.frames)-1]
+		p.frames = p.frames[:len(p.frames)-1]
+
+		for _, kind := range kinds {
+			if f.kind == kind {
+				return f
+			}
+		}
+	}
+
+	return frame{}
+}
+
+// closeThen closes frames up to and including the operator after then of the innermost if operator.
+func (p *printer) closeThen() frame {
+	for len(p.frames) != 0 {
+		f := p.frames[len(p.frames)-1]
+		p.frames = p.frames[:len(p.frames)-1]
+
+		if f.kind == frameOperator && f.then {
+			return f
+		}
+	}
+
+	return frame{}
+}
+
+func (p *printer) indent() int {
+	if len(p.frames) == 0 {
+		return 0
+	}
+
+	return p.frames[len(p.frames)-1].indent
+}
+
+// lineBreak starts a new line with the indent. A single blank line is kept if it was in the source code.
+func (p *printer) lineBreak(indent int, line literal.LineNumber) {
+	if p.sb.Len() != 0 {
+		p.sb.WriteString("\n")
+
+		if line > p.lastLine+1 {
+			p.sb.WriteString("\n")
+		}
+	}
+
+	if indent < 0 {
+		indent = 0
+	}
+
+	p.sb.WriteString(strings.Repeat(indentation, indent))
+	p.lineIndent = indent
+	p.pendingBreak = false
+}
+
+// startsDeclaration checks whether the token starts a new section, function or block.
+func startsDeclaration(id token.ID) bool {
+	switch id {
+	case token.Const, token.Type, token.Var, token.Function, token.Procedure, token.Begin:
+		return true
+	default:
+		return false
+	}
+}
+
+func isCaseElse(l leaf) bool {
+	return (l.ID == token.Else || l.ID == token.Otherwise) && l.parent.Has(ast.MarkerCaseElse)
+}
+
+// needsSpace checks whether tokens on the same line are separated with a space.
+func needsSpace(prev, cur leaf) bool {
+	switch {
+	// Empty operator after a case label.
+	case cur.ID == token.Semicolon:
+		return prev.ID == token.Colon && prev.parent.Has(ast.MarkerCaseBranch)
+	case cur.ID == token.Comma,
+		cur.ID == token.Colon,
+		cur.ID == token.Period,
+		cur.ID == token.Range,
+		cur.ID == token.ClosingParenthesis,
+		cur.ID == token.OpeningSquareBrace,
+		cur.ID == token.ClosingSquareBrace:
+		return false
+	case prev.ID == token.OpeningParenthesis,
+		prev.ID == token.OpeningSquareBrace,
+		prev.ID == token.Period,
+		prev.ID == token.Range:
+		return false
+	// Signs aren't separated from their operands.
+	case prev.ID == token.Plus || prev.ID == token.Minus:
+		return prev.Has(ast.MarkerAdditiveOp)
+	// Calls and function headers.
+	case cur.ID == token.OpeningParenthesis:
+		return prev.ID != token.UserDefined
+	default:
+		return true
+	}
+}
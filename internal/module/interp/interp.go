@@ -0,0 +1,729 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+	"github.com/iskorotkov/compiler/internal/module/typechecker"
+)
+
+// maxCallDepth limits recursion, so infinite recursion in a program fails instead of crashing the interpreter.
+const maxCallDepth = 10000
+
+var (
+	ErrDivideByZero       = errors.New("integer divide by zero")
+	ErrIntegerOverflow    = errors.New("integer overflow")
+	ErrCallStackExhausted = errors.New("call stack exhausted")
+	ErrIndexOutOfBounds   = errors.New("index out of bounds")
+	ErrOutOfRange         = errors.New("value out of range")
+)
+
+// RuntimeError is an error which occurred during program execution.
+type RuntimeError struct {
+	Position literal.Position
+	Err      error
+}
+
+func (e RuntimeError) Error() string {
+	return fmt.Sprintf("runtime error at %v: %v", e.Position, e.Err)
+}
+
+func (e RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Interpreter executes type checked programs by walking their AST.
+//
+// It defines reference semantics of the language which doesn't depend on the code generator,
+// so it mimics generated code where the language leaves a choice (for example, ints are 32-bit wide).
+type Interpreter struct {
+	out io.Writer
+}
+
+func New(out io.Writer) *Interpreter {
+	return &Interpreter{
+		out: out,
+	}
+}
+
+// env holds variables, constants and functions of the program or function block being executed.
+// Parent is an environment of the enclosing block, so nested functions have access to variables of enclosing ones.
+type env struct {
+	values map[string]*Value
+	funcs  map[string]*typechecker.FuncResult
+	parent *env
+	depth  int
+	// scope is used to find constants in case labels.
+	scope symbol.Scope
+}
+
+func (e *env) lookupValue(name string) (*Value, bool) {
+	for e := e; e != nil; e = e.parent {
+		if v, ok := e.values[name]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// lookupFunc returns the function and the environment it was declared in.
+func (e *env) lookupFunc(name string) (*typechecker.FuncResult, *env, bool) {
+	for e := e; e != nil; e = e.parent {
+		if f, ok := e.funcs[name]; ok {
+			return f, e, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+func (i *Interpreter) Run(program typechecker.Result) error {
+	e, err := i.newEnv(nil, program, 0)
+	if err != nil {
+		return err
+	}
+
+	return i.execBlock(e, ast.Operators(program.Node))
+}
+
+// newEnv creates an environment with variables and constants of the scope and functions declared in the block.
+func (i *Interpreter) newEnv(parent *env, block typechecker.Result, depth int) (*env, error) {
+	e := &env{
+		values: map[string]*Value{},
+		funcs:  map[string]*typechecker.FuncResult{},
+		parent: parent,
+		depth:  depth,
+		scope:  block.Scope,
+	}
+
+	for _, s := range block.Scope.Symbols() {
+		switch s := s.(type) {
+		case *symbol.Var:
+			v, err := zero(s.Type)
+			if err != nil {
+				return nil, RuntimeError{Position: s.Position, Err: err}
+			}
+
+			e.values[s.Value] = &v
+		case *symbol.Const:
+			v, err := parseLiteral(s.Type.BuiltinType, s.RawValue)
+			if err != nil {
+				return nil, RuntimeError{Position: s.Position, Err: err}
+			}
+
+			e.values[s.Value] = &v
+		}
+	}
+
+	for j := range block.Funcs {
+		e.funcs[block.Funcs[j].Symbol.Value] = &block.Funcs[j]
+	}
+
+	return e, nil
+}
+
+func (i *Interpreter) execBlock(e *env, node ast.Node) error {
+	// Empty operators have no blocks.
+	if node == nil {
+		return nil
+	}
+
+	nodes := node.Query(ast.QueryTypeTop,
+		ast.MarkerAssign,
+		ast.MarkerIf,
+		ast.MarkerFor,
+		ast.MarkerWhile,
+		ast.MarkerRepeat,
+		ast.MarkerWith,
+		ast.MarkerCase,
+		ast.MarkerFuncCall,
+	)
+
+	for _, node := range nodes {
+		if err := i.exec(e, node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Interpreter) exec(e *env, node ast.Node) error {
+	switch {
+	case node.Has(ast.MarkerAssign):
+		variable := node.
+			Query(ast.QueryTypeOne, ast.MarkerLeftSide)[0]
+
+		expr := node.
+			Query(ast.QueryTypeOne, ast.MarkerRightSide)[0].
+			Query(ast.QueryTypeOne, ast.MarkerExpr)[0]
+
+		v, err := i.evalNode(e, expr)
+		if err != nil {
+			return err
+		}
+
+		target, err := i.ref(e, variable)
+		if err != nil {
+			return err
+		}
+
+		// Values assigned to subrange variables are checked, as in generated code with range checks.
+		if target.Range != nil && !target.Range.Contains(v.ordinal()) {
+			return RuntimeError{Position: variable.Position(), Err: fmt.Errorf("%w: %d", ErrOutOfRange, v.ordinal())}
+		}
+
+		store(target, v)
+		return nil
+	case node.Has(ast.MarkerIf):
+		cond, err := i.evalNode(e, node.Query(ast.QueryTypeOne, ast.MarkerExpr)[0])
+		if err != nil {
+			return err
+		}
+
+		then, otherwise := ast.IfBlocks(node)
+		if cond.Bool {
+			return i.execBlock(e, then)
+		}
+
+		return i.execBlock(e, otherwise)
+	case node.Has(ast.MarkerCase):
+		return i.execCase(e, node)
+	case node.Has(ast.MarkerFor):
+		return i.execFor(e, node)
+	case node.Has(ast.MarkerWhile):
+		cond := node.Query(ast.QueryTypeOne, ast.MarkerExpr)[0]
+		body := ast.Body(node)
+
+		for {
+			v, err := i.evalNode(e, cond)
+			if err != nil {
+				return err
+			}
+
+			if !v.Bool {
+				return nil
+			}
+
+			if err := i.execBlock(e, body); err != nil {
+				return err
+			}
+		}
+	case node.Has(ast.MarkerRepeat):
+		cond := node.Query(ast.QueryTypeOne, ast.MarkerRepeatExpr)[0]
+		body := ast.Body(node)
+
+		for {
+			if err := i.execBlock(e, body); err != nil {
+				return err
+			}
+
+			v, err := i.evalNode(e, cond)
+			if err != nil {
+				return err
+			}
+
+			if v.Bool {
+				return nil
+			}
+		}
+	case node.Has(ast.MarkerWith):
+		// Fields in the block are replaced with selectors of record variables by the type checker.
+		return i.execBlock(e, ast.Body(node))
+	case node.Has(ast.MarkerFuncCall):
+		_, err := i.call(e, node)
+		return err
+	default:
+		panic("unknown node marker")
+	}
+}
+
+// execCase executes the first branch with a label matching the selector, or the else branch if there is no such branch.
+func (i *Interpreter) execCase(e *env, node ast.Node) error {
+	selector, err := i.evalNode(e, node.Query(ast.QueryTypeOne, ast.MarkerCaseExpr)[0])
+	if err != nil {
+		return err
+	}
+
+	branches, elseBlock, err := typechecker.CaseBranches(e.scope, node)
+	if err != nil {
+		return RuntimeError{Position: node.Position(), Err: err}
+	}
+
+	v := selector.ordinal()
+	for _, branch := range branches {
+		for _, label := range branch.Labels {
+			if !label.Contains(v) {
+				continue
+			}
+
+			if branch.Block == nil {
+				return nil
+			}
+
+			return i.execBlock(e, branch.Block)
+		}
+	}
+
+	if elseBlock == nil {
+		return nil
+	}
+
+	return i.execBlock(e, elseBlock)
+}
+
+// execFor executes for loop.
+// Bounds are evaluated once before the loop, and the control variable keeps the final value after the loop.
+func (i *Interpreter) execFor(e *env, node ast.Node) error {
+	header := node.Query(ast.QueryTypeOne, ast.MarkerForHeader)[0]
+	variable := header.Query(ast.QueryTypeOne, ast.MarkerForVar)[0].(*ast.Leaf)
+	direction := header.Query(ast.QueryTypeOne, ast.MarkerForDirection)[0].(*ast.Leaf)
+	expressions := header.Query(ast.QueryTypeTop, ast.MarkerExpr)
+	body := ast.Body(node)
+
+	first, err := i.evalNode(e, expressions[0])
+	if err != nil {
+		return err
+	}
+
+	last, err := i.evalNode(e, expressions[1])
+	if err != nil {
+		return err
+	}
+
+	if err := i.assign(e, variable, first); err != nil {
+		return err
+	}
+
+	step := 1
+	if direction.ID == token.Downto {
+		step = -1
+	}
+
+	// Control variable may have any ordinal type, so it's changed by its ordinal value.
+	if (step > 0 && first.ordinal() > last.ordinal()) || (step < 0 && first.ordinal() < last.ordinal()) {
+		return nil
+	}
+
+	for {
+		if err := i.execBlock(e, body); err != nil {
+			return err
+		}
+
+		v, ok := e.lookupValue(variable.Value)
+		if !ok {
+			return RuntimeError{Position: variable.Position(), Err: fmt.Errorf("variable %s not found", variable.Value)}
+		}
+
+		// Control variable is compared before it's changed, so it doesn't overflow.
+		if v.ordinal() == last.ordinal() {
+			return nil
+		}
+
+		*v = v.withOrdinal(v.ordinal() + step)
+	}
+}
+
+func (i *Interpreter) assign(e *env, variable ast.Node, v Value) error {
+	target, err := i.ref(e, variable)
+	if err != nil {
+		return err
+	}
+
+	store(target, v)
+	return nil
+}
+
+// store converts the value to the type of the variable and stores it in place.
+// Range belongs to the variable, so it's kept as is.
+func store(target *Value, v Value) {
+	r := target.Range
+	*target = v.convert(target.Type)
+	target.Range = r
+}
+
+// call calls a user-defined or a builtin function.
+// It returns nil value for functions without result.
+func (i *Interpreter) call(e *env, call ast.Node) (*Value, error) {
+	name := call.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
+
+	f, declEnv, ok := e.lookupFunc(name.Value)
+
+	// Args passed by reference share values with variables of the caller.
+	var args []*Value
+	for j, arg := range ast.CallArgs(call) {
+		if ok && j < len(f.Symbol.Params) && f.Symbol.Params[j].ByRef {
+			ref, err := i.ref(e, arg)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, ref)
+			continue
+		}
+
+		v, err := i.evalNode(e, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, &v)
+	}
+
+	if !ok {
+		var values []Value
+		for _, arg := range args {
+			values = append(values, *arg)
+		}
+
+		return i.callBuiltin(name, values)
+	}
+
+	if e.depth+1 == maxCallDepth {
+		return nil, RuntimeError{Position: call.Position(), Err: ErrCallStackExhausted}
+	}
+
+	if len(args) != len(f.Symbol.Params) {
+		return nil, RuntimeError{Position: call.Position(), Err: fmt.Errorf("wrong number of arguments for function %s", name.Value)}
+	}
+
+	// Function environment is nested into the environment the function was declared in,
+	// so the function sees variables of the enclosing blocks, not of the caller.
+	fe, err := i.newEnv(declEnv, f.Result, e.depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, param := range f.Symbol.Params {
+		if param.ByRef {
+			fe.values[param.Value] = args[j]
+			continue
+		}
+
+		v := args[j].convert(param.Type.BuiltinType)
+		v.Range = param.Type.Range
+		fe.values[param.Value] = &v
+	}
+
+	if err := i.execBlock(fe, ast.Operators(f.Node)); err != nil {
+		return nil, err
+	}
+
+	// Function result is stored in a variable with the function name.
+	return fe.values[f.Symbol.Value], nil
+}
+
+// ref returns the variable or the array element, so it can be changed in place.
+// It's used for assignments and for arguments passed by reference.
+func (i *Interpreter) ref(e *env, node ast.Node) (*Value, error) {
+	tree, err := ast.NewExpr(node)
+	if err != nil {
+		return nil, RuntimeError{Position: node.Position(), Err: err}
+	}
+
+	if !tree.IsOperand() || tree.IsCall() {
+		return nil, RuntimeError{Position: node.Position(), Err: fmt.Errorf("expected variable, got %v", tree)}
+	}
+
+	v, ok := e.lookupValue(tree.Leaf.Value)
+	if !ok {
+		return nil, RuntimeError{Position: node.Position(), Err: fmt.Errorf("variable %s not found", tree.Leaf.Value)}
+	}
+
+	for _, selector := range ast.Selectors(tree.Variable) {
+		if selector.Has(ast.MarkerField) {
+			name := selector.(*ast.Leaf)
+
+			field, ok := v.Fields[name.Value]
+			if !ok {
+				return nil, RuntimeError{Position: name.Position(), Err: fmt.Errorf("field %s not found", name.Value)}
+			}
+
+			v = field
+			continue
+		}
+
+		index := selector
+		idx, err := i.evalNode(e, index)
+		if err != nil {
+			return nil, err
+		}
+
+		j := int(idx.Int) - v.Low
+		if j < 0 || j >= len(v.Elems) {
+			return nil, RuntimeError{Position: index.Position(), Err: fmt.Errorf("%w: %d", ErrIndexOutOfBounds, idx.Int)}
+		}
+
+		v = &v.Elems[j]
+	}
+
+	return v, nil
+}
+
+// callBuiltin calls writeln or one of ordinal builtins.
+// It returns nil value for functions without result.
+func (i *Interpreter) callBuiltin(name *ast.Leaf, args []Value) (*Value, error) {
+	switch name.Value {
+	case "writeln":
+		var strs []string
+		for _, arg := range args {
+			strs = append(strs, arg.String())
+		}
+
+		if _, err := fmt.Fprintln(i.out, strings.Join(strs, " ")); err != nil {
+			return nil, RuntimeError{Position: name.Position(), Err: err}
+		}
+
+		return nil, nil
+	case "ord":
+		v := Int(int32(args[0].ordinal()))
+		return &v, nil
+	case "succ":
+		v := args[0].withOrdinal(args[0].ordinal() + 1)
+		return &v, nil
+	case "pred":
+		v := args[0].withOrdinal(args[0].ordinal() - 1)
+		return &v, nil
+	default:
+		return nil, RuntimeError{Position: name.Position(), Err: fmt.Errorf("function %s not found", name.Value)}
+	}
+}
+
+func (i *Interpreter) evalNode(e *env, node ast.Node) (Value, error) {
+	tree, err := ast.NewExpr(node)
+	if err != nil {
+		return Value{}, RuntimeError{Position: node.Position(), Err: err}
+	}
+
+	return i.eval(e, tree)
+}
+
+func (i *Interpreter) eval(e *env, tree *ast.Expr) (Value, error) {
+	switch {
+	case tree.IsCall():
+		v, err := i.call(e, tree.Call)
+		if err != nil {
+			return Value{}, err
+		}
+
+		if v == nil {
+			return Value{}, RuntimeError{Position: tree.Position(), Err: fmt.Errorf("function %s doesn't return a value", tree.Leaf.Value)}
+		}
+
+		return *v, nil
+	case tree.HasSelectors():
+		v, err := i.ref(e, tree.Variable)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return *v, nil
+	case tree.IsOperand():
+		return i.evalOperand(e, tree.Leaf)
+	case tree.IsUnary():
+		return i.evalUnary(e, tree)
+	}
+
+	left, err := i.eval(e, tree.Left)
+	if err != nil {
+		return Value{}, err
+	}
+
+	right, err := i.eval(e, tree.Right)
+	if err != nil {
+		return Value{}, err
+	}
+
+	v, err := binaryOp(tree.Leaf.ID, left, right)
+	if err != nil {
+		return Value{}, RuntimeError{Position: tree.Position(), Err: err}
+	}
+
+	return v, nil
+}
+
+func (i *Interpreter) evalOperand(e *env, leaf *ast.Leaf) (Value, error) {
+	var (
+		v   Value
+		err error
+	)
+
+	switch leaf.ID {
+	case token.BoolLiteral:
+		v, err = parseLiteral(symbol.BuiltinTypeBool, leaf.Value)
+	case token.IntLiteral:
+		v, err = parseLiteral(symbol.BuiltinTypeInt, leaf.Value)
+	case token.DoubleLiteral:
+		v, err = parseLiteral(symbol.BuiltinTypeDouble, leaf.Value)
+	case token.StringLiteral:
+		v, err = parseLiteral(typechecker.StringLiteralType(leaf.Value), leaf.Value)
+	case token.UserDefined:
+		value, ok := e.lookupValue(leaf.Value)
+		if !ok {
+			err = fmt.Errorf("symbol %s not found", leaf.Value)
+			break
+		}
+
+		v = *value
+	default:
+		err = fmt.Errorf("unexpected token id: %s", leaf.ID)
+	}
+
+	if err != nil {
+		return Value{}, RuntimeError{Position: leaf.Position(), Err: err}
+	}
+
+	return v, nil
+}
+
+func (i *Interpreter) evalUnary(e *env, tree *ast.Expr) (Value, error) {
+	if tree.IsNegatedIntLiteral() {
+		v, err := parseLiteral(symbol.BuiltinTypeInt, "-"+tree.Right.Leaf.Value)
+		if err != nil {
+			return Value{}, RuntimeError{Position: tree.Position(), Err: err}
+		}
+
+		return v, nil
+	}
+
+	v, err := i.eval(e, tree.Right)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch {
+	case tree.Leaf.ID == token.Not && v.Type == symbol.BuiltinTypeBool:
+		return Bool(!v.Bool), nil
+	case tree.Leaf.ID == token.Minus && v.Type == symbol.BuiltinTypeInt:
+		return Int(-v.Int), nil
+	case tree.Leaf.ID == token.Minus && v.Type == symbol.BuiltinTypeDouble:
+		return Double(-v.Double), nil
+	case tree.Leaf.ID == token.Plus && v.Type != symbol.BuiltinTypeBool:
+		return v, nil
+	default:
+		return Value{}, RuntimeError{Position: tree.Position(), Err: fmt.Errorf("unsupported operation %s %s", tree.Leaf.Value, v.Type)}
+	}
+}
+
+func binaryOp(op token.ID, left, right Value) (Value, error) {
+	// Ints are converted to doubles if the other operand is double.
+	if left.Type == symbol.BuiltinTypeDouble || right.Type == symbol.BuiltinTypeDouble {
+		left, right = left.convert(symbol.BuiltinTypeDouble), right.convert(symbol.BuiltinTypeDouble)
+	}
+
+	if left.Type != right.Type {
+		return Value{}, fmt.Errorf("incompatible operand types %s and %s", left.Type, right.Type)
+	}
+
+	switch left.Type {
+	case symbol.BuiltinTypeInt:
+		return binaryOpInt(op, left.Int, right.Int)
+	case symbol.BuiltinTypeDouble:
+		return binaryOpDouble(op, left.Double, right.Double)
+	case symbol.BuiltinTypeBool:
+		return binaryOpBool(op, left.Bool, right.Bool)
+	case symbol.BuiltinTypeChar, symbol.BuiltinTypeEnum:
+		// Chars and enums are compared by their ordinal values.
+		return binaryOpInt(op, left.Int, right.Int)
+	default:
+		return Value{}, fmt.Errorf("unsupported operand type %s", left.Type)
+	}
+}
+
+func binaryOpInt(op token.ID, l, r int32) (Value, error) {
+	switch op {
+	case token.Plus:
+		return Int(l + r), nil
+	case token.Minus:
+		return Int(l - r), nil
+	case token.Multiply:
+		return Int(l * r), nil
+	case token.Divide, token.Div:
+		if r == 0 {
+			return Value{}, ErrDivideByZero
+		}
+
+		if l == math.MinInt32 && r == -1 {
+			return Value{}, ErrIntegerOverflow
+		}
+
+		return Int(l / r), nil
+	case token.Mod:
+		if r == 0 {
+			return Value{}, ErrDivideByZero
+		}
+
+		if r == -1 {
+			return Int(0), nil
+		}
+
+		return Int(l % r), nil
+	case token.Eq:
+		return Bool(l == r), nil
+	case token.Ne:
+		return Bool(l != r), nil
+	case token.Lt:
+		return Bool(l < r), nil
+	case token.Lte:
+		return Bool(l <= r), nil
+	case token.Gt:
+		return Bool(l > r), nil
+	case token.Gte:
+		return Bool(l >= r), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported operation %s for ints", op)
+	}
+}
+
+func binaryOpDouble(op token.ID, l, r float64) (Value, error) {
+	switch op {
+	case token.Plus:
+		return Double(l + r), nil
+	case token.Minus:
+		return Double(l - r), nil
+	case token.Multiply:
+		return Double(l * r), nil
+	case token.Divide:
+		return Double(l / r), nil
+	case token.Eq:
+		return Bool(l == r), nil
+	case token.Ne:
+		return Bool(l != r), nil
+	case token.Lt:
+		return Bool(l < r), nil
+	case token.Lte:
+		return Bool(l <= r), nil
+	case token.Gt:
+		return Bool(l > r), nil
+	case token.Gte:
+		return Bool(l >= r), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported operation %s for doubles", op)
+	}
+}
+
+func binaryOpBool(op token.ID, l, r bool) (Value, error) {
+	// Both operands are always evaluated, so there is no short-circuit evaluation.
+	switch op {
+	case token.And:
+		return Bool(l && r), nil
+	case token.Or:
+		return Bool(l || r), nil
+	case token.Xor:
+		return Bool(l != r), nil
+	case token.Eq:
+		return Bool(l == r), nil
+	case token.Ne:
+		return Bool(l != r), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported operation %s for bools", op)
+	}
+}
@@ -0,0 +1,361 @@
+package interp_test
+
+import (
+	"bytes"
+	stdcontext "context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/module/interp"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
+	"github.com/iskorotkov/compiler/internal/module/syntax_analyzer"
+	"github.com/iskorotkov/compiler/internal/module/typechecker"
+	"github.com/iskorotkov/compiler/internal/module/vm"
+	"github.com/iskorotkov/compiler/internal/module/wasm"
+	"github.com/iskorotkov/compiler/testdata/programs"
+)
+
+type test struct {
+	name     string
+	input    string
+	expected string
+}
+
+var tests = []test{
+	{
+		name:     "arrays program",
+		input:    programs.Arrays,
+		expected: "3\n15\n52963\n7\n21.5\n30.5\n1\n0\nhello\n\n100\n",
+	},
+	{
+		name:     "case program",
+		input:    programs.Case,
+		expected: "other\nminus one\nzero\none or two\none or two\nthree to five\nthree to five\nthree to five\nother\nother\nother\nother\ntop\nother\n1\n100\n1000\n0\nb to d\nyes\n211\n",
+	},
+	{
+		name:     "const expressions program",
+		input:    programs.ConstExpressions,
+		expected: "6.28318\n-10\n314.159\n1000000\n6\n1\nx\nit's\n1\n-14\n5\n",
+	},
+	{
+		name:     "enums program",
+		input:    programs.Enums,
+		expected: "1\n2\n0\n1\n0\nred\ngreen\nblue\n2\n1\n45\n113\nr\n1\n1\n1\n42\n18\n",
+	},
+	{
+		name:     "for program",
+		input:    programs.For,
+		expected: "10\n3\n2\n1\n10\n",
+	},
+	{
+		name:     "if program",
+		input:    programs.If,
+		expected: "100\n",
+	},
+	{
+		name:     "locals program",
+		input:    programs.Locals,
+		expected: "11\n",
+	},
+	{
+		name:     "math program",
+		input:    programs.Math,
+		expected: "100.48\n",
+	},
+	{
+		name:     "procedures program",
+		input:    programs.Procedures,
+		expected: "2\n5\n5\n",
+	},
+	{
+		name:     "records program",
+		input:    programs.Records,
+		expected: "25\n4\n3\n3.5\n0\n0\n200\n150\n1\npoints\n1\n1\n",
+	},
+	{
+		name:     "recursion program",
+		input:    programs.Recursion,
+		expected: "120\n1\n",
+	},
+	{
+		name:     "repeat program",
+		input:    programs.Repeat,
+		expected: "7\n8\n",
+	},
+	{
+		name:     "strings program",
+		input:    programs.Strings,
+		expected: "Hello\nit's\nHello\n!\n!\nF\n1\n\n",
+	},
+	{
+		name:     "var params program",
+		input:    programs.VarParams,
+		expected: "2\n1\n70\n3\n2\n12\n120\n",
+	},
+	{
+		name:     "while program",
+		input:    programs.While,
+		expected: "0\n1\n1\n2\n2\n4\n3\n8\n4\n16\n5\n32\n",
+	},
+	{
+		name: "expressions",
+		input: `program p;
+var b: boolean;
+begin
+  writeln(7 div 2 + 7 mod 2 * 10);
+  writeln(-7 / 2.0);
+  b := not (1 < 2) or (2.5 >= 2);
+  writeln(b);
+end.`,
+		expected: "13\n-3.5\n1\n",
+	},
+	{
+		name: "nested functions",
+		input: `program p;
+var n: integer;
+function outer(a: integer): integer;
+var b: integer;
+function inner(x: integer): integer;
+begin
+  inner := a + b + x;
+end
+begin
+  b := 10;
+  outer := inner(100);
+end
+begin
+  n := outer(1);
+  writeln(n);
+end.`,
+		expected: "111\n",
+	},
+	{
+		name: "nested functions with recursion",
+		input: `program p;
+type list = array[1..3] of integer;
+var total: integer;
+    items: list;
+procedure count(depth: integer; var sum: integer; var xs: list);
+var level: integer;
+procedure visit(i: integer);
+begin
+  sum := sum + xs[i] * level;
+  level := level + 1;
+  if i < 3 then
+    visit(i + 1);
+end
+begin
+  level := depth;
+  if depth < 2 then
+    count(depth + 1, sum, xs);
+  visit(1);
+  writeln(level);
+end
+begin
+  items[1] := 1;
+  items[2] := 10;
+  items[3] := 100;
+  total := 0;
+  count(0, total, items);
+  writeln(total);
+end.`,
+		expected: "5\n4\n3\n963\n",
+	},
+	{
+		name: "empty bodies",
+		input: `program p;
+var
+  x, i: integer;
+begin
+  x := 1;
+  if x > 0 then ;
+  if x > 0 then else x := 2;
+  if x > 9 then x := 3 else ;
+  for i := 0 to 9 do ;
+  while x > 9 do ;
+  repeat until x > 0;
+  writeln(x);
+  writeln(i);
+end.`,
+		expected: "1\n9\n",
+	},
+	{
+		name: "smallest int literal",
+		input: `program p;
+const
+  MIN = -2147483648;
+var
+  x: integer;
+begin
+  x := -2147483648;
+  writeln(x);
+  writeln(MIN + 1);
+  writeln(x div 2);
+end.`,
+		expected: "-2147483648\n-2147483647\n-1073741824\n",
+	},
+	{
+		name: "deep recursion with local arrays",
+		input: `program p;
+procedure deep(n: integer);
+var
+  a: array[1..5000] of integer;
+begin
+  a[5000] := n;
+  if n < 100 then
+    deep(n + 1);
+  if n mod 25 = 0 then
+    writeln(a[5000]);
+end
+begin
+  deep(1);
+end.`,
+		expected: "100\n75\n50\n25\n",
+	},
+}
+
+func TestInterpreter_Run(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			err := interp.New(&out).Run(check(t, test.input))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, out.String())
+		})
+	}
+}
+
+// TestInterpreter_RunDifferential checks that compiled programs produce the same output as interpreted ones.
+func TestInterpreter_RunDifferential(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var interpreted bytes.Buffer
+			err := interp.New(&interpreted).Run(check(t, test.input))
+			assert.NoError(t, err)
+
+			ctx := context.NewEnvContext(stdcontext.Background())
+			results := make(chan typechecker.Result, 1)
+			results <- check(t, test.input)
+			close(results)
+
+			m, ok := <-wasm.NewGenerator().Generate(ctx, results)
+			assert.True(t, ok)
+			assert.Empty(t, ctx.Errors())
+
+			var compiled bytes.Buffer
+			err = vm.New(vm.ConsoleImports(&compiled)).Run(m, "main")
+			assert.NoError(t, err)
+
+			assert.Equal(t, interpreted.String(), compiled.String())
+		})
+	}
+}
+
+func TestInterpreter_RunErrors(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+var x: integer;
+begin
+  x := 0;
+  writeln(1);
+  writeln(10 div x);
+end.`
+
+	var out bytes.Buffer
+	err := interp.New(&out).Run(check(t, input))
+	assert.ErrorIs(t, err, interp.ErrDivideByZero)
+	assert.EqualError(t, err, "runtime error at 6:11-19: integer divide by zero")
+	assert.Equal(t, "1\n", out.String())
+}
+
+func TestInterpreter_RunIndexErrors(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+var a: array[1..3] of integer; i: integer;
+begin
+  i := 4;
+  writeln(1);
+  a[i] := 10;
+end.`
+
+	var out bytes.Buffer
+	err := interp.New(&out).Run(check(t, input))
+	assert.ErrorIs(t, err, interp.ErrIndexOutOfBounds)
+	assert.EqualError(t, err, "runtime error at 6:5: index out of bounds: 4")
+	assert.Equal(t, "1\n", out.String())
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+	results := make(chan typechecker.Result, 1)
+	results <- check(t, input)
+	close(results)
+
+	m, ok := <-wasm.NewGenerator().Generate(ctx, results)
+	assert.True(t, ok)
+
+	var compiled bytes.Buffer
+	err = vm.New(vm.ConsoleImports(&compiled)).Run(m, "main")
+	assert.ErrorIs(t, err, vm.ErrIndexOutOfBounds)
+	assert.EqualError(t, err, "index out of bounds: 4 at 6:5")
+	assert.Equal(t, "1\n", compiled.String())
+}
+
+func TestInterpreter_RunRangeErrors(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+var d: 0..9; i: integer;
+begin
+  i := 10;
+  d := i - 1;
+  writeln(d);
+  d := i;
+end.`
+
+	var out bytes.Buffer
+	err := interp.New(&out).Run(check(t, input))
+	assert.ErrorIs(t, err, interp.ErrOutOfRange)
+	assert.EqualError(t, err, "runtime error at 7:3: value out of range: 10")
+	assert.Equal(t, "9\n", out.String())
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+	results := make(chan typechecker.Result, 1)
+	results <- check(t, input)
+	close(results)
+
+	m, ok := <-wasm.NewGenerator().Generate(ctx, results)
+	assert.True(t, ok)
+
+	var compiled bytes.Buffer
+	err = vm.New(vm.ConsoleImports(&compiled)).Run(m, "main")
+	assert.ErrorIs(t, err, vm.ErrOutOfRange)
+	assert.EqualError(t, err, "value out of range: 10 at 7:3")
+	assert.Equal(t, "9\n", compiled.String())
+}
+
+func check(t *testing.T, input string) typechecker.Result {
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	literals := reader.New(0).Read(ctx, strings.NewReader(input))
+	tokens := scanner.New(0).Scan(ctx, literals)
+	programs := syntax_analyzer.New(0).Analyze(ctx, tokens)
+	result := <-typechecker.NewTypeChecker(0).Check(ctx, programs)
+
+	assert.Empty(t, ctx.Errors())
+	return result
+}
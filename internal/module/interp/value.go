@@ -0,0 +1,193 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+// Value is a value of a variable, a constant or an expression.
+// Only the field matching the type is used.
+type Value struct {
+	Type   symbol.BuiltinType
+	Int    int32
+	Double float64
+	Bool   bool
+	// Str is a value of a string.
+	// Chars store their codes in Int.
+	Str string
+	// Elems are elements of an array, and Low is an index of the first element.
+	Elems []Value
+	Low   int
+	// Fields are fields of a record by name.
+	Fields map[string]*Value
+	// Range is a range of the subrange variable the value is stored in.
+	// It belongs to the variable, so it isn't changed by assignments.
+	Range *symbol.Range
+}
+
+func Int(v int32) Value {
+	return Value{Type: symbol.BuiltinTypeInt, Int: v}
+}
+
+func Double(v float64) Value {
+	return Value{Type: symbol.BuiltinTypeDouble, Double: v}
+}
+
+func Bool(v bool) Value {
+	return Value{Type: symbol.BuiltinTypeBool, Bool: v}
+}
+
+func String(v string) Value {
+	return Value{Type: symbol.BuiltinTypeString, Str: v}
+}
+
+func Char(v byte) Value {
+	return Value{Type: symbol.BuiltinTypeChar, Int: int32(v)}
+}
+
+// String formats the value the same way as writeln in generated WASM code does,
+// so output of the interpreter can be compared with output of compiled programs.
+func (v Value) String() string {
+	switch v.Type {
+	case symbol.BuiltinTypeInt:
+		return strconv.FormatInt(int64(v.Int), 10)
+	case symbol.BuiltinTypeDouble:
+		return strconv.FormatFloat(v.Double, 'f', -1, 64)
+	case symbol.BuiltinTypeBool:
+		// Bools are passed to writeln as ints.
+		if v.Bool {
+			return "1"
+		}
+
+		return "0"
+	case symbol.BuiltinTypeString:
+		return v.Str
+	case symbol.BuiltinTypeChar:
+		return string([]byte{byte(v.Int)})
+	case symbol.BuiltinTypeEnum:
+		// Enums are passed to writeln as their ordinal values.
+		return strconv.FormatInt(int64(v.Int), 10)
+	default:
+		return fmt.Sprintf("<%s value>", v.Type)
+	}
+}
+
+// ordinal returns an ordinal value of the int, char, bool or enum value.
+func (v Value) ordinal() int {
+	if v.Type == symbol.BuiltinTypeBool {
+		if v.Bool {
+			return 1
+		}
+
+		return 0
+	}
+
+	return int(v.Int)
+}
+
+// withOrdinal returns a value of the same type with the given ordinal value.
+func (v Value) withOrdinal(ordinal int) Value {
+	if v.Type == symbol.BuiltinTypeBool {
+		v.Bool = ordinal != 0
+		return v
+	}
+
+	v.Int = int32(ordinal)
+	return v
+}
+
+// convert converts the value to the type of variable or param it's assigned to.
+func (v Value) convert(t symbol.BuiltinType) Value {
+	if v.Type == symbol.BuiltinTypeInt && t == symbol.BuiltinTypeDouble {
+		return Double(float64(v.Int))
+	}
+
+	if v.Type == symbol.BuiltinTypeChar && t == symbol.BuiltinTypeString {
+		return String(v.String())
+	}
+
+	return v
+}
+
+func zero(t symbol.Type) (Value, error) {
+	switch t.BuiltinType {
+	case symbol.BuiltinTypeInt, symbol.BuiltinTypeDouble, symbol.BuiltinTypeBool, symbol.BuiltinTypeString, symbol.BuiltinTypeChar, symbol.BuiltinTypeEnum:
+		return Value{Type: t.BuiltinType, Range: t.Range}, nil
+	case symbol.BuiltinTypeArray:
+		elems := make([]Value, t.Array.Len())
+		for i := range elems {
+			elem, err := zero(t.Array.Elem)
+			if err != nil {
+				return Value{}, err
+			}
+
+			elems[i] = elem
+		}
+
+		return Value{Type: t.BuiltinType, Elems: elems, Low: t.Array.Low}, nil
+	case symbol.BuiltinTypeRecord:
+		fields := make(map[string]*Value, len(t.Record.Fields))
+		for _, f := range t.Record.Fields {
+			field, err := zero(f.Type)
+			if err != nil {
+				return Value{}, err
+			}
+
+			fields[f.Name] = &field
+		}
+
+		return Value{Type: t.BuiltinType, Fields: fields}, nil
+	default:
+		return Value{}, fmt.Errorf("unsupported type %s", t.BuiltinType)
+	}
+}
+
+// parseLiteral parses a value of a literal or a constant.
+func parseLiteral(t symbol.BuiltinType, raw string) (Value, error) {
+	switch t {
+	case symbol.BuiltinTypeInt:
+		v, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid int value %s: %w", raw, err)
+		}
+
+		return Int(int32(v)), nil
+	case symbol.BuiltinTypeEnum:
+		// Enum constants are stored as their ordinal values.
+		v, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid enum value %s: %w", raw, err)
+		}
+
+		return Value{Type: t, Int: int32(v)}, nil
+	case symbol.BuiltinTypeDouble:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid double value %s: %w", raw, err)
+		}
+
+		return Double(v), nil
+	case symbol.BuiltinTypeBool:
+		return Bool(raw == "true"), nil
+	case symbol.BuiltinTypeString, symbol.BuiltinTypeChar:
+		v, err := token.Unquote(raw)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid string value %s: %w", raw, err)
+		}
+
+		if t == symbol.BuiltinTypeChar {
+			if len(v) != 1 {
+				return Value{}, fmt.Errorf("invalid char value %s", raw)
+			}
+
+			return Char(v[0]), nil
+		}
+
+		return String(v), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported type %s", t)
+	}
+}
@@ -0,0 +1,282 @@
+package lsp
+
+import (
+	stdcontext "context"
+	"sort"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
+	"github.com/iskorotkov/compiler/internal/module/syntax_analyzer"
+	"github.com/iskorotkov/compiler/internal/module/typechecker"
+)
+
+// document contains results of the analysis of the opened file.
+type document struct {
+	uri         string
+	source      *literal.Source
+	diagnostics []context.Error
+	// idents contains names used in the program with scopes they are resolved in.
+	idents []ident
+	// blocks contains scopes of the program and functions, and the program block is the first one.
+	blocks []block
+}
+
+type ident struct {
+	leaf  *ast.Leaf
+	scope symbol.Scope
+}
+
+type block struct {
+	first, last literal.Position
+	scope       symbol.Scope
+}
+
+// analyze runs all modules of the compiler except the code generator on the text.
+// Program has syntax errors if it can't be parsed at all, and in this case only diagnostics are available.
+func analyze(uri, text string) *document {
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	buffer := 0
+
+	literals := reader.New(buffer).Read(ctx, strings.NewReader(text))
+	tokens := scanner.New(buffer).Scan(ctx, literals)
+	programs := syntax_analyzer.New(buffer).Analyze(ctx, tokens)
+	results := typechecker.NewTypeChecker(buffer).Check(ctx, programs)
+
+	doc := &document{uri: uri, source: ctx.Source()}
+
+	for result := range results {
+		block := result.Node.Query(ast.QueryTypeOne, ast.MarkerProgramBlock)[0]
+		doc.addBlock(result.Scope, block)
+		doc.addFuncs(result.Funcs)
+	}
+
+	doc.diagnostics = ctx.Diagnostics()
+
+	return doc
+}
+
+func (d *document) addFuncs(funcs []typechecker.FuncResult) {
+	for _, f := range funcs {
+		d.addBlock(f.Scope, f.Node)
+		d.addFuncs(f.Funcs)
+	}
+}
+
+func (d *document) addBlock(scope symbol.Scope, root ast.Node) {
+	b := block{scope: scope}
+	d.collect(scope, root, root, &b)
+	d.blocks = append(d.blocks, b)
+}
+
+// collect adds names of the block. Nested functions are skipped, as they are added with their own scopes.
+func (d *document) collect(scope symbol.Scope, root, node ast.Node, b *block) {
+	switch node := node.(type) {
+	case *ast.Leaf:
+		pos := node.Position()
+		if pos.Line == 0 {
+			return
+		}
+
+		if b.first == (literal.Position{}) || pos.Before(b.first) {
+			b.first = pos
+		}
+
+		if pos.After(b.last) || pos == b.last {
+			b.last = pos
+		}
+
+		if node.ID == token.UserDefined && !node.Has(ast.MarkerField) {
+			d.idents = append(d.idents, ident{leaf: node, scope: scope})
+		}
+	case *ast.Branch:
+		if node != root && node.Has(ast.MarkerFuncDecl) {
+			return
+		}
+
+		for _, item := range node.Items {
+			d.collect(scope, root, item, b)
+		}
+	}
+}
+
+// symbolAt returns the symbol with the name under the cursor and the position of the name.
+// Cursor right after the name is treated as on the name, as editors place it there after typing.
+func (d *document) symbolAt(p position) (symbol.Symbol, literal.Position, bool) {
+	pos, ok := d.fromLSP(p)
+	if !ok {
+		return nil, literal.Position{}, false
+	}
+
+	var found *ident
+	for i, id := range d.idents {
+		leafPos := id.leaf.Position()
+		if leafPos.Line != pos.Line || pos.StartCol < leafPos.StartCol || pos.StartCol > leafPos.EndCol {
+			continue
+		}
+
+		found = &d.idents[i]
+		if pos.StartCol < leafPos.EndCol {
+			break
+		}
+	}
+
+	if found == nil {
+		return nil, literal.Position{}, false
+	}
+
+	// Function result variables hide functions, but calls and headers always refer to functions.
+	if found.leaf.Has(ast.MarkerFuncName) {
+		if f, ok := found.scope.LookupFunc(found.leaf.Value); ok {
+			return f, found.leaf.Position(), true
+		}
+	}
+
+	s, ok := found.scope.Lookup(&symbol.Name{Name: found.leaf.Value})
+	return s, found.leaf.Position(), ok
+}
+
+// symbolsAt returns symbols visible at the cursor sorted by name. Symbols of inner scopes hide symbols of outer ones.
+func (d *document) symbolsAt(p position) []symbol.Symbol {
+	pos, ok := d.fromLSP(p)
+	if !ok || len(d.blocks) == 0 {
+		return nil
+	}
+
+	// Functions are added after their enclosing blocks, so the last block containing the cursor is the innermost one.
+	scope := d.blocks[0].scope
+	for _, b := range d.blocks[1:] {
+		if !pos.Before(b.first) && !pos.After(b.last) {
+			scope = b.scope
+		}
+	}
+
+	seen := map[string]bool{}
+
+	var res []symbol.Symbol
+	for s := &scope; s != nil; s = s.ParentScope() {
+		for _, sym := range s.Symbols() {
+			name := symbolName(sym)
+			if name == "" || seen[name] {
+				continue
+			}
+
+			seen[name] = true
+			res = append(res, sym)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return symbolName(res[i]) < symbolName(res[j])
+	})
+
+	return res
+}
+
+// toLSP returns the range of the position. Columns of the compiler are bytes, and columns of LSP are UTF-16 code units.
+func (d *document) toLSP(p literal.Position) rangeLSP {
+	// Some diagnostics (for example, skipped code generation) don't refer to the source code.
+	if p.Line == 0 {
+		return rangeLSP{}
+	}
+
+	line, _ := d.source.Line(p.Line)
+
+	end := p.EndCol
+	if end <= p.StartCol {
+		end = p.StartCol + 1
+	}
+
+	return rangeLSP{
+		Start: position{Line: int(p.Line) - 1, Character: utf16Len(line, int(p.StartCol)-1)},
+		End:   position{Line: int(p.Line) - 1, Character: utf16Len(line, int(end)-1)},
+	}
+}
+
+// fromLSP returns the position of the character under the cursor.
+func (d *document) fromLSP(p position) (literal.Position, bool) {
+	n := literal.LineNumber(p.Line + 1)
+
+	line, ok := d.source.Line(n)
+	if !ok {
+		return literal.Position{}, false
+	}
+
+	col := literal.ColNumber(byteLen(line, p.Character) + 1)
+	return literal.Position{Line: n, StartCol: col, EndCol: col + 1}, true
+}
+
+// utf16Len returns the number of UTF-16 code units in the first n bytes of the line.
+func utf16Len(line string, n int) int {
+	if n > len(line) {
+		n = len(line)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	res := 0
+	for _, r := range line[:n] {
+		res += runeUnits(r)
+	}
+
+	return res
+}
+
+// byteLen returns the number of bytes in the first n UTF-16 code units of the line.
+func byteLen(line string, n int) int {
+	units := 0
+	for i, r := range line {
+		if units >= n {
+			return i
+		}
+
+		units += runeUnits(r)
+	}
+
+	return len(line)
+}
+
+// runeUnits returns the number of UTF-16 code units of the rune. Runes outside of the BMP are encoded as surrogate pairs.
+func runeUnits(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
+
+func symbolName(s symbol.Symbol) string {
+	switch s := s.(type) {
+	case *symbol.Var:
+		return s.Value
+	case *symbol.Const:
+		return s.Value
+	case *symbol.Type:
+		return s.Value
+	case *symbol.Func:
+		return s.Value
+	default:
+		return ""
+	}
+}
+
+func completionKind(s symbol.Symbol) int {
+	switch s.(type) {
+	case *symbol.Const:
+		return completionItemKindConstant
+	case *symbol.Type:
+		return completionItemKindClass
+	case *symbol.Func:
+		return completionItemKindFunction
+	default:
+		return completionItemKindVariable
+	}
+}
@@ -0,0 +1,305 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/module/report"
+)
+
+var ErrExitWithoutShutdown = errors.New("exit notification received before shutdown request")
+
+// Server is a language server which communicates with the editor over the base protocol of LSP.
+// Documents are analyzed again on every change, and requests are processed one by one in order of arrival.
+type Server struct {
+	w        io.Writer
+	docs     map[string]*document
+	shutdown bool
+}
+
+func New() *Server {
+	return &Server{
+		docs: map[string]*document{},
+	}
+}
+
+// Serve processes messages until the exit notification is received or the input is closed.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.w = w
+
+	br := bufio.NewReader(r)
+	for {
+		content, err := readMessage(br)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(content, &msg); err != nil {
+			if err := s.reply(nil, nil, &responseError{Code: errorParse, Message: err.Error()}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+
+			return nil
+		}
+
+		// Errors of requests are sent to the editor, and other errors mean that the output is broken.
+		result, err := s.handle(msg)
+
+		var respErr *responseError
+		if err != nil && !errors.As(err, &respErr) {
+			return err
+		}
+
+		// Notifications don't have IDs and don't need responses.
+		if msg.ID == nil {
+			continue
+		}
+
+		if err := s.reply(msg.ID, result, respErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg message) (interface{}, error) {
+	if s.shutdown && msg.Method != "shutdown" {
+		return nil, &responseError{Code: errorInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var res initializeResult
+		res.Capabilities.TextDocumentSync = textDocumentSyncFull
+		res.Capabilities.HoverProvider = true
+		res.Capabilities.DefinitionProvider = true
+		res.Capabilities.CodeActionProvider = true
+		res.ServerInfo.Name = "compiler"
+
+		return res, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		// Documents are synchronized in full, so the last change contains the whole text.
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		delete(s.docs, params.TextDocument.URI)
+
+		// Diagnostics of closed documents are cleared, as they aren't updated anymore.
+		return nil, s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return s.hover(params), nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return s.definition(params), nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return s.completion(params), nil
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return s.codeActions(params), nil
+	default:
+		return nil, &responseError{Code: errorMethodNotFound, Message: fmt.Sprintf("method %s is not supported", msg.Method)}
+	}
+}
+
+// update analyzes the new text of the document and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	doc := analyze(uri, text)
+	s.docs[uri] = doc
+
+	diagnostics := []diagnostic{}
+	for _, d := range doc.diagnostics {
+		diagnostics = append(diagnostics, doc.diagnostic(d))
+	}
+
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	})
+}
+
+func (s *Server) hover(params textDocumentPositionParams) interface{} {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+
+	sym, pos, ok := doc.symbolAt(params.Position)
+	if !ok {
+		return nil
+	}
+
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: "```pascal\n" + symbol.Describe(sym) + "\n```"},
+		Range:    doc.toLSP(pos),
+	}
+}
+
+func (s *Server) definition(params textDocumentPositionParams) interface{} {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+
+	sym, _, ok := doc.symbolAt(params.Position)
+	if !ok {
+		return nil
+	}
+
+	// Builtin symbols aren't declared in the source code.
+	pos := symbol.Position(sym)
+	if pos == (literal.Position{}) {
+		return nil
+	}
+
+	return location{URI: doc.uri, Range: doc.toLSP(pos)}
+}
+
+func (s *Server) completion(params textDocumentPositionParams) interface{} {
+	items := []completionItem{}
+
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return items
+	}
+
+	for _, sym := range doc.symbolsAt(params.Position) {
+		items = append(items, completionItem{
+			Label:  symbolName(sym),
+			Kind:   completionKind(sym),
+			Detail: symbol.Describe(sym),
+		})
+	}
+
+	return items
+}
+
+// codeActions returns fixes suggested by the neutralizer for diagnostics on lines of the range.
+func (s *Server) codeActions(params codeActionParams) interface{} {
+	actions := []codeAction{}
+
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return actions
+	}
+
+	for _, d := range doc.diagnostics {
+		line := int(d.Position.Line) - 1
+		if line < params.Range.Start.Line || line > params.Range.End.Line {
+			continue
+		}
+
+		edit, ok := report.Fix(doc.source, d)
+		if !ok {
+			continue
+		}
+
+		actions = append(actions, codeAction{
+			Title:       "Replace with " + edit.Text,
+			Kind:        "quickfix",
+			Diagnostics: []diagnostic{doc.diagnostic(d)},
+			IsPreferred: true,
+			Edit: workspaceEdit{
+				Changes: map[string][]textEdit{
+					doc.uri: {{Range: doc.toLSP(edit.Position), NewText: edit.Text}},
+				},
+			},
+		})
+	}
+
+	return actions
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, respErr *responseError) error {
+	if respErr != nil {
+		return writeMessage(s.w, errorResponse{JSONRPC: "2.0", ID: id, Error: respErr})
+	}
+
+	return writeMessage(s.w, response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.w, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (d *document) diagnostic(e context.Error) diagnostic {
+	severity := diagnosticSeverityError
+	switch e.Severity {
+	case context.SeverityWarning:
+		severity = diagnosticSeverityWarning
+	case context.SeverityNote:
+		severity = diagnosticSeverityInformation
+	}
+
+	return diagnostic{
+		Range:    d.toLSP(e.Position),
+		Severity: severity,
+		Source:   string(e.Source),
+		Message:  e.Err.Error(),
+	}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: errorInvalidParams, Message: err.Error()}
+}
@@ -0,0 +1,249 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/module/lsp"
+)
+
+const program = `program p;
+type point = record x, y: integer end;
+var total: integer;
+function square(a: integer): integer;
+begin
+  square := a * a;
+end
+begin
+  total := square(3);
+  writeln(totl);
+end.
+`
+
+const uri = "file:///main.pas"
+
+func TestServer_Serve(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]interface{}{"uri": uri}
+
+	responses, notifications := serve(t, program, []map[string]interface{}{
+		{"id": 2, "method": "textDocument/hover", "params": at(8, 4)},
+		{"id": 3, "method": "textDocument/hover", "params": at(8, 13)},
+		{"id": 4, "method": "textDocument/hover", "params": at(5, 12)},
+		{"id": 5, "method": "textDocument/definition", "params": at(8, 13)},
+		{"id": 6, "method": "textDocument/definition", "params": at(9, 3)},
+		{"id": 7, "method": "textDocument/completion", "params": at(5, 12)},
+		{"id": 8, "method": "textDocument/codeAction", "params": map[string]interface{}{
+			"textDocument": doc,
+			"range": map[string]interface{}{
+				"start": map[string]interface{}{"line": 9, "character": 0},
+				"end":   map[string]interface{}{"line": 9, "character": 0},
+			},
+			"context": map[string]interface{}{"diagnostics": []interface{}{}},
+		}},
+		// Fixed text is analyzed again, and diagnostics are replaced.
+		{"method": "textDocument/didChange", "params": map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+			"contentChanges": []interface{}{map[string]interface{}{"text": strings.Replace(program, "totl", "total", 1)}},
+		}},
+		{"id": 9, "method": "unknown/method"},
+	})
+
+	assert.Equal(t, []string{
+		`{"uri":"file:///main.pas","diagnostics":[` +
+			`{"range":{"start":{"line":1,"character":5},"end":{"line":1,"character":10}},"severity":2,"source":"typecheck","message":"type point is declared but never used"},` +
+			`{"range":{"start":{"line":9,"character":10},"end":{"line":9,"character":14}},"severity":1,"source":"typecheck","message":"replace totl with total"}]}`,
+		`{"uri":"file:///main.pas","diagnostics":[` +
+			`{"range":{"start":{"line":1,"character":5},"end":{"line":1,"character":10}},"severity":2,"source":"typecheck","message":"type point is declared but never used"}]}`,
+	}, notifications)
+
+	assert.Equal(t, "```pascal\nvar total: integer\n```", hoverValue(t, responses["2"]))
+	assert.Equal(t, "```pascal\nfunction square(a: integer): integer\n```", hoverValue(t, responses["3"]))
+	assert.Equal(t, "```pascal\n(param) a: integer\n```", hoverValue(t, responses["4"]))
+
+	assert.Equal(t, `{"uri":"file:///main.pas","range":{"start":{"line":3,"character":9},"end":{"line":3,"character":15}}}`, responses["5"])
+	// Builtin functions aren't declared in the source code.
+	assert.Equal(t, `null`, responses["6"])
+
+	var items []struct {
+		Label string `json:"label"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(responses["7"]), &items))
+
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+
+	assert.Subset(t, labels, []string{"a", "point", "square", "total", "writeln"})
+
+	assert.Equal(t, `[{"title":"Replace with total","kind":"quickfix",`+
+		`"diagnostics":[{"range":{"start":{"line":9,"character":10},"end":{"line":9,"character":14}},"severity":1,"source":"typecheck","message":"replace totl with total"}],`+
+		`"isPreferred":true,"edit":{"changes":{"file:///main.pas":[{"range":{"start":{"line":9,"character":10},"end":{"line":9,"character":14}},"newText":"total"}]}}}]`,
+		responses["8"])
+
+	assert.Equal(t, `{"code":-32601,"message":"method unknown/method is not supported"}`, responses["9"])
+}
+
+func TestServer_HoverEnumsAndBuiltins(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+type color = (red, green, blue);
+var paint: color; day: (mon, tue);
+begin
+  paint := succ(red);
+  day := mon;
+  writeln(ord(paint), ord(day));
+end.
+`
+
+	responses, _ := serve(t, input, []map[string]interface{}{
+		{"id": 2, "method": "textDocument/hover", "params": at(1, 15)},
+		{"id": 3, "method": "textDocument/hover", "params": at(4, 2)},
+		{"id": 4, "method": "textDocument/hover", "params": at(4, 16)},
+		{"id": 5, "method": "textDocument/hover", "params": at(5, 9)},
+		{"id": 6, "method": "textDocument/hover", "params": at(6, 11)},
+		{"id": 7, "method": "textDocument/hover", "params": at(4, 12)},
+		{"id": 8, "method": "textDocument/hover", "params": at(6, 3)},
+	})
+
+	// Enum values have the type of their enum declaration, and values of enums without declarations have the enum itself.
+	assert.Equal(t, "```pascal\nconst red: color\n```", hoverValue(t, responses["2"]))
+	assert.Equal(t, "```pascal\nvar paint: color\n```", hoverValue(t, responses["3"]))
+	assert.Equal(t, "```pascal\nconst red: color\n```", hoverValue(t, responses["4"]))
+	assert.Equal(t, "```pascal\nconst mon: (mon, tue)\n```", hoverValue(t, responses["5"]))
+	assert.Equal(t, "```pascal\nfunction ord(x: ordinal): integer\n```", hoverValue(t, responses["6"]))
+	assert.Equal(t, "```pascal\nfunction succ(x: ordinal): ordinal\n```", hoverValue(t, responses["7"]))
+	assert.Equal(t, "```pascal\nprocedure writeln(s: any)\n```", hoverValue(t, responses["8"]))
+}
+
+func TestServer_ExitWithoutShutdown(t *testing.T) {
+	t.Parallel()
+
+	var in bytes.Buffer
+	writeMessage(t, &in, map[string]interface{}{"jsonrpc": "2.0", "method": "exit"})
+
+	assert.ErrorIs(t, lsp.New().Serve(&in, io.Discard), lsp.ErrExitWithoutShutdown)
+}
+
+// at returns params of the request at the position in the document.
+func at(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+// serve opens the document, sends the requests to the server and shuts it down.
+// It returns results or errors of requests by their IDs and params of notifications.
+func serve(t *testing.T, text string, requests []map[string]interface{}) (map[string]string, []string) {
+	t.Helper()
+
+	messages := []map[string]interface{}{
+		{"id": 1, "method": "initialize", "params": map[string]interface{}{}},
+		{"method": "initialized", "params": map[string]interface{}{}},
+		{"method": "textDocument/didOpen", "params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri, "languageId": "pascal", "version": 1, "text": text},
+		}},
+	}
+	messages = append(messages, requests...)
+	messages = append(messages,
+		map[string]interface{}{"id": 10, "method": "shutdown"},
+		map[string]interface{}{"method": "exit"},
+	)
+
+	var in bytes.Buffer
+	for _, msg := range messages {
+		msg["jsonrpc"] = "2.0"
+		writeMessage(t, &in, msg)
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, lsp.New().Serve(&in, &out))
+
+	responses := map[string]string{}
+	var notifications []string
+
+	r := bufio.NewReader(&out)
+	for {
+		content, ok := readMessage(t, r)
+		if !ok {
+			break
+		}
+
+		var msg struct {
+			ID     *json.RawMessage `json:"id"`
+			Result json.RawMessage  `json:"result"`
+			Error  json.RawMessage  `json:"error"`
+			Params json.RawMessage  `json:"params"`
+		}
+		assert.NoError(t, json.Unmarshal(content, &msg))
+
+		switch {
+		case msg.ID == nil:
+			notifications = append(notifications, string(msg.Params))
+		case msg.Error != nil:
+			responses[string(*msg.ID)] = string(msg.Error)
+		default:
+			responses[string(*msg.ID)] = string(msg.Result)
+		}
+	}
+
+	assert.Equal(t, `null`, responses["10"])
+
+	return responses, notifications
+}
+
+func writeMessage(t *testing.T, w io.Writer, msg interface{}) {
+	t.Helper()
+
+	content, err := json.Marshal(msg)
+	assert.NoError(t, err)
+
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	assert.NoError(t, err)
+}
+
+func readMessage(t *testing.T, r *bufio.Reader) ([]byte, bool) {
+	t.Helper()
+
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err == io.EOF {
+		return nil, false
+	}
+
+	assert.NoError(t, err)
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	assert.NoError(t, err)
+
+	content := make([]byte, length)
+	_, err = io.ReadFull(r, content)
+	assert.NoError(t, err)
+
+	return content, true
+}
+
+func hoverValue(t *testing.T, result string) string {
+	t.Helper()
+
+	var h struct {
+		Contents struct {
+			Value string `json:"value"`
+		} `json:"contents"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(result), &h))
+
+	return h.Contents.Value
+}
@@ -7,10 +7,17 @@ import (
 )
 
 var (
-	_ error = (*FixableKeywordError)(nil)
-	_ error = (*UnfixableKeywordError)(nil)
+	_ Fixable = (*FixableKeywordError)(nil)
+	_ Fixable = (*FixableNameError)(nil)
+	_ error   = (*UnfixableKeywordError)(nil)
 )
 
+// Fixable is an error which can be fixed by replacing the actual text in the source with the expected one.
+type Fixable interface {
+	error
+	Replacement() (actual, expected string)
+}
+
 type FixableKeywordError struct {
 	Expected token.ID
 	Actual   token.Token
@@ -25,6 +32,24 @@ func (e *FixableKeywordError) Is(other error) bool {
 	return ok
 }
 
+func (e *FixableKeywordError) Replacement() (string, string) {
+	return e.Actual.Value, token.ByID(e.Expected)
+}
+
+// FixableNameError is a misspelled name of the symbol or the record field.
+type FixableNameError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *FixableNameError) Error() string {
+	return fmt.Sprintf("replace %s with %s", e.Actual, e.Expected)
+}
+
+func (e *FixableNameError) Replacement() (string, string) {
+	return e.Actual, e.Expected
+}
+
 type UnfixableKeywordError struct {
 	Expected token.ID
 	Actual   token.Token
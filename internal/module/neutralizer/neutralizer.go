@@ -26,7 +26,7 @@ func (n Neutralizer) NeutralizeKeyword(expected token.ID, actual token.Token) (t
 	}
 
 	switch expected {
-	case token.Unknown, token.UserDefined, token.EOF, token.IntLiteral, token.DoubleLiteral, token.BoolLiteral:
+	case token.Unknown, token.UserDefined, token.EOF, token.IntLiteral, token.DoubleLiteral, token.BoolLiteral, token.StringLiteral:
 		return actual, &UnfixableKeywordError{
 			Expected: expected,
 			Actual:   actual,
@@ -72,7 +72,7 @@ func (n Neutralizer) NeutralizeUserDefined(scope symbol.Scope, actual string) (s
 		var symbolName string
 		switch savedSymbol := savedSymbol.(type) {
 		case *symbol.Const:
-			symbolName = savedSymbol.RawValue
+			symbolName = savedSymbol.Value
 		case *symbol.Var:
 			symbolName = savedSymbol.Value
 		case *symbol.Func:
@@ -88,8 +88,27 @@ func (n Neutralizer) NeutralizeUserDefined(scope symbol.Scope, actual string) (s
 			continue
 		}
 
-		return savedSymbol, fmt.Errorf("replace %s with %s", actual, symbolName)
+		return savedSymbol, &FixableNameError{Expected: symbolName, Actual: actual}
 	}
 
 	return nil, fmt.Errorf("no symbol found for %s", actual)
 }
+
+// NeutralizeField looks for the field in the record.
+// If there is no such field, the field with the closest name is returned with an error suggesting to replace the name.
+func (n Neutralizer) NeutralizeField(record symbol.Record, actual string) (symbol.Field, error) {
+	if f, ok := record.Field(actual); ok {
+		return f, nil
+	}
+
+	for _, f := range record.Fields {
+		dist := levenshtein.ComputeDistance(actual, f.Name)
+		if dist > n.maxDistance {
+			continue
+		}
+
+		return f, &FixableNameError{Expected: f.Name, Actual: actual}
+	}
+
+	return symbol.Field{}, fmt.Errorf("no field found for %s", actual)
+}
@@ -18,8 +18,8 @@ var (
 	// doubleConstantRegex is used for finding double constants.
 	doubleConstantRegex = regexp.MustCompile(`^\d+\.\d+`)
 
-	// complexOperatorRegex matches complex operators that consist of 2 characters.
-	complexOperatorRegex = regexp.MustCompile(`[<>][<>=]|:=`)
+	// complexOperatorRegex matches complex operators, ranges and comment delimiters that consist of 2 characters.
+	complexOperatorRegex = regexp.MustCompile(`[<>][<>=]|:=|\.\.|\(\*|\*\)|//`)
 )
 
 type Reader struct {
@@ -35,6 +35,7 @@ func New(buffer int) *Reader {
 func (s Reader) Read(
 	ctx interface {
 		context.ErrorsContext
+		context.SourceContext
 	},
 	r io.Reader,
 ) <-chan literal.Literal {
@@ -55,6 +56,7 @@ func (s Reader) Read(
 			}
 
 			line := scanner.Text()
+			ctx.Source().AddLine(line)
 			s.splitLine(line, lineNumber, ch)
 
 			lineNumber++
@@ -82,14 +84,20 @@ func (s Reader) splitLine(input string, lineNumber literal.LineNumber, ch chan<-
 
 		boundaryStart, boundaryEnd := literal.ColNumber(boundary[0]), literal.ColNumber(boundary[1])
 
-		// Extend selection for complex operators.
+		// Extend selection for string literals and complex operators.
 		complexBoundary := complexOperatorRegex.FindStringIndex(rest)
-		if complexBoundary != nil && boundary[0] == complexBoundary[0] {
+		if rest[boundaryStart] == '\'' {
+			boundaryEnd = s.stringLiteralEnd(rest, boundaryStart)
+		} else if complexBoundary != nil && boundary[0] == complexBoundary[0] {
 			boundaryEnd = literal.ColNumber(complexBoundary[1])
 		} else {
 			// Expand selection for double constants.
 			doubleConstantBoundary := doubleConstantRegex.FindStringIndex(rest)
-			if doubleConstantBoundary != nil {
+			if doubleConstantBoundary != nil && doubleConstantBoundary[1] == len(rest) {
+				// Double constant ends the line, so there is no boundary after it.
+				ch <- literal.New(rest, lineNumber, offset+1, inputLength+1)
+				break
+			} else if doubleConstantBoundary != nil {
 				boundaryStart = literal.ColNumber(doubleConstantBoundary[1])
 				boundaryEnd = boundaryStart + 1
 			}
@@ -110,3 +118,22 @@ func (s Reader) splitLine(input string, lineNumber literal.LineNumber, ch chan<-
 	// Add newline.
 	ch <- literal.New("\n", lineNumber, inputLength+1, inputLength+2)
 }
+
+// stringLiteralEnd returns the index after the closing quote of the string literal starting at start.
+// Doubled quotes are a part of the literal. Unterminated literal spans until the end of the line.
+func (s Reader) stringLiteralEnd(input string, start literal.ColNumber) literal.ColNumber {
+	for i := int(start) + 1; i < len(input); i++ {
+		if input[i] != '\'' {
+			continue
+		}
+
+		if i+1 < len(input) && input[i+1] == '\'' {
+			i++
+			continue
+		}
+
+		return literal.ColNumber(i + 1)
+	}
+
+	return literal.ColNumber(len(input))
+}
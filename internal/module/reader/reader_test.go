@@ -1,11 +1,13 @@
 package reader_test
 
 import (
+	stdcontext "context"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/iskorotkov/compiler/internal/context"
 	"github.com/iskorotkov/compiler/internal/data/literal"
 	"github.com/iskorotkov/compiler/internal/fn/channel"
 	"github.com/iskorotkov/compiler/internal/module/reader"
@@ -65,6 +67,57 @@ func TestReader_Read(t *testing.T) {
 				literal.New("\n", 3, 13, 14),
 			},
 		},
+		{
+			name:  "double constant at the end of the line",
+			input: "x:=2.71",
+			expected: []literal.Literal{
+				literal.New("x", 1, 1, 2),
+				literal.New(":=", 1, 2, 4),
+				literal.New("2.71", 1, 4, 8),
+				literal.New("\n", 1, 8, 9),
+			},
+		},
+		{
+			name:  "sequence with comment delimiters",
+			input: "{(*x*)}//",
+			expected: []literal.Literal{
+				literal.New("{", 1, 1, 2),
+				literal.New("(*", 1, 2, 4),
+				literal.New("x", 1, 4, 5),
+				literal.New("*)", 1, 5, 7),
+				literal.New("}", 1, 7, 8),
+				literal.New("//", 1, 8, 10),
+				literal.New("\n", 1, 10, 11),
+			},
+		},
+		{
+			name:  "sequence with range",
+			input: "[1..10,2.5]",
+			expected: []literal.Literal{
+				literal.New("[", 1, 1, 2),
+				literal.New("1", 1, 2, 3),
+				literal.New("..", 1, 3, 5),
+				literal.New("10", 1, 5, 7),
+				literal.New(",", 1, 7, 8),
+				literal.New("2.5", 1, 8, 11),
+				literal.New("]", 1, 11, 12),
+				literal.New("\n", 1, 12, 13),
+			},
+		},
+		{
+			name:  "sequence with string literals",
+			input: "s:='it''s a';'x\n'(* ",
+			expected: []literal.Literal{
+				literal.New("s", 1, 1, 2),
+				literal.New(":=", 1, 2, 4),
+				literal.New("'it''s a'", 1, 4, 13),
+				literal.New(";", 1, 13, 14),
+				literal.New("'x", 1, 14, 16),
+				literal.New("\n", 1, 16, 17),
+				literal.New("'(* ", 2, 1, 5),
+				literal.New("\n", 2, 5, 6),
+			},
+		},
 	}
 
 	configs := []Config{
@@ -93,7 +146,7 @@ func TestReader_Read(t *testing.T) {
 				t.Run(test.name, func(t *testing.T) {
 					t.Parallel()
 
-					actual := channel.ToSlice(r.Read(nil, strings.NewReader(test.input)))
+					actual := channel.ToSlice(r.Read(context.NewEnvContext(stdcontext.Background()), strings.NewReader(test.input)))
 					assert.Equal(t, test.expected, actual)
 				})
 			}
@@ -101,6 +154,21 @@ func TestReader_Read(t *testing.T) {
 	}
 }
 
+func TestReader_ReadSource(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	channel.ToSlice(reader.New(0).Read(ctx, strings.NewReader("program p;\n\tbegin\nend.")))
+
+	line, ok := ctx.Source().Line(2)
+	assert.True(t, ok)
+	assert.Equal(t, "\tbegin", line)
+
+	_, ok = ctx.Source().Line(4)
+	assert.False(t, ok)
+}
+
 func TestReader_ReadWithSnapshots(t *testing.T) {
 	t.Parallel()
 
@@ -127,7 +195,7 @@ func TestReader_ReadWithSnapshots(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			actual := channel.ToSlice(r.Read(nil, strings.NewReader(test.input)))
+			actual := channel.ToSlice(r.Read(context.NewEnvContext(stdcontext.Background()), strings.NewReader(test.input)))
 			s := snapshot.NewSlice(actual)
 
 			expected := snapshot.Load(test.name)
@@ -0,0 +1,88 @@
+package report
+
+import (
+	"errors"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/module/neutralizer"
+)
+
+// Edit is a replacement of the text in the source which fixes the diagnostic.
+// Position covers the replaced text only, and it's always on a single line.
+type Edit struct {
+	Position literal.Position
+	Text     string
+}
+
+// Fix returns the edit for diagnostics of misspelled keywords and names.
+// Such diagnostics are reported at the misspelled word, so the edit replaces the word at the diagnostic position.
+func Fix(source *literal.Source, d context.Error) (Edit, bool) {
+	var fixable neutralizer.Fixable
+	if !errors.As(d.Err, &fixable) {
+		return Edit{}, false
+	}
+
+	line, ok := source.Line(d.Position.Line)
+	if !ok {
+		return Edit{}, false
+	}
+
+	actual, expected := fixable.Replacement()
+
+	// Diagnostics at other positions don't point to the misspelled word, so they aren't fixed.
+	start := int(d.Position.StartCol) - 1
+	end := start + len(actual)
+	if start < 0 || end > len(line) || line[start:end] != actual {
+		return Edit{}, false
+	}
+
+	return Edit{
+		Position: literal.Position{
+			Line:     d.Position.Line,
+			StartCol: d.Position.StartCol,
+			EndCol:   literal.ColNumber(end + 1),
+		},
+		Text: expected,
+	}, true
+}
+
+// Apply returns the line with the edit applied.
+func (e Edit) Apply(line string) string {
+	return line[:e.Position.StartCol-1] + e.Text + line[e.Position.EndCol-1:]
+}
+
+// nonEmpty returns the position which covers at least one character.
+// Positions joined across several lines may end before they start, so they are reduced to their first character.
+func nonEmpty(p literal.Position) literal.Position {
+	if p.EndCol <= p.StartCol {
+		p.EndCol = p.StartCol + 1
+	}
+
+	return p
+}
+
+// underlineRange returns indexes of the first and after the last underlined bytes of the line.
+// Positions joined across several lines end before they start, so they are underlined until the end of the line.
+// Positions of line ends are right after the last byte, so there is at least one underlined byte.
+func underlineRange(line string, p literal.Position) (int, int) {
+	start := int(p.StartCol) - 1
+	if start < 0 {
+		start = 0
+	}
+
+	if start > len(line) {
+		start = len(line)
+	}
+
+	end := int(p.EndCol) - 1
+	if end <= start || end > len(line) {
+		end = len(line)
+	}
+
+	if end <= start {
+		end = start + 1
+	}
+
+	return start, end
+}
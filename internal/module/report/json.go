@@ -0,0 +1,80 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+)
+
+// JSON writes diagnostics as a single JSON document for editors and other tools.
+// Lines and columns start from 1, and end columns point right after the last character, as in SARIF.
+type JSON struct {
+	Filename string
+	Source   *literal.Source
+}
+
+type jsonReport struct {
+	Diagnostics []jsonDiagnostic `json:"diagnostics"`
+}
+
+type jsonDiagnostic struct {
+	Source   context.ErrorSource `json:"source"`
+	Severity string              `json:"severity"`
+	File     string              `json:"file"`
+	// Range is nil for diagnostics which don't refer to the source code.
+	Range   *jsonRange `json:"range,omitempty"`
+	Message string     `json:"message"`
+	Fix     *jsonEdit  `json:"fix,omitempty"`
+}
+
+type jsonRange struct {
+	Line        literal.LineNumber `json:"line"`
+	StartColumn literal.ColNumber  `json:"startColumn"`
+	EndColumn   literal.ColNumber  `json:"endColumn"`
+}
+
+type jsonEdit struct {
+	Range jsonRange `json:"range"`
+	Text  string    `json:"text"`
+}
+
+func (j JSON) Write(w io.Writer, diagnostics []context.Error) error {
+	// Empty list is written as [], not null.
+	report := jsonReport{Diagnostics: []jsonDiagnostic{}}
+	for _, d := range diagnostics {
+		item := jsonDiagnostic{
+			Source:   d.Source,
+			Severity: d.Severity.String(),
+			File:     j.Filename,
+			Message:  d.Err.Error(),
+		}
+
+		if d.Position.Line != 0 {
+			r := newJSONRange(d.Position)
+			item.Range = &r
+		}
+
+		if edit, ok := Fix(j.Source, d); ok {
+			item.Fix = &jsonEdit{Range: newJSONRange(edit.Position), Text: edit.Text}
+		}
+
+		report.Diagnostics = append(report.Diagnostics, item)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write JSON report: %w", err)
+	}
+
+	return nil
+}
+
+func newJSONRange(p literal.Position) jsonRange {
+	p = nonEmpty(p)
+	return jsonRange{Line: p.Line, StartColumn: p.StartCol, EndColumn: p.EndCol}
+}
@@ -0,0 +1,37 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+)
+
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatSARIF Format = "sarif"
+)
+
+// Format of the diagnostics output.
+type Format string
+
+// Writer writes diagnostics of the program read from the file.
+type Writer interface {
+	Write(w io.Writer, diagnostics []context.Error) error
+}
+
+// New returns the writer for the format. Colors are used only in text format.
+func New(format Format, filename string, source *literal.Source, colors bool) (Writer, error) {
+	switch format {
+	case FormatText:
+		return Text{Filename: filename, Source: source, Colors: colors}, nil
+	case FormatJSON:
+		return JSON{Filename: filename, Source: source}, nil
+	case FormatSARIF:
+		return SARIF{Filename: filename, Source: source}, nil
+	default:
+		return nil, fmt.Errorf("unknown diagnostics format %q, expected text, json or sarif", format)
+	}
+}
@@ -0,0 +1,213 @@
+package report_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/module/neutralizer"
+	"github.com/iskorotkov/compiler/internal/module/report"
+)
+
+func TestWriter_Write(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		name     string
+		format   report.Format
+		expected string
+	}
+
+	source := &literal.Source{}
+	source.AddLine("progrm p;")
+
+	diagnostics := []context.Error{
+		{
+			Source: context.ErrorSourceCodegen,
+			Err:    errors.New("generation skipped"),
+		},
+		{
+			Source:   context.ErrorSourceSyntax,
+			Position: literal.New("", 1, 1, 7).Position,
+			Err:      &neutralizer.FixableNameError{Expected: "program", Actual: "progrm"},
+		},
+		{
+			Severity: context.SeverityWarning,
+			Source:   context.ErrorSourceTypecheck,
+			Position: literal.New("", 1, 8, 9).Position,
+			Err:      errors.New("unused"),
+		},
+	}
+
+	tests := []Test{
+		{
+			name:   "json",
+			format: report.FormatJSON,
+			expected: `{
+  "diagnostics": [
+    {
+      "source": "codegen",
+      "severity": "error",
+      "file": "main.pas",
+      "message": "generation skipped"
+    },
+    {
+      "source": "syntax",
+      "severity": "error",
+      "file": "main.pas",
+      "range": {
+        "line": 1,
+        "startColumn": 1,
+        "endColumn": 7
+      },
+      "message": "replace progrm with program",
+      "fix": {
+        "range": {
+          "line": 1,
+          "startColumn": 1,
+          "endColumn": 7
+        },
+        "text": "program"
+      }
+    },
+    {
+      "source": "typecheck",
+      "severity": "warning",
+      "file": "main.pas",
+      "range": {
+        "line": 1,
+        "startColumn": 8,
+        "endColumn": 9
+      },
+      "message": "unused"
+    }
+  ]
+}
+`,
+		},
+		{
+			name:   "sarif",
+			format: report.FormatSARIF,
+			expected: `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "compiler",
+          "informationUri": "https://github.com/iskorotkov/compiler"
+        }
+      },
+      "results": [
+        {
+          "ruleId": "codegen",
+          "level": "error",
+          "message": {
+            "text": "generation skipped"
+          }
+        },
+        {
+          "ruleId": "syntax",
+          "level": "error",
+          "message": {
+            "text": "replace progrm with program"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "main.pas"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1,
+                  "endLine": 1,
+                  "endColumn": 7
+                }
+              }
+            }
+          ],
+          "fixes": [
+            {
+              "description": {
+                "text": "replace with program"
+              },
+              "artifactChanges": [
+                {
+                  "artifactLocation": {
+                    "uri": "main.pas"
+                  },
+                  "replacements": [
+                    {
+                      "deletedRegion": {
+                        "startLine": 1,
+                        "startColumn": 1,
+                        "endLine": 1,
+                        "endColumn": 7
+                      },
+                      "insertedContent": {
+                        "text": "program"
+                      }
+                    }
+                  ]
+                }
+              ]
+            }
+          ]
+        },
+        {
+          "ruleId": "typecheck",
+          "level": "warning",
+          "message": {
+            "text": "unused"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "main.pas"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 8,
+                  "endLine": 1,
+                  "endColumn": 9
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			w, err := report.New(test.format, "main.pas", source, false)
+			assert.NoError(t, err)
+
+			var sb strings.Builder
+			assert.NoError(t, w.Write(&sb, diagnostics))
+			assert.Equal(t, test.expected, sb.String())
+		})
+	}
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := report.New("xml", "main.pas", &literal.Source{}, false)
+	assert.EqualError(t, err, `unknown diagnostics format "xml", expected text, json or sarif`)
+}
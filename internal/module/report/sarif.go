@@ -0,0 +1,159 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "compiler"
+	toolURI      = "https://github.com/iskorotkov/compiler"
+)
+
+// SARIF writes diagnostics in SARIF 2.1.0 format, so they can be uploaded to code scanning services.
+// Sources of diagnostics (syntax, typecheck, etc.) are used as rule IDs.
+type SARIF struct {
+	Filename string
+	Source   *literal.Source
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+	Fixes     []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   literal.LineNumber `json:"startLine"`
+	StartColumn literal.ColNumber  `json:"startColumn"`
+	EndLine     literal.LineNumber `json:"endLine"`
+	EndColumn   literal.ColNumber  `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+func (s SARIF) Write(w io.Writer, diagnostics []context.Error) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+		}},
+		// Empty list is written as [], as results are required for successful runs.
+		Results: []sarifResult{},
+	}
+
+	artifact := sarifArtifactLocation{URI: artifactURI(s.Filename)}
+
+	for _, d := range diagnostics {
+		result := sarifResult{
+			RuleID:  string(d.Source),
+			Level:   d.Severity.String(),
+			Message: sarifMessage{Text: d.Err.Error()},
+		}
+
+		if d.Position.Line != 0 {
+			result.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: artifact,
+				Region:           newSARIFRegion(d.Position),
+			}}}
+		}
+
+		if edit, ok := Fix(s.Source, d); ok {
+			result.Fixes = []sarifFix{{
+				Description: sarifMessage{Text: fmt.Sprintf("replace with %s", edit.Text)},
+				ArtifactChanges: []sarifArtifactChange{{
+					ArtifactLocation: artifact,
+					Replacements: []sarifReplacement{{
+						DeletedRegion:   newSARIFRegion(edit.Position),
+						InsertedContent: sarifMessage{Text: edit.Text},
+					}},
+				}},
+			}}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}); err != nil {
+		return fmt.Errorf("failed to write SARIF report: %w", err)
+	}
+
+	return nil
+}
+
+// artifactURI converts the file name to the URI. Relative paths are valid relative URIs, so only absolute paths are converted.
+func artifactURI(filename string) string {
+	if !filepath.IsAbs(filename) {
+		return filepath.ToSlash(filename)
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String()
+}
+
+func newSARIFRegion(p literal.Position) sarifRegion {
+	p = nonEmpty(p)
+	return sarifRegion{StartLine: p.Line, StartColumn: p.StartCol, EndLine: p.Line, EndColumn: p.EndCol}
+}
@@ -0,0 +1,138 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+)
+
+// ANSI escape codes used for colored output.
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorCyan   = "\x1b[36m"
+)
+
+// Text writes diagnostics in a human-readable form with lines of the source code they refer to,
+// similar to diagnostics of rustc and clang:
+//
+//	error[syntax]: replace progrm with program
+//	 --> main.pas:1:1
+//	  |
+//	1 | progrm p;
+//	  | ^~~~~~
+//	  |
+//	  = help: replace with program
+//	  |
+//	1 | program p;
+//	  | ~~~~~~~
+type Text struct {
+	Filename string
+	Source   *literal.Source
+	// Colors enables ANSI colors, so it must be set only if the output is a terminal.
+	Colors bool
+}
+
+func (t Text) Write(w io.Writer, diagnostics []context.Error) error {
+	var sb strings.Builder
+	for _, d := range diagnostics {
+		t.writeDiagnostic(&sb, d)
+		sb.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (t Text) writeDiagnostic(sb *strings.Builder, d context.Error) {
+	severityColor := colorRed
+	switch d.Severity {
+	case context.SeverityWarning:
+		severityColor = colorYellow
+	case context.SeverityNote:
+		severityColor = colorCyan
+	}
+
+	sb.WriteString(t.paint(colorBold+severityColor, fmt.Sprintf("%s[%s]", d.Severity, d.Source)))
+	sb.WriteString(t.paint(colorBold, fmt.Sprintf(": %v", d.Err)))
+	sb.WriteString("\n")
+
+	// Some diagnostics (for example, skipped code generation) don't refer to the source code.
+	if d.Position.Line == 0 {
+		return
+	}
+
+	gutter := strings.Repeat(" ", len(strconv.Itoa(int(d.Position.Line))))
+
+	fmt.Fprintf(sb, "%s%s %s:%d:%d\n", gutter, t.paint(colorBold+colorBlue, "-->"), t.Filename, d.Position.Line, d.Position.StartCol)
+
+	line, ok := t.Source.Line(d.Position.Line)
+	if !ok {
+		return
+	}
+
+	start, end := underlineRange(line, d.Position)
+
+	t.writeGutter(sb, gutter, "")
+	t.writeGutter(sb, strconv.Itoa(int(d.Position.Line)), line)
+	t.writeGutter(sb, gutter, indent(line, start)+t.paint(colorBold+severityColor, "^"+strings.Repeat("~", end-start-1)))
+
+	edit, ok := Fix(t.Source, d)
+	if !ok {
+		return
+	}
+
+	fixed := edit.Apply(line)
+
+	t.writeGutter(sb, gutter, "")
+	fmt.Fprintf(sb, "%s %s %s\n", gutter, t.paint(colorBold+colorBlue, "="), t.paint(colorBold, "help: replace with "+edit.Text))
+	t.writeGutter(sb, gutter, "")
+	t.writeGutter(sb, strconv.Itoa(int(d.Position.Line)), fixed)
+	t.writeGutter(sb, gutter, indent(fixed, int(edit.Position.StartCol)-1)+t.paint(colorGreen, strings.Repeat("~", len(edit.Text))))
+}
+
+// writeGutter writes the line with the line number or the empty space in the gutter.
+func (t Text) writeGutter(sb *strings.Builder, gutter, text string) {
+	sb.WriteString(t.paint(colorBold+colorBlue, gutter+" |"))
+	if text != "" {
+		sb.WriteString(" ")
+		sb.WriteString(text)
+	}
+
+	sb.WriteString("\n")
+}
+
+func (t Text) paint(color, s string) string {
+	if !t.Colors {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+// indent returns whitespace of the same width as the first n bytes of the line.
+// Tabs are kept as is, so the underline is aligned with the line in any terminal.
+func indent(line string, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n && i < len(line); i++ {
+		if line[i] == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+
+	for i := len(line); i < n; i++ {
+		sb.WriteByte(' ')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,117 @@
+package report_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/module/neutralizer"
+	"github.com/iskorotkov/compiler/internal/module/report"
+)
+
+func TestText_Write(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		name        string
+		diagnostics []context.Error
+		expected    string
+	}
+
+	source := &literal.Source{}
+	for _, line := range []string{
+		"program p;",
+		"var x: integer;",
+		"begin",
+		"\tx := xx + true",
+		"\tq.xy := xy;",
+		"end.",
+	} {
+		source.AddLine(line)
+	}
+
+	tests := []Test{
+		{
+			name: "error with fix",
+			diagnostics: []context.Error{{
+				Source:   context.ErrorSourceTypecheck,
+				Position: literal.New("", 4, 7, 9).Position,
+				Err:      &neutralizer.FixableNameError{Expected: "x", Actual: "xx"},
+			}},
+			expected: `error[typecheck]: replace xx with x
+ --> main.pas:4:7
+  |
+4 | 	x := xx + true
+  | 	     ^~
+  |
+  = help: replace with x
+  |
+4 | 	x := x + true
+  | 	     ~
+
+`,
+		},
+		{
+			name: "fix of the name which is also a field",
+			diagnostics: []context.Error{{
+				Source:   context.ErrorSourceTypecheck,
+				Position: literal.New("", 5, 10, 12).Position,
+				Err:      &neutralizer.FixableNameError{Expected: "xx", Actual: "xy"},
+			}},
+			expected: `error[typecheck]: replace xy with xx
+ --> main.pas:5:10
+  |
+5 | 	q.xy := xy;
+  | 	        ^~
+  |
+  = help: replace with xx
+  |
+5 | 	q.xy := xx;
+  | 	        ~~
+
+`,
+		},
+		{
+			name: "warning at the end of the line",
+			diagnostics: []context.Error{{
+				Severity: context.SeverityWarning,
+				Source:   context.ErrorSourceSyntax,
+				Position: literal.New("", 4, 16, 17).Position,
+				Err:      errors.New("missing semicolon"),
+			}},
+			expected: `warning[syntax]: missing semicolon
+ --> main.pas:4:16
+  |
+4 | 	x := xx + true
+  | 	              ^
+
+`,
+		},
+		{
+			name: "diagnostics without position",
+			diagnostics: []context.Error{{
+				Severity: context.SeverityNote,
+				Source:   context.ErrorSourceCodegen,
+				Err:      errors.New("generation skipped"),
+			}},
+			expected: "note[codegen]: generation skipped\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var sb strings.Builder
+			err := report.Text{Filename: "main.pas", Source: source}.Write(&sb, test.diagnostics)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, sb.String())
+		})
+	}
+}
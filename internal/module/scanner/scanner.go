@@ -17,6 +17,14 @@ var (
 	userIdentifierRegex = regexp.MustCompile(`^(?i)[a-z_]\w*$`)
 )
 
+// CommentDelimiters maps opening comment delimiters to closing ones.
+// Line comments are closed by the end of line.
+var CommentDelimiters = map[token.ID]token.ID{
+	token.OpeningBrace:   token.ClosingBrace,
+	token.OpeningComment: token.ClosingComment,
+	token.LineComment:    token.Newline,
+}
+
 type Scanner struct {
 	buffer int
 }
@@ -33,10 +41,33 @@ func (l Scanner) Scan(ctx interface{ context.ErrorsContext }, input <-chan liter
 	go func() {
 		defer close(ch)
 
+		// comment is an opening delimiter of the comment being skipped.
+		var comment *token.Token
+
 		for lit := range input {
+			id := token.GetID(lit.Value)
+
+			if comment != nil {
+				if id == CommentDelimiters[comment.ID] {
+					comment = nil
+				}
+
+				continue
+			}
+
+			if _, ok := CommentDelimiters[id]; ok {
+				t := token.New(id, lit)
+				comment = &t
+				continue
+			}
+
 			addTypedToken(ctx, lit, ch)
 		}
 
+		if comment != nil {
+			ctx.AddError(context.ErrorSourceScanner, comment.Position, fmt.Errorf("unterminated comment"))
+		}
+
 		ch <- token.Token{ID: token.EOF}
 	}()
 
@@ -56,6 +87,16 @@ func addTypedToken(ctx interface{ context.ErrorsContext }, lit literal.Literal,
 	}
 
 	// Constants.
+	if strings.HasPrefix(lit.Value, "'") {
+		if _, err := token.Unquote(lit.Value); err != nil {
+			ctx.AddError(context.ErrorSourceScanner, lit.Position, err)
+			return
+		}
+
+		ch <- token.New(token.StringLiteral, lit)
+		return
+	}
+
 	if intConstantRegex.MatchString(lit.Value) {
 		ch <- token.New(token.IntLiteral, lit)
 		return
@@ -1,13 +1,17 @@
 package scanner_test
 
 import (
+	stdcontext "context"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/iskorotkov/compiler/internal/context"
 	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/token"
 	"github.com/iskorotkov/compiler/internal/fn/channel"
+	"github.com/iskorotkov/compiler/internal/module/reader"
 	"github.com/iskorotkov/compiler/internal/module/scanner"
 	"github.com/iskorotkov/compiler/internal/snapshot"
 )
@@ -46,6 +50,90 @@ func TestScanner_Scan(t *testing.T) {
 	}
 }
 
+func TestScanner_ScanCommentsAndStrings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []token.Token
+		errors   []string
+	}{
+		{
+			name:  "comments of all kinds",
+			input: "x { a\n b } y (* { c *) z // d *)\nw",
+			expected: []token.Token{
+				token.New(token.UserDefined, literal.New("x", 1, 1, 2)),
+				token.New(token.UserDefined, literal.New("y", 2, 6, 7)),
+				token.New(token.UserDefined, literal.New("z", 2, 18, 19)),
+				token.New(token.UserDefined, literal.New("w", 3, 1, 2)),
+				{ID: token.EOF},
+			},
+		},
+		{
+			name:  "unterminated comment",
+			input: "x (* y\nz",
+			expected: []token.Token{
+				token.New(token.UserDefined, literal.New("x", 1, 1, 2)),
+				{ID: token.EOF},
+			},
+			errors: []string{"SCANNER 1:3-5: unterminated comment"},
+		},
+		{
+			name:  "string literals",
+			input: "x := 'it''s' { '}' }",
+			expected: []token.Token{
+				token.New(token.UserDefined, literal.New("x", 1, 1, 2)),
+				token.New(token.Assign, literal.New(":=", 1, 3, 5)),
+				token.New(token.StringLiteral, literal.New("'it''s'", 1, 6, 13)),
+				{ID: token.EOF},
+			},
+		},
+		{
+			name:  "unterminated string literal",
+			input: "x := 'abc;\ny",
+			expected: []token.Token{
+				token.New(token.UserDefined, literal.New("x", 1, 1, 2)),
+				token.New(token.Assign, literal.New(":=", 1, 3, 5)),
+				token.New(token.UserDefined, literal.New("y", 2, 1, 2)),
+				{ID: token.EOF},
+			},
+			errors: []string{"SCANNER 1:6-11: unterminated string literal"},
+		},
+		{
+			name:  "keyword casing",
+			input: "IF Count Then Value",
+			expected: []token.Token{
+				token.New(token.If, literal.New("IF", 1, 1, 3)),
+				token.New(token.UserDefined, literal.New("Count", 1, 4, 9)),
+				token.New(token.Then, literal.New("Then", 1, 10, 14)),
+				token.New(token.Value, literal.New("Value", 1, 15, 20)),
+				{ID: token.EOF},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.NewEnvContext(stdcontext.Background())
+
+			literals := reader.New(0).Read(ctx, strings.NewReader(test.input))
+			actual := channel.ToSlice(scanner.New(0).Scan(ctx, literals))
+			assert.Equal(t, test.expected, actual)
+
+			var errors []string
+			for _, err := range ctx.Errors() {
+				errors = append(errors, err.Error())
+			}
+
+			assert.Equal(t, test.errors, errors)
+		})
+	}
+}
+
 func fromString(s string) []literal.Literal {
 	var literals []literal.Literal
 	for _, part := range strings.Split(s, " ") {
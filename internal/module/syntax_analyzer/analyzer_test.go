@@ -2,14 +2,18 @@ package syntax_analyzer_test
 
 import (
 	stdcontext "context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
 	"github.com/iskorotkov/compiler/internal/data/token"
 	"github.com/iskorotkov/compiler/internal/fn/channel"
 	"github.com/iskorotkov/compiler/internal/fn/slice"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
 	"github.com/iskorotkov/compiler/internal/module/syntax_analyzer"
 	"github.com/iskorotkov/compiler/internal/snapshot"
 )
@@ -137,3 +141,91 @@ func TestAnalyzer(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalyzer_Recovery(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		name   string
+		input  string
+		errors []string
+	}
+
+	tests := []Test{
+		{
+			name: "errors in operators",
+			input: `program p;
+begin
+  x := 1 y := 2;
+  z := ;
+  begin
+    x := x * ;
+  end;
+  writeln(x);
+end.`,
+			errors: []string{
+				`SYNTAX 3:10: unexpected token "y"`,
+				`SYNTAX 4:8: unexpected token ";"`,
+				`SYNTAX 6:14: unexpected token ";"`,
+			},
+		},
+		{
+			name: "errors in declarations",
+			input: `program p;
+const
+  a = ;
+  b = 2;
+var
+  x integer;
+  y: integer;
+begin
+end.`,
+			errors: []string{
+				`SYNTAX 3:7: unexpected token: expected expression, got ";"`,
+				`SYNTAX 6:5-12: unexpected token: expected ":", got "integer"`,
+			},
+		},
+		{
+			name: "errors in function headers",
+			input: `program p;
+function f(a: integer; b: integer) integer;
+begin
+  f := a;
+end
+function g(a: integer): integer;
+begin
+  g := a +;
+end
+begin
+end.`,
+			errors: []string{
+				`SYNTAX 2:36-43: unexpected token: expected ":", got "integer"`,
+				`SYNTAX 8:11: unexpected token ";"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.NewEnvContext(stdcontext.Background())
+
+			literals := reader.New(0).Read(ctx, strings.NewReader(test.input))
+			tokens := scanner.New(0).Scan(ctx, literals)
+			res := channel.ToSlice(syntax_analyzer.New(0).Analyze(ctx, tokens))
+
+			// Partial AST is produced, so later stages can report their errors too.
+			assert.Len(t, res, 1)
+			assert.NotEmpty(t, res[0].Query(ast.QueryTypeOne, ast.MarkerError))
+
+			var errors []string
+			for _, err := range ctx.Errors() {
+				errors = append(errors, err.Error())
+			}
+
+			assert.Equal(t, test.errors, errors)
+		})
+	}
+}
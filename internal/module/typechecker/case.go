@@ -0,0 +1,299 @@
+package typechecker
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+// CaseBranch is a branch of the case operator with labels converted to ordinal values.
+type CaseBranch struct {
+	Labels []CaseLabel
+	// Block is nil if the branch has no operators.
+	Block ast.Node
+}
+
+// CaseLabel is a range of ordinal values of the case label (Low is equal to High for single values).
+type CaseLabel struct {
+	Node ast.Node
+	Low  int
+	High int
+	Type symbol.BuiltinType
+}
+
+// Contains checks whether the ordinal value matches the label.
+func (l CaseLabel) Contains(v int) bool {
+	return v >= l.Low && v <= l.High
+}
+
+// CaseBranches returns branches of the case operator and the else block (nil if there is no else branch or it's empty).
+// Labels must be checked by the type checker before, so only the first error is returned.
+func CaseBranches(scope symbol.Scope, node ast.Node) ([]CaseBranch, ast.Node, error) {
+	lookup := scopeLookup(scope)
+
+	var branches []CaseBranch
+	var elseBlock ast.Node
+	for _, item := range node.Query(ast.QueryTypeTop, ast.MarkerCaseBranch, ast.MarkerCaseElse) {
+		if item.Has(ast.MarkerCaseElse) {
+			if blocks := ast.Blocks(item); len(blocks) != 0 {
+				elseBlock = blocks[0]
+			}
+
+			continue
+		}
+
+		labels, block := caseBranchNodes(item)
+
+		branch := CaseBranch{Block: block}
+		for _, labelNode := range labels {
+			label, err := caseLabel(lookup, labelNode)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			branch.Labels = append(branch.Labels, label)
+		}
+
+		branches = append(branches, branch)
+	}
+
+	return branches, elseBlock, nil
+}
+
+// Ordinal returns an ordinal value of the constant of int, char, bool or enum type.
+// Values of enum constants are stored as their ordinal values.
+func Ordinal(t symbol.BuiltinType, raw string) (int, error) {
+	switch t {
+	case symbol.BuiltinTypeInt, symbol.BuiltinTypeEnum:
+		v, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid int value %s: %w", raw, err)
+		}
+
+		return int(v), nil
+	case symbol.BuiltinTypeChar:
+		v, err := token.Unquote(raw)
+		if err != nil || len(v) != 1 {
+			return 0, fmt.Errorf("invalid char value %s", raw)
+		}
+
+		return int(v[0]), nil
+	case symbol.BuiltinTypeBool:
+		switch raw {
+		case "true":
+			return 1, nil
+		case "false":
+			return 0, nil
+		default:
+			return 0, fmt.Errorf("invalid bool value %s", raw)
+		}
+	default:
+		return 0, fmt.Errorf("%s is not an ordinal type", t)
+	}
+}
+
+// isOrdinal checks whether values of the type can be used as case labels, for loop variables and subrange bounds.
+func isOrdinal(t symbol.BuiltinType) bool {
+	return t == symbol.BuiltinTypeInt || t == symbol.BuiltinTypeChar || t == symbol.BuiltinTypeBool || t == symbol.BuiltinTypeEnum
+}
+
+// checkCase checks that the case selector has an ordinal type, and labels are constants of the same type without duplicates.
+func (c TypeChecker) checkCase(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+) {
+	expr := node.Query(ast.QueryTypeOne, ast.MarkerCaseExpr)[0]
+
+	selectorType, err := c.resolver.Resolve(ctx, scope, expr)
+	if err != nil {
+		addError(ctx, expr.Position(), err)
+		return
+	}
+
+	if !isOrdinal(selectorType) {
+		ctx.AddError(context.ErrorSourceTypecheck, expr.Position(), fmt.Errorf("case selector must have ordinal type, got %s", selectorType))
+		return
+	}
+
+	lookup := func(name string) (symbol.Symbol, error) {
+		return ctx.Neutralizer().NeutralizeUserDefined(scope, name)
+	}
+
+	var seen []CaseLabel
+	for _, branch := range node.Query(ast.QueryTypeTop, ast.MarkerCaseBranch, ast.MarkerCaseElse) {
+		if branch.Has(ast.MarkerCaseElse) {
+			continue
+		}
+
+		labels, _ := caseBranchNodes(branch)
+		for _, labelNode := range labels {
+			label, err := caseLabel(lookup, labelNode)
+			if err != nil {
+				// Int literals out of range were already reported.
+				if !errors.Is(err, errIntLiteralRange) {
+					addError(ctx, labelNode.Position(), err)
+				}
+
+				continue
+			}
+
+			if label.Type != selectorType {
+				ctx.AddError(context.ErrorSourceTypecheck, labelNode.Position(), fmt.Errorf("case label has type %s, but selector has type %s", label.Type, selectorType))
+				continue
+			}
+
+			if label.Low > label.High {
+				ctx.AddError(context.ErrorSourceTypecheck, labelNode.Position(), fmt.Errorf("case label range is empty"))
+				continue
+			}
+
+			for _, other := range seen {
+				if label.Low <= other.High && other.Low <= label.High {
+					ctx.AddError(context.ErrorSourceTypecheck, labelNode.Position(), fmt.Errorf("duplicate case label, already used at %v", other.Node.Position()))
+					break
+				}
+			}
+
+			seen = append(seen, label)
+		}
+	}
+}
+
+// caseBranchNodes returns label nodes and the block of the case branch (nil if the branch has no operators).
+// Nested case operators are located in the block, so their labels are skipped.
+func caseBranchNodes(branch ast.Node) ([]ast.Node, ast.Node) {
+	var labels []ast.Node
+	var block ast.Node
+	for _, item := range branch.(*ast.Branch).Items {
+		for _, node := range item.Query(ast.QueryTypeTop, ast.MarkerCaseLabel, ast.MarkerBlock) {
+			if node.Has(ast.MarkerBlock) {
+				block = node
+				continue
+			}
+
+			labels = append(labels, node)
+		}
+	}
+
+	return labels, block
+}
+
+// caseLabel converts the single value or the range of values to ordinal values.
+func caseLabel(lookup func(name string) (symbol.Symbol, error), node ast.Node) (CaseLabel, error) {
+	if node.Has(ast.MarkerValue) {
+		v, t, err := ordinalConst(lookup, node)
+		if err != nil {
+			return CaseLabel{}, err
+		}
+
+		return CaseLabel{Node: node, Low: v, High: v, Type: t.BuiltinType}, nil
+	}
+
+	bounds := node.Query(ast.QueryTypeTop, ast.MarkerValue)
+
+	low, lowType, err := ordinalConst(lookup, bounds[0])
+	if err != nil {
+		return CaseLabel{}, err
+	}
+
+	high, highType, err := ordinalConst(lookup, bounds[1])
+	if err != nil {
+		return CaseLabel{}, err
+	}
+
+	if lowType.BuiltinType != highType.BuiltinType {
+		return CaseLabel{}, fmt.Errorf("bounds of case label range have different types %s and %s", lowType.BuiltinType, highType.BuiltinType)
+	}
+
+	return CaseLabel{Node: node, Low: low, High: high, Type: lowType.BuiltinType}, nil
+}
+
+// scopeLookup returns a function which looks for symbols in the scope without neutralization of errors.
+func scopeLookup(scope symbol.Scope) func(name string) (symbol.Symbol, error) {
+	return func(name string) (symbol.Symbol, error) {
+		s, ok := scope.Lookup(&symbol.Name{Name: name})
+		if !ok {
+			return nil, fmt.Errorf("no symbol found for %s", name)
+		}
+
+		return s, nil
+	}
+}
+
+// ordinalConst returns an ordinal value and a type of the literal or the constant with an optional sign.
+// It's used for case labels and bounds of subrange types.
+func ordinalConst(lookup func(name string) (symbol.Symbol, error), node ast.Node) (int, symbol.Type, error) {
+	sign := 1
+	signed := false
+	if branch, ok := node.(*ast.Branch); ok {
+		if branch.Items[0].(*ast.Leaf).ID == token.Minus {
+			sign = -1
+		}
+
+		signed = true
+		node = branch.Items[len(branch.Items)-1]
+	}
+
+	leaf := node.(*ast.Leaf)
+
+	var t symbol.Type
+	raw := leaf.Value
+
+	// Minus is folded into the int literal, so the smallest int can be used.
+	if leaf.ID == token.IntLiteral && sign == -1 {
+		raw, sign = "-"+raw, 1
+	}
+
+	switch leaf.ID {
+	case token.IntLiteral:
+		t = symbol.Type{BuiltinType: symbol.BuiltinTypeInt}
+
+		if _, err := parseIntLiteral(raw); err != nil {
+			return 0, symbol.Type{}, err
+		}
+	case token.StringLiteral:
+		t = symbol.Type{BuiltinType: StringLiteralType(leaf.Value)}
+	case token.BoolLiteral:
+		t = symbol.Type{BuiltinType: symbol.BuiltinTypeBool}
+	case token.UserDefined:
+		s, err := lookup(leaf.Value)
+		if err != nil {
+			return 0, symbol.Type{}, atName(leaf, err)
+		}
+
+		constant, ok := s.(*symbol.Const)
+		if !ok {
+			return 0, symbol.Type{}, fmt.Errorf("%s must be a constant", leaf.Value)
+		}
+
+		t = constant.Type
+		raw = constant.RawValue
+	default:
+		return 0, symbol.Type{}, fmt.Errorf("unexpected token id %v", leaf.ID)
+	}
+
+	if !isOrdinal(t.BuiltinType) {
+		return 0, symbol.Type{}, fmt.Errorf("value must have ordinal type, got %s", t.BuiltinType)
+	}
+
+	if signed && t.BuiltinType != symbol.BuiltinTypeInt {
+		return 0, symbol.Type{}, fmt.Errorf("sign can't be used with %s values", t.BuiltinType)
+	}
+
+	v, err := Ordinal(t.BuiltinType, raw)
+	if err != nil {
+		return 0, symbol.Type{}, err
+	}
+
+	return sign * v, t, nil
+}
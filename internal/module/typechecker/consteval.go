@@ -0,0 +1,467 @@
+package typechecker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+// errIntLiteralRange is returned for int literals which don't fit in 32-bit ints.
+// Such literals are reported once by checkIntLiterals, so other checks don't report them again.
+var errIntLiteralRange = errors.New("integer literal is out of range")
+
+// constError is an error in the constant expression with the position of the operand or the operation which caused it.
+type constError struct {
+	Position literal.Position
+	Err      error
+}
+
+func (e constError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Position, e.Err)
+}
+
+func (e constError) Unwrap() error {
+	return e.Err
+}
+
+// constValue is a value of the constant expression computed at compile time.
+// Only the field matching the type is used. Ints, chars and enums store their ordinal values in Int.
+type constValue struct {
+	Type   symbol.Type
+	Int    int64
+	Double float64
+	Bool   bool
+	Str    string
+}
+
+// RawValue returns the value as it's stored in constants.
+func (v constValue) RawValue() string {
+	switch v.Type.BuiltinType {
+	case symbol.BuiltinTypeDouble:
+		return strconv.FormatFloat(v.Double, 'g', -1, 64)
+	case symbol.BuiltinTypeBool:
+		return strconv.FormatBool(v.Bool)
+	case symbol.BuiltinTypeString:
+		return token.Quote(v.Str)
+	case symbol.BuiltinTypeChar:
+		return token.Quote(string([]byte{byte(v.Int)}))
+	default:
+		return strconv.FormatInt(v.Int, 10)
+	}
+}
+
+func (v constValue) toDouble() float64 {
+	if v.Type.BuiltinType == symbol.BuiltinTypeDouble {
+		return v.Double
+	}
+
+	return float64(v.Int)
+}
+
+// evalConst computes the value of the constant expression.
+// Expression must be type checked before, so operands have types supported by operations.
+// Ints are 32-bit wide, as in generated code, so overflows are reported instead of wrapping around.
+func evalConst(scope symbol.Scope, tree *ast.Expr) (constValue, error) {
+	switch {
+	case tree.IsCall():
+		return evalConstCall(scope, tree)
+	case tree.HasSelectors():
+		return constValue{}, constError{Position: tree.Position(), Err: fmt.Errorf("%v is not a constant", tree)}
+	case tree.IsOperand():
+		return evalConstOperand(scope, tree.Leaf)
+	case tree.IsNegatedIntLiteral():
+		v, err := parseIntLiteral("-" + tree.Right.Leaf.Value)
+		if err != nil {
+			return constValue{}, constError{Position: tree.Right.Leaf.Position(), Err: err}
+		}
+
+		return constValue{Type: symbol.Type{BuiltinType: symbol.BuiltinTypeInt}, Int: v}, nil
+	case tree.IsUnary():
+		v, err := evalConst(scope, tree.Right)
+		if err != nil {
+			return constValue{}, err
+		}
+
+		switch {
+		case tree.Leaf.ID == token.Not:
+			v.Bool = !v.Bool
+		case tree.Leaf.ID == token.Minus && v.Type.BuiltinType == symbol.BuiltinTypeDouble:
+			v.Double = -v.Double
+		case tree.Leaf.ID == token.Minus:
+			v.Int = -v.Int
+			if v.Int > math.MaxInt32 {
+				return constValue{}, constError{Position: tree.Position(), Err: fmt.Errorf("integer overflow in constant expression")}
+			}
+		}
+
+		return v, nil
+	}
+
+	left, err := evalConst(scope, tree.Left)
+	if err != nil {
+		return constValue{}, err
+	}
+
+	right, err := evalConst(scope, tree.Right)
+	if err != nil {
+		return constValue{}, err
+	}
+
+	v, err := evalConstOp(tree.Leaf.ID, left, right)
+	if err != nil {
+		return constValue{}, constError{Position: tree.Position(), Err: err}
+	}
+
+	return v, nil
+}
+
+// evalConstCall computes ord, succ or pred of the constant argument. Other functions aren't constant.
+func evalConstCall(scope symbol.Scope, tree *ast.Expr) (constValue, error) {
+	args := ast.CallArgs(tree.Call)
+
+	f, ok := scope.LookupFunc(tree.Leaf.Value)
+	if !ok || !IsOrdinalBuiltin(f) || len(args) != 1 {
+		return constValue{}, constError{Position: tree.Position(), Err: fmt.Errorf("%v is not a constant", tree)}
+	}
+
+	arg, err := ast.NewExpr(args[0])
+	if err != nil {
+		return constValue{}, constError{Position: args[0].Position(), Err: err}
+	}
+
+	v, err := evalConst(scope, arg)
+	if err != nil {
+		return constValue{}, err
+	}
+
+	v, err = evalOrdinalFunc(f.Value, v)
+	if err != nil {
+		return constValue{}, constError{Position: tree.Position(), Err: err}
+	}
+
+	return v, nil
+}
+
+// evalOrdinalFunc computes ord, succ or pred of the constant.
+// Results of succ and pred must be values of the argument type, so the first and the last values have no pred and succ.
+func evalOrdinalFunc(name string, v constValue) (constValue, error) {
+	ordinal := v.Int
+	if v.Type.BuiltinType == symbol.BuiltinTypeBool && v.Bool {
+		ordinal = 1
+	}
+
+	var res int64
+	switch name {
+	case "ord":
+		return constValue{Type: symbol.Type{BuiltinType: symbol.BuiltinTypeInt}, Int: ordinal}, nil
+	case "succ":
+		res = ordinal + 1
+	default:
+		res = ordinal - 1
+	}
+
+	low, high := int64(math.MinInt32), int64(math.MaxInt32)
+	switch {
+	case v.Type.Enum != nil:
+		low, high = 0, int64(len(v.Type.Enum.Values)-1)
+	case v.Type.BuiltinType == symbol.BuiltinTypeBool:
+		low, high = 0, 1
+	case v.Type.BuiltinType == symbol.BuiltinTypeChar:
+		low, high = 0, math.MaxUint8
+	}
+
+	if res < low || res > high {
+		if v.Type.BuiltinType == symbol.BuiltinTypeInt {
+			return constValue{}, fmt.Errorf("integer overflow in constant expression")
+		}
+
+		return constValue{}, fmt.Errorf("%s(%s) is out of range %s..%s",
+			name, v.Type.OrdinalName(int(ordinal)), v.Type.OrdinalName(int(low)), v.Type.OrdinalName(int(high)))
+	}
+
+	v.Int = res
+	v.Bool = res != 0
+
+	return v, nil
+}
+
+func evalConstOperand(scope symbol.Scope, leaf *ast.Leaf) (constValue, error) {
+	var (
+		v   constValue
+		err error
+	)
+
+	switch leaf.ID {
+	case token.IntLiteral:
+		v.Type = symbol.Type{BuiltinType: symbol.BuiltinTypeInt}
+		v.Int, err = parseIntLiteral(leaf.Value)
+	case token.DoubleLiteral:
+		v.Type = symbol.Type{BuiltinType: symbol.BuiltinTypeDouble}
+		v.Double, err = strconv.ParseFloat(leaf.Value, 64)
+	case token.BoolLiteral:
+		v.Type = symbol.Type{BuiltinType: symbol.BuiltinTypeBool}
+		v.Bool = leaf.Value == "true"
+	case token.StringLiteral:
+		v, err = stringConst(StringLiteralType(leaf.Value), leaf.Value)
+	case token.UserDefined:
+		s, ok := scope.Lookup(&symbol.Name{Name: leaf.Value})
+		if !ok {
+			err = fmt.Errorf("no symbol found for %s", leaf.Value)
+			break
+		}
+
+		constant, ok := s.(*symbol.Const)
+		if !ok {
+			err = fmt.Errorf("%s is not a constant", leaf.Value)
+			break
+		}
+
+		v, err = constFromRaw(constant.Type, constant.RawValue)
+	default:
+		err = fmt.Errorf("unexpected token id %v", leaf.ID)
+	}
+
+	if err != nil {
+		return constValue{}, constError{Position: leaf.Position(), Err: err}
+	}
+
+	return v, nil
+}
+
+// parseIntLiteral parses the int literal with the minus of the unary operator if it's negated.
+// Negative values are written with unary minus, so the minus is folded into the literal before the range check,
+// and the smallest int can be written even though the literal without minus is greater than the largest int.
+func parseIntLiteral(raw string) (int64, error) {
+	v, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil && strings.HasPrefix(raw, "-") {
+		return 0, fmt.Errorf("%w: %s is less than %d", errIntLiteralRange, raw, math.MinInt32)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is greater than %d", errIntLiteralRange, raw, math.MaxInt32)
+	}
+
+	return v, nil
+}
+
+// checkIntLiterals reports int literals which don't fit in 32-bit ints at their positions.
+// Unary minus is the leaf without the operator marker right before the literal, so it's folded into the literal.
+func checkIntLiterals(ctx context.ErrorsContext, node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Leaf:
+		if node.ID != token.IntLiteral {
+			return
+		}
+
+		if _, err := parseIntLiteral(node.Value); err != nil {
+			ctx.AddError(context.ErrorSourceTypecheck, node.Position(), err)
+		}
+	case *ast.Branch:
+		for i := 0; i < len(node.Items); i++ {
+			if value, ok := negatedIntLiteral(node.Items, i); ok {
+				if _, err := parseIntLiteral("-" + value.Value); err != nil {
+					ctx.AddError(context.ErrorSourceTypecheck, value.Position(), err)
+				}
+
+				i++
+				continue
+			}
+
+			checkIntLiterals(ctx, node.Items[i])
+		}
+	}
+}
+
+// negatedIntLiteral returns the int literal after the item if the item is unary minus.
+func negatedIntLiteral(items []ast.Node, i int) (*ast.Leaf, bool) {
+	minus, ok := items[i].(*ast.Leaf)
+	if !ok || minus.ID != token.Minus || minus.Has(ast.MarkerAdditiveOp) || i+1 == len(items) {
+		return nil, false
+	}
+
+	value, ok := items[i+1].(*ast.Leaf)
+	if !ok || value.ID != token.IntLiteral {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// constFromRaw parses the value of the declared constant.
+func constFromRaw(t symbol.Type, raw string) (constValue, error) {
+	v := constValue{Type: t}
+
+	var err error
+	switch t.BuiltinType {
+	case symbol.BuiltinTypeInt, symbol.BuiltinTypeEnum:
+		v.Int, err = strconv.ParseInt(raw, 10, 32)
+	case symbol.BuiltinTypeDouble:
+		v.Double, err = strconv.ParseFloat(raw, 64)
+	case symbol.BuiltinTypeBool:
+		v.Bool = raw == "true"
+	case symbol.BuiltinTypeString, symbol.BuiltinTypeChar:
+		s, err := stringConst(t.BuiltinType, raw)
+		if err != nil {
+			return constValue{}, err
+		}
+
+		s.Type = t
+		return s, nil
+	default:
+		err = fmt.Errorf("unsupported constant type %s", t.BuiltinType)
+	}
+
+	return v, err
+}
+
+func stringConst(t symbol.BuiltinType, raw string) (constValue, error) {
+	s, err := token.Unquote(raw)
+	if err != nil {
+		return constValue{}, err
+	}
+
+	if t == symbol.BuiltinTypeChar {
+		return constValue{Type: symbol.Type{BuiltinType: t}, Int: int64(s[0])}, nil
+	}
+
+	return constValue{Type: symbol.Type{BuiltinType: t}, Str: s}, nil
+}
+
+// evalConstOp computes the binary operation the same way as the interpreter does.
+func evalConstOp(op token.ID, left, right constValue) (constValue, error) {
+	// Ints are converted to doubles if the other operand is double.
+	if left.Type.BuiltinType == symbol.BuiltinTypeDouble || right.Type.BuiltinType == symbol.BuiltinTypeDouble {
+		return evalConstDoubleOp(op, left.toDouble(), right.toDouble())
+	}
+
+	switch left.Type.BuiltinType {
+	case symbol.BuiltinTypeBool:
+		return evalConstBoolOp(op, left.Bool, right.Bool)
+	case symbol.BuiltinTypeInt:
+		return evalConstIntOp(op, left.Int, right.Int)
+	case symbol.BuiltinTypeChar, symbol.BuiltinTypeEnum:
+		// Chars and enums are compared by their ordinal values.
+		return evalConstIntOp(op, left.Int, right.Int)
+	default:
+		return constValue{}, fmt.Errorf("unsupported operand type %s", left.Type.BuiltinType)
+	}
+}
+
+func evalConstIntOp(op token.ID, l, r int64) (constValue, error) {
+	var v int64
+	switch op {
+	case token.Plus:
+		v = l + r
+	case token.Minus:
+		v = l - r
+	case token.Multiply:
+		v = l * r
+	case token.Divide, token.Div:
+		if r == 0 {
+			return constValue{}, fmt.Errorf("division by zero in constant expression")
+		}
+
+		v = l / r
+	case token.Mod:
+		if r == 0 {
+			return constValue{}, fmt.Errorf("division by zero in constant expression")
+		}
+
+		v = l % r
+	default:
+		return compareConst(op, compare(l, r))
+	}
+
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return constValue{}, fmt.Errorf("integer overflow in constant expression")
+	}
+
+	return constValue{Type: symbol.Type{BuiltinType: symbol.BuiltinTypeInt}, Int: v}, nil
+}
+
+func evalConstDoubleOp(op token.ID, l, r float64) (constValue, error) {
+	var v float64
+	switch op {
+	case token.Plus:
+		v = l + r
+	case token.Minus:
+		v = l - r
+	case token.Multiply:
+		v = l * r
+	case token.Divide:
+		if r == 0 {
+			return constValue{}, fmt.Errorf("division by zero in constant expression")
+		}
+
+		v = l / r
+	default:
+		return compareConst(op, compare(l, r))
+	}
+
+	if math.IsInf(v, 0) {
+		return constValue{}, fmt.Errorf("floating point overflow in constant expression")
+	}
+
+	return constValue{Type: symbol.Type{BuiltinType: symbol.BuiltinTypeDouble}, Double: v}, nil
+}
+
+func evalConstBoolOp(op token.ID, l, r bool) (constValue, error) {
+	var v bool
+	switch op {
+	case token.And:
+		v = l && r
+	case token.Or:
+		v = l || r
+	case token.Xor, token.Ne:
+		v = l != r
+	case token.Eq:
+		v = l == r
+	default:
+		return constValue{}, fmt.Errorf("unsupported operation %s for bools", op)
+	}
+
+	return constValue{Type: symbol.Type{BuiltinType: symbol.BuiltinTypeBool}, Bool: v}, nil
+}
+
+// compareConst converts the result of comparison (-1, 0 or 1) to the value of the comparison operation.
+func compareConst(op token.ID, cmp int) (constValue, error) {
+	var v bool
+	switch op {
+	case token.Eq:
+		v = cmp == 0
+	case token.Ne:
+		v = cmp != 0
+	case token.Lt:
+		v = cmp < 0
+	case token.Lte:
+		v = cmp <= 0
+	case token.Gt:
+		v = cmp > 0
+	case token.Gte:
+		v = cmp >= 0
+	default:
+		return constValue{}, fmt.Errorf("unsupported operation %s in constant expression", op)
+	}
+
+	return constValue{Type: symbol.Type{BuiltinType: symbol.BuiltinTypeBool}, Bool: v}, nil
+}
+
+// compare returns -1, 0 or 1 if the left value is less than, equal to or greater than the right one.
+func compare[T int64 | float64](l, r T) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,315 @@
+package typechecker
+
+import (
+	"fmt"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+// assigned is a set of variables which are definitely assigned at some point of the program.
+type assigned map[*symbol.Var]bool
+
+func (a assigned) copy() assigned {
+	res := make(assigned, len(a))
+	for v := range a {
+		res[v] = true
+	}
+
+	return res
+}
+
+// intersect returns variables assigned in all branches.
+func intersect(branches ...assigned) assigned {
+	res := branches[0].copy()
+	for v := range res {
+		for _, other := range branches[1:] {
+			if !other[v] {
+				delete(res, v)
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+// initChecker checks that variables of the block are assigned before they are read.
+// Only scalar variables declared in the block are tracked, as arrays and records are assigned element by element.
+// Params are initialized by the caller, so they are never tracked.
+type initChecker struct {
+	ctx interface {
+		context.ErrorsContext
+		context.NeutralizerContext
+	}
+	scope symbol.Scope
+	// warned contains variables which were already reported, so each variable is reported once.
+	warned map[*symbol.Var]bool
+}
+
+// checkInitialization warns about variables which may be read before they are assigned,
+// and reports an error if the function may return without assigning its result.
+// Result is nil for the program block and for procedures.
+// Operators with syntax errors may contain assignments which weren't parsed, so blocks with them aren't checked.
+func (c TypeChecker) checkInitialization(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	program ast.Node,
+	result *symbol.Var,
+) {
+	if len(program.Query(ast.QueryTypeRecursive, ast.MarkerError)) != 0 {
+		return
+	}
+
+	ic := initChecker{ctx: ctx, scope: scope, warned: map[*symbol.Var]bool{}}
+
+	state := ic.checkBlock(program, assigned{})
+
+	if result != nil && !state[result] {
+		ctx.AddError(context.ErrorSourceTypecheck, result.Position, fmt.Errorf("function %s may return without assigning its result", result.Value))
+	}
+}
+
+// checkBlock checks operators of the block. Empty operators have no blocks, so nil blocks don't change the state.
+func (ic initChecker) checkBlock(block ast.Node, state assigned) assigned {
+	if block == nil {
+		return state
+	}
+
+	nodes := block.Query(ast.QueryTypeTop,
+		ast.MarkerAssign,
+		ast.MarkerIf,
+		ast.MarkerFor,
+		ast.MarkerWhile,
+		ast.MarkerRepeat,
+		ast.MarkerWith,
+		ast.MarkerCase,
+		ast.MarkerFuncCall,
+	)
+
+	for _, node := range nodes {
+		state = ic.checkOperator(node, state)
+	}
+
+	return state
+}
+
+func (ic initChecker) checkOperator(node ast.Node, state assigned) assigned {
+	switch {
+	case node.Has(ast.MarkerAssign):
+		left := node.Query(ast.QueryTypeOne, ast.MarkerLeftSide)[0]
+		expr := node.Query(ast.QueryTypeOne, ast.MarkerRightSide)[0].Query(ast.QueryTypeOne, ast.MarkerExpr)[0]
+
+		// Right side is evaluated before the variable is assigned, so "x := x + 1" reads x.
+		state = ic.checkExpr(expr, state)
+		return ic.assign(left, state)
+	case node.Has(ast.MarkerIf):
+		cond := node.Query(ast.QueryTypeOne, ast.MarkerExpr)[0]
+		state = ic.checkExpr(cond, state)
+
+		then, otherwise := ast.IfBlocks(node)
+
+		// Only the branch selected by the constant condition is executed.
+		if v, ok := ic.constCond(cond); ok {
+			if v {
+				return ic.checkBlock(then, state)
+			}
+
+			return ic.checkBlock(otherwise, state)
+		}
+
+		return intersect(ic.checkBlock(then, state.copy()), ic.checkBlock(otherwise, state.copy()))
+	case node.Has(ast.MarkerCase):
+		state = ic.checkExpr(node.Query(ast.QueryTypeOne, ast.MarkerCaseExpr)[0], state)
+
+		branches, elseBlock, err := CaseBranches(ic.scope, node)
+		if err != nil {
+			// Labels were already reported by the type checker.
+			return state
+		}
+
+		var states []assigned
+		for _, branch := range branches {
+			if branch.Block == nil {
+				states = append(states, state)
+				continue
+			}
+
+			states = append(states, ic.checkBlock(branch.Block, state.copy()))
+		}
+
+		// Selector may match no labels, so the state before the case operator is used as the else branch.
+		if elseBlock != nil {
+			states = append(states, ic.checkBlock(elseBlock, state.copy()))
+		} else {
+			states = append(states, state)
+		}
+
+		return intersect(states...)
+	case node.Has(ast.MarkerFor):
+		header := node.Query(ast.QueryTypeOne, ast.MarkerForHeader)[0]
+		for _, expr := range header.Query(ast.QueryTypeTop, ast.MarkerExpr) {
+			state = ic.checkExpr(expr, state)
+		}
+
+		// Control variable is assigned even if the body is never executed.
+		state = ic.assign(header.Query(ast.QueryTypeOne, ast.MarkerForVar)[0], state)
+
+		ic.checkBlock(ast.Body(node), state.copy())
+		return state
+	case node.Has(ast.MarkerWhile):
+		state = ic.checkExpr(node.Query(ast.QueryTypeOne, ast.MarkerExpr)[0], state)
+
+		// Body may be never executed, so assignments in it are discarded.
+		ic.checkBlock(ast.Body(node), state.copy())
+		return state
+	case node.Has(ast.MarkerRepeat):
+		// Body is executed at least once, so the condition sees its assignments.
+		state = ic.checkBlock(ast.Body(node), state)
+		return ic.checkExpr(node.Query(ast.QueryTypeOne, ast.MarkerRepeatExpr)[0], state)
+	case node.Has(ast.MarkerWith):
+		return ic.checkBlock(ast.Body(node), state)
+	case node.Has(ast.MarkerFuncCall):
+		return ic.checkCall(node, state)
+	default:
+		return state
+	}
+}
+
+// constCond returns the value of the condition if it's a constant expression.
+func (ic initChecker) constCond(cond ast.Node) (bool, bool) {
+	tree, err := ast.NewExpr(cond)
+	if err != nil {
+		return false, false
+	}
+
+	v, err := evalConst(ic.scope, tree)
+	if err != nil || v.Type.BuiltinType != symbol.BuiltinTypeBool {
+		return false, false
+	}
+
+	return v.Bool, true
+}
+
+// checkExpr reports variables read in the expression before they are assigned.
+// Expressions with errors were already reported by the type checker, so they are skipped.
+func (ic initChecker) checkExpr(expr ast.Node, state assigned) assigned {
+	tree, err := ast.NewExpr(expr)
+	if err != nil {
+		return state
+	}
+
+	return ic.checkTree(tree, state)
+}
+
+func (ic initChecker) checkTree(tree *ast.Expr, state assigned) assigned {
+	switch {
+	case tree.IsCall():
+		return ic.checkCall(tree.Call, state)
+	case tree.HasSelectors():
+		return ic.checkSelectors(tree.Variable, state)
+	case tree.IsOperand():
+		if v, ok := ic.tracked(tree.Leaf); ok && !state[v] && !ic.warned[v] {
+			ic.warned[v] = true
+			ic.ctx.AddWarning(context.ErrorSourceTypecheck, tree.Leaf.Position(), fmt.Errorf("variable %s may be used before it's assigned", v.Value))
+		}
+
+		return state
+	case tree.IsUnary():
+		return ic.checkTree(tree.Right, state)
+	default:
+		state = ic.checkTree(tree.Left, state)
+		return ic.checkTree(tree.Right, state)
+	}
+}
+
+// checkCall checks arguments of the call.
+// Variables passed by reference are treated as assigned, as the function may assign them before reading.
+// Functions declared in the block may assign any variable of the block, so all variables are treated as assigned after the call.
+func (ic initChecker) checkCall(call ast.Node, state assigned) assigned {
+	name := call.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
+	f, ok := ic.scope.LookupFunc(name.Value)
+
+	for i, arg := range ast.CallArgs(call) {
+		if ok && i < len(f.Params) && f.Params[i].ByRef {
+			state = ic.assign(arg, state)
+			continue
+		}
+
+		state = ic.checkExpr(arg, state)
+	}
+
+	// Builtin functions don't have positions and don't assign variables.
+	if !ok || f.Position == (literal.Position{}) {
+		return state
+	}
+
+	if local, ok := ic.scope.LookupLocal(&symbol.Name{Name: name.Value}); !ok || local != symbol.Symbol(f) {
+		return state
+	}
+
+	for _, s := range ic.scope.Symbols() {
+		if v, ok := s.(*symbol.Var); ok {
+			state[v] = true
+		}
+	}
+
+	return state
+}
+
+// assign marks the variable as assigned.
+// Indexes of the variable are read before the assignment, and variables with selectors are never tracked.
+func (ic initChecker) assign(variable ast.Node, state assigned) assigned {
+	tree, err := ast.NewExpr(variable)
+	if err != nil || !tree.IsOperand() || tree.IsCall() {
+		return state
+	}
+
+	if tree.HasSelectors() {
+		return ic.checkSelectors(tree.Variable, state)
+	}
+
+	if v, ok := ic.tracked(tree.Leaf); ok {
+		state[v] = true
+	}
+
+	return state
+}
+
+func (ic initChecker) checkSelectors(variable ast.Node, state assigned) assigned {
+	for _, selector := range ast.Selectors(variable) {
+		if !selector.Has(ast.MarkerField) {
+			state = ic.checkExpr(selector, state)
+		}
+	}
+
+	return state
+}
+
+// tracked returns the variable if it's declared in the block, isn't a param and isn't composite.
+// Misspelled names were already reported, so they are replaced with suggested ones to avoid repeated errors.
+func (ic initChecker) tracked(leaf *ast.Leaf) (*symbol.Var, bool) {
+	if leaf.ID != token.UserDefined {
+		return nil, false
+	}
+
+	s, _ := ic.ctx.Neutralizer().NeutralizeUserDefined(ic.scope, leaf.Value)
+
+	v, ok := s.(*symbol.Var)
+	if !ok || v.Param || v.Type.IsComposite() {
+		return nil, false
+	}
+
+	if local, ok := ic.scope.LookupLocal(v); !ok || local != symbol.Symbol(v) {
+		return nil, false
+	}
+
+	return v, true
+}
@@ -0,0 +1,46 @@
+package typechecker
+
+import (
+	"errors"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/module/neutralizer"
+)
+
+// nameError is a misspelled name with the position of the name.
+// Lookup errors are often reported at the whole expression, and the name error is reported at the name instead,
+// so the fix replaces the name and not another occurrence of the same word.
+type nameError struct {
+	Position literal.Position
+	Err      error
+}
+
+func (e nameError) Error() string {
+	return e.Err.Error()
+}
+
+func (e nameError) Unwrap() error {
+	return e.Err
+}
+
+// atName returns the error with the position of the name if the name is misspelled.
+func atName(name *ast.Leaf, err error) error {
+	var fixable neutralizer.Fixable
+	if !errors.As(err, &fixable) {
+		return err
+	}
+
+	return nameError{Position: name.Position(), Err: err}
+}
+
+// addError reports the error at the position, or at the position of the misspelled name for name errors.
+func addError(ctx context.ErrorsContext, position literal.Position, err error) {
+	var nameErr nameError
+	if errors.As(err, &nameErr) {
+		position, err = nameErr.Position, nameErr.Err
+	}
+
+	ctx.AddError(context.ErrorSourceTypecheck, position, err)
+}
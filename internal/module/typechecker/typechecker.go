@@ -1,7 +1,9 @@
 package typechecker
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/iskorotkov/compiler/internal/context"
 	"github.com/iskorotkov/compiler/internal/data/ast"
@@ -9,9 +11,28 @@ import (
 	"github.com/iskorotkov/compiler/internal/data/token"
 )
 
+// constTypeNames are names of builtin types of constants.
+var constTypeNames = map[symbol.BuiltinType]string{
+	symbol.BuiltinTypeInt:    "integer",
+	symbol.BuiltinTypeDouble: "real",
+	symbol.BuiltinTypeBool:   "boolean",
+	symbol.BuiltinTypeString: "string",
+	symbol.BuiltinTypeChar:   "char",
+}
+
+// maxArrayElems limits a size of arrays and records, so they fit into linear memory of generated modules.
+const maxArrayElems = 1 << 24
+
 type Result struct {
 	Node  ast.Node
 	Scope symbol.Scope
+	// Funcs declared in the program or function block.
+	Funcs []FuncResult
+}
+
+type FuncResult struct {
+	Result
+	Symbol *symbol.Func
 }
 
 type TypeChecker struct {
@@ -48,12 +69,19 @@ func (c TypeChecker) Check(
 			block := program.Query(ast.QueryTypeOne, ast.MarkerProgramBlock)[0]
 			scope := symbol.NewScope()
 
-			c.checkBlock(ctx, scope, block)
+			checkIntLiterals(ctx, program)
+
+			funcs := c.checkBlock(ctx, scope, block, nil)
 
-			ch <- Result{
+			result := Result{
 				Node:  program,
 				Scope: scope,
+				Funcs: funcs,
 			}
+
+			c.checkUsage(ctx, result)
+
+			ch <- result
 		}
 
 		ctx.Logger().Infof("type checking succeeded")
@@ -70,24 +98,28 @@ func (c TypeChecker) checkBlock(
 	},
 	scope symbol.Scope,
 	block ast.Node,
-) {
-	c.addTypeDecls(ctx, scope, block)
+	result *symbol.Var,
+) []FuncResult {
+	// Constants are added first, so they can be used as array bounds in types.
 	c.addConstDecls(ctx, scope, block)
+	c.addTypeDecls(ctx, scope, block)
 	c.addVarDecls(ctx, scope, block)
-	c.addFuncDecls(ctx, scope, block)
+	funcs := c.addFuncDecls(ctx, scope, block)
 
-	// TODO: Find a better way to find main program block.
-	operatorsBlocks := block.Query(ast.QueryTypeTop, ast.MarkerOperators)
-	mainBlock := operatorsBlocks[len(operatorsBlocks)-1]
+	mainBlock := ast.QueryBlock(block, ast.MarkerOperators)[0]
+	c.expandWith(ctx, scope, mainBlock, nil)
 
 	c.checkAssignments(ctx, scope, mainBlock)
 	c.checkFlowOperators(ctx, scope, mainBlock)
 	c.checkFunctionCalls(ctx, scope, mainBlock)
+	c.checkInitialization(ctx, scope, mainBlock, result)
 
 	ctx.Logger().Infof("type checker found %d symbols in current scope", len(scope.Symbols()))
 	for _, s := range scope.Symbols() {
 		ctx.Logger().Infof("%d: %v", s.Hash(), s)
 	}
+
+	return funcs
 }
 
 func (c TypeChecker) checkFunctionCalls(
@@ -99,40 +131,23 @@ func (c TypeChecker) checkFunctionCalls(
 	scope symbol.Scope,
 	program ast.Node,
 ) {
-	calls := program.Query(ast.QueryTypeTop, ast.MarkerFuncCall)
-	for _, call := range calls {
-		name := call.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
-
-		sym, err := ctx.Neutralizer().NeutralizeUserDefined(scope, name.Value)
-		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, name.Position(), err)
+	// Calls inside expressions are checked during expression type resolution,
+	// so here we check only calls used as operators.
+	for _, call := range program.Query(ast.QueryTypeTop, ast.MarkerFuncCall, ast.MarkerExpr) {
+		if call.Has(ast.MarkerExpr) {
 			continue
 		}
 
-		args := call.Query(ast.QueryTypeTop, ast.MarkerFuncArg)
-		switch sym := sym.(type) {
-		case *symbol.Var, *symbol.Const:
+		if _, err := c.resolver.ResolveCall(ctx, scope, call); err != nil {
+			addError(ctx, call.Position(), err)
 			continue
-		case *symbol.Func:
-			if len(args) != len(sym.Params) {
-				ctx.AddError(context.ErrorSourceTypecheck, name.Position(), fmt.Errorf("wrong number of arguments for function %s", name.Value))
-				continue
-			}
+		}
 
-			for i, arg := range args {
-				argType, err := c.resolver.Resolve(ctx, scope, arg)
-				if err != nil {
-					ctx.AddError(context.ErrorSourceTypecheck, arg.Position(), err)
-					continue
-				}
-
-				if _, ok := c.converter.IsAssignable(argType, sym.Params[i].Type.BuiltinType); ok {
-					ctx.AddError(context.ErrorSourceTypecheck, arg.Position(), fmt.Errorf("wrong type of argument %d for function %s", i, name.Value))
-					continue
-				}
-			}
-		default:
-			panic(fmt.Errorf("unexpected symbol type %T", sym))
+		// Ordinal builtins are computed in place, so there is no function to call.
+		name := call.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
+		if f, ok := scope.LookupFunc(name.Value); ok && IsOrdinalBuiltin(f) {
+			ctx.AddError(context.ErrorSourceTypecheck, call.Position(), fmt.Errorf("result of %s must be used", name.Value))
+			continue
 		}
 	}
 }
@@ -148,23 +163,39 @@ func (c TypeChecker) checkFlowOperators(
 ) {
 	forHeaders := program.Query(ast.QueryTypeTop, ast.MarkerForHeader)
 	for _, header := range forHeaders {
+		v := header.Query(ast.QueryTypeOne, ast.MarkerForVar)[0].(*ast.Leaf)
+
+		vSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, v.Value)
+		if err != nil {
+			addError(ctx, v.Position(), err)
+			continue
+		}
+
+		vVar, ok := vSymbol.(*symbol.Var)
+		if !ok || !isOrdinal(vVar.Type.BuiltinType) {
+			ctx.AddError(context.ErrorSourceTypecheck, v.Position(), fmt.Errorf("control variable in for loop must be an ordinal variable: %s", v.Value))
+			continue
+		}
+
+		varType := vVar.Type.BuiltinType
+
 		expressions := header.Query(ast.QueryTypeTop, ast.MarkerExpr)
 		fromExpr, toExpr := expressions[0], expressions[1]
 
 		fromType, err := c.resolver.Resolve(ctx, scope, fromExpr)
 		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, fromExpr.Position(), err)
+			addError(ctx, fromExpr.Position(), err)
 			continue
 		}
 
 		toType, err := c.resolver.Resolve(ctx, scope, toExpr)
 		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, toExpr.Position(), err)
+			addError(ctx, toExpr.Position(), err)
 			continue
 		}
 
-		if fromType != symbol.BuiltinTypeInt || toType != symbol.BuiltinTypeInt {
-			ctx.AddError(context.ErrorSourceTypecheck, fromExpr.Position().Join(toExpr.Position()), fmt.Errorf("range in for loop must have int type"))
+		if fromType != varType || toType != varType {
+			ctx.AddError(context.ErrorSourceTypecheck, fromExpr.Position().Join(toExpr.Position()), fmt.Errorf("range in for loop must have %s type", varType))
 			continue
 		}
 	}
@@ -177,7 +208,7 @@ func (c TypeChecker) checkFlowOperators(
 	for _, condition := range conditions {
 		conditionType, err := c.resolver.Resolve(ctx, scope, condition)
 		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, condition.Position(), err)
+			addError(ctx, condition.Position(), err)
 			continue
 		}
 
@@ -186,6 +217,10 @@ func (c TypeChecker) checkFlowOperators(
 			continue
 		}
 	}
+
+	for _, node := range program.Query(ast.QueryTypeRecursive, ast.MarkerCase) {
+		c.checkCase(ctx, scope, node)
+	}
 }
 
 func (c TypeChecker) checkAssignments(
@@ -199,16 +234,11 @@ func (c TypeChecker) checkAssignments(
 ) {
 	assignments := program.Query(ast.QueryTypeRecursive, ast.MarkerAssign)
 	for _, a := range assignments {
-		v := a.Query(ast.QueryTypeOne, ast.MarkerLeftSide)[0].(*ast.Leaf)
+		left := a.Query(ast.QueryTypeOne, ast.MarkerLeftSide)[0]
 
-		vSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, v.Value)
+		_, varType, err := c.resolver.ResolveVariable(ctx, scope, left)
 		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, v.Position(), err)
-			continue
-		}
-
-		if _, ok := vSymbol.(*symbol.Var); !ok {
-			ctx.AddError(context.ErrorSourceTypecheck, v.Position(), fmt.Errorf("symbol is not a variable: %s", v.Value))
+			addError(ctx, left.Position(), err)
 			continue
 		}
 
@@ -216,17 +246,65 @@ func (c TypeChecker) checkAssignments(
 
 		exprType, err := c.resolver.Resolve(ctx, scope, expr[0])
 		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, a.Position(), err)
+			addError(ctx, a.Position(), err)
 			continue
 		}
 
-		if _, ok := c.converter.IsAssignable(exprType, vSymbol.(*symbol.Var).Type.BuiltinType); !ok {
-			ctx.AddError(context.ErrorSourceTypecheck, a.Position(), fmt.Errorf("type mismatch: %s", vSymbol.(*symbol.Var).Type.BuiltinType))
+		if _, ok := c.converter.IsAssignable(exprType, varType.BuiltinType); !ok {
+			ctx.AddError(context.ErrorSourceTypecheck, a.Position(), fmt.Errorf("type mismatch: %s", varType.BuiltinType))
 			continue
 		}
+
+		if varType.Range != nil {
+			if err := checkConstRange(scope, expr[0], varType); err != nil {
+				addError(ctx, expr[0].Position(), err)
+				continue
+			}
+		}
 	}
 }
 
+// checkConstRange checks that the constant assigned to the subrange variable is within its range.
+// Other values are checked at runtime if range checks are enabled in the code generator.
+func checkConstRange(scope symbol.Scope, expr ast.Node, t symbol.Type) error {
+	tree, err := ast.NewExpr(expr)
+	if err != nil {
+		return err
+	}
+
+	// Minus is folded into int literals, so the smallest int is checked as well.
+	if tree.IsNegatedIntLiteral() {
+		v, err := parseIntLiteral("-" + tree.Right.Leaf.Value)
+		if err == nil && !t.Range.Contains(int(v)) {
+			return fmt.Errorf("value %s is out of range %s..%s", t.OrdinalName(int(v)), t.OrdinalName(t.Range.Low), t.OrdinalName(t.Range.High))
+		}
+
+		return nil
+	}
+
+	sign := 1
+	if tree.IsUnary() && tree.Leaf.ID == token.Minus {
+		sign = -1
+		tree = tree.Right
+	}
+
+	if !tree.IsOperand() || tree.IsCall() || tree.HasSelectors() {
+		return nil
+	}
+
+	// Variables aren't constants, so they are skipped.
+	v, _, err := ordinalConst(scopeLookup(scope), tree.Leaf)
+	if err != nil {
+		return nil
+	}
+
+	if v *= sign; !t.Range.Contains(v) {
+		return fmt.Errorf("value %s is out of range %s..%s", t.OrdinalName(v), t.OrdinalName(t.Range.Low), t.OrdinalName(t.Range.High))
+	}
+
+	return nil
+}
+
 func (c TypeChecker) addFuncDecls(
 	ctx interface {
 		context.LoggerContext
@@ -235,72 +313,105 @@ func (c TypeChecker) addFuncDecls(
 	},
 	scope symbol.Scope,
 	program ast.Node,
-) {
-	for _, decl := range program.Query(ast.QueryTypeTop, ast.MarkerFuncDecl) {
-		name := decl.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
-		returnType := decl.Query(ast.QueryTypeOne, ast.MarkerReturnType)[0].Query(ast.QueryTypeOne, ast.MarkerType)[0].(*ast.Leaf)
+) []FuncResult {
+	var funcs []FuncResult
+	for _, decl := range ast.QueryBlock(program, ast.MarkerFuncDecl) {
+		header := decl.Query(ast.QueryTypeOne, ast.MarkerFuncHeader)[0]
 
-		returnTypeSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, returnType.Value)
-		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, returnType.Position(), err)
+		// Function header has syntax errors, so the function can't be checked.
+		if header.Has(ast.MarkerError) {
 			continue
 		}
 
-		if _, ok := returnTypeSymbol.(*symbol.Type); !ok {
-			ctx.AddError(context.ErrorSourceTypecheck, returnType.Position(), fmt.Errorf("symbol %s is not a type", returnType.Value))
-			continue
+		name := header.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
+
+		// Procedures don't have return type.
+		returnTypeSymbol := &symbol.Type{BuiltinType: symbol.BuiltinTypeVoid}
+		if returnTypes := header.Query(ast.QueryTypeOne, ast.MarkerReturnType); len(returnTypes) != 0 {
+			returnType := returnTypes[0].Query(ast.QueryTypeOne, ast.MarkerType)[0]
+
+			var ok bool
+			returnTypeSymbol, ok = c.resolveType(ctx, scope, returnType)
+			if !ok {
+				continue
+			}
+
+			if returnTypeSymbol.IsComposite() {
+				ctx.AddError(context.ErrorSourceTypecheck, returnType.Position(), fmt.Errorf("function %s can't return %s values", name.Value, returnTypeSymbol.BuiltinType))
+				continue
+			}
 		}
 
 		var params []symbol.Var
-		for _, param := range decl.Query(ast.QueryTypeTop, ast.MarkerParamGroupDecl) {
-			paramName := param.Query(ast.QueryTypeOne, ast.MarkerName)[0].(*ast.Leaf)
-			paramType := param.Query(ast.QueryTypeOne, ast.MarkerType)[0].(*ast.Leaf)
+		// Params of nested functions are located in the function block, so we skip it.
+		for _, param := range decl.Query(ast.QueryTypeTop, ast.MarkerParamGroupDecl, ast.MarkerFunctionBlock) {
+			if !param.Has(ast.MarkerParamGroupDecl) {
+				continue
+			}
 
-			paramTypeSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, paramType.Value)
-			if err != nil {
-				ctx.AddError(context.ErrorSourceTypecheck, paramType.Position(), err)
+			paramType := param.Query(ast.QueryTypeOne, ast.MarkerType)[0]
+
+			paramTypeSymbol, ok := c.resolveType(ctx, scope, paramType)
+			if !ok {
 				continue
 			}
 
-			if _, ok := paramTypeSymbol.(*symbol.Type); !ok {
-				ctx.AddError(context.ErrorSourceTypecheck, paramType.Position(), fmt.Errorf("symbol %s is not a type", paramType.Value))
+			// Arrays and records aren't copied, so they can be passed only by reference.
+			if paramTypeSymbol.IsComposite() && !param.Has(ast.MarkerByRef) {
+				ctx.AddError(context.ErrorSourceTypecheck, paramType.Position(), fmt.Errorf("%s params must be declared with var", paramTypeSymbol.BuiltinType))
 				continue
 			}
 
-			params = append(params, symbol.Var{
-				Token:       paramName.Token,
-				Type:        *paramTypeSymbol.(*symbol.Type),
-				Initialized: false,
-			})
+			for _, paramName := range param.Query(ast.QueryTypeTop, ast.MarkerName) {
+				params = append(params, symbol.Var{
+					Token: paramName.(*ast.Leaf).Token,
+					Type:  *paramTypeSymbol,
+					Param: true,
+					ByRef: param.Has(ast.MarkerByRef),
+				})
+			}
 		}
 
 		functionSymbol := &symbol.Func{
 			Token:      name.Token,
 			Params:     params,
-			ReturnType: *returnTypeSymbol.(*symbol.Type),
+			ReturnType: *returnTypeSymbol,
 		}
 
 		if err := scope.Add(functionSymbol); err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, name.Position(), err)
+			addError(ctx, name.Position(), err)
 			continue
 		}
 
 		var functionSymbols []symbol.Symbol
-		for _, param := range params {
-			functionSymbols = append(functionSymbols, &param)
+		for i := range params {
+			functionSymbols = append(functionSymbols, &params[i])
 		}
 
-		functionSymbols = append(functionSymbols, &symbol.Var{
-			Token:       functionSymbol.Token,
-			Type:        functionSymbol.ReturnType,
-			Initialized: false,
-		})
+		// Function result is assigned to the variable with the function name.
+		var result *symbol.Var
+		if !functionSymbol.IsProcedure() {
+			result = &symbol.Var{
+				Token: functionSymbol.Token,
+				Type:  functionSymbol.ReturnType,
+			}
+			functionSymbols = append(functionSymbols, result)
+		}
 
 		functionScope := scope.SubScope(functionSymbols)
 		functionBlock := decl.Query(ast.QueryTypeOne, ast.MarkerFunctionBlock)[0]
 
-		c.checkBlock(ctx, functionScope, functionBlock)
+		funcs = append(funcs, FuncResult{
+			Result: Result{
+				Node:  decl,
+				Scope: functionScope,
+				Funcs: c.checkBlock(ctx, functionScope, functionBlock, result),
+			},
+			Symbol: functionSymbol,
+		})
 	}
+
+	return funcs
 }
 
 func (c TypeChecker) addTypeDecls(
@@ -312,27 +423,28 @@ func (c TypeChecker) addTypeDecls(
 	scope symbol.Scope,
 	program ast.Node,
 ) {
-	for _, decl := range program.Query(ast.QueryTypeTop, ast.MarkerTypeDecl) {
+	for _, decl := range ast.QueryBlock(program, ast.MarkerTypeDecl) {
 		name := decl.Query(ast.QueryTypeOne, ast.MarkerName)[0].(*ast.Leaf)
-		typeName := decl.Query(ast.QueryTypeOne, ast.MarkerType)[0].(*ast.Leaf)
-
-		typeNameSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, typeName.Value)
-		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, typeName.Position(), err)
+		typeSymbol, ok := c.resolveType(ctx, scope, decl.Query(ast.QueryTypeOne, ast.MarkerType)[0])
+		if !ok {
 			continue
 		}
 
-		if _, ok := typeNameSymbol.(*symbol.Type); !ok {
-			ctx.AddError(context.ErrorSourceTypecheck, typeName.Position(), fmt.Errorf("symbol %s is not a type", typeName.Value))
-			continue
+		// Enum values are added before the enum is named, so they get the name of their type here.
+		if typeSymbol.Enum != nil && typeSymbol.Range == nil && typeSymbol.Enum.Name == "" {
+			typeSymbol.Enum.Name = name.Value
 		}
 
 		if err := scope.Add(&symbol.Type{
 			Token:       name.Token,
-			Alias:       typeNameSymbol.(*symbol.Type),
-			BuiltinType: typeNameSymbol.(*symbol.Type).BuiltinType,
+			Alias:       typeSymbol,
+			BuiltinType: typeSymbol.BuiltinType,
+			Array:       typeSymbol.Array,
+			Record:      typeSymbol.Record,
+			Enum:        typeSymbol.Enum,
+			Range:       typeSymbol.Range,
 		}); err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, name.Position(), err)
+			addError(ctx, name.Position(), err)
 			continue
 		}
 	}
@@ -347,40 +459,65 @@ func (c TypeChecker) addConstDecls(
 	scope symbol.Scope,
 	program ast.Node,
 ) {
-	for _, decl := range program.Query(ast.QueryTypeTop, ast.MarkerConstDecl) {
+	for _, decl := range ast.QueryBlock(program, ast.MarkerConstDecl) {
 		name := decl.Query(ast.QueryTypeOne, ast.MarkerName)[0].(*ast.Leaf)
-		valueNode := decl.Query(ast.QueryTypeOne, ast.MarkerValue)[0].(*ast.Leaf)
-
-		var typeName string
-		switch valueNode.ID {
-		case token.IntLiteral:
-			typeName = "integer"
-		case token.DoubleLiteral:
-			typeName = "real"
-		case token.BoolLiteral:
-			typeName = "boolean"
-		default:
-			ctx.AddError(context.ErrorSourceTypecheck, valueNode.Position(), fmt.Errorf("unsupported constant type %s", valueNode.ID))
+		valueNode := decl.Query(ast.QueryTypeOne, ast.MarkerValue)[0]
+
+		// Types of operands are checked the same way as in other expressions before the value is computed.
+		if _, err := c.resolver.Resolve(ctx, scope, valueNode); err != nil {
+			addError(ctx, valueNode.Position(), err)
 			continue
 		}
 
-		typeSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, typeName)
+		tree, err := ast.NewExpr(valueNode)
 		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, valueNode.Position(), err)
+			addError(ctx, valueNode.Position(), err)
 			continue
 		}
 
-		if _, ok := typeSymbol.(*symbol.Type); !ok {
-			ctx.AddError(context.ErrorSourceTypecheck, valueNode.Position(), fmt.Errorf("symbol %s is not a type", typeName))
+		value, err := evalConst(scope, tree)
+		if errors.Is(err, errIntLiteralRange) {
+			// Int literals out of range were already reported.
 			continue
 		}
 
+		if err != nil {
+			var constErr constError
+			if errors.As(err, &constErr) {
+				ctx.AddError(context.ErrorSourceTypecheck, constErr.Position, constErr.Err)
+				continue
+			}
+
+			addError(ctx, valueNode.Position(), err)
+			continue
+		}
+
+		// Enum constants keep the type of enum values, and other constants have builtin types.
+		typeSymbol := value.Type
+		if typeSymbol.Enum == nil {
+			typeName := constTypeNames[typeSymbol.BuiltinType]
+
+			s, err := ctx.Neutralizer().NeutralizeUserDefined(scope, typeName)
+			if err != nil {
+				addError(ctx, valueNode.Position(), err)
+				continue
+			}
+
+			t, ok := s.(*symbol.Type)
+			if !ok {
+				ctx.AddError(context.ErrorSourceTypecheck, valueNode.Position(), fmt.Errorf("symbol %s is not a type", typeName))
+				continue
+			}
+
+			typeSymbol = *t
+		}
+
 		if err := scope.Add(&symbol.Const{
 			Token:    name.Token,
-			Type:     *typeSymbol.(*symbol.Type),
-			RawValue: valueNode.Value,
+			Type:     typeSymbol,
+			RawValue: value.RawValue(),
 		}); err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, name.Position(), err)
+			addError(ctx, name.Position(), err)
 			continue
 		}
 	}
@@ -395,30 +532,305 @@ func (c TypeChecker) addVarDecls(
 	scope symbol.Scope,
 	program ast.Node,
 ) {
-	for _, decl := range program.Query(ast.QueryTypeTop, ast.MarkerVarDecl) {
-		typeName := decl.Query(ast.QueryTypeOne, ast.MarkerType)[0].(*ast.Leaf)
+	for _, decl := range ast.QueryBlock(program, ast.MarkerVarDecl) {
+		typeSymbol, ok := c.resolveType(ctx, scope, decl.Query(ast.QueryTypeOne, ast.MarkerType)[0])
+		if !ok {
+			continue
+		}
 
-		typeNameSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, typeName.Value)
+		for _, name := range decl.Query(ast.QueryTypeTop, ast.MarkerName) {
+			name := name.(*ast.Leaf)
+
+			if err := scope.Add(&symbol.Var{
+				Token: name.Token,
+				Type:  *typeSymbol,
+			}); err != nil {
+				addError(ctx, name.Position(), err)
+				continue
+			}
+		}
+	}
+}
+
+// resolveType returns the type described by the type node (a type name, an array, record, enum or subrange type).
+// Errors are added to the context, and false is returned if the type can't be resolved.
+func (c TypeChecker) resolveType(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+) (*symbol.Type, bool) {
+	switch {
+	case node.Has(ast.MarkerRecordType):
+		return c.resolveRecordType(ctx, scope, node)
+	case node.Has(ast.MarkerEnumType):
+		return c.resolveEnumType(ctx, scope, node)
+	case node.Has(ast.MarkerSubrangeType):
+		return c.resolveSubrangeType(ctx, scope, node)
+	}
+
+	if !node.Has(ast.MarkerArrayType) {
+		typeName := node.(*ast.Leaf)
+
+		typeSymbol, err := ctx.Neutralizer().NeutralizeUserDefined(scope, typeName.Value)
 		if err != nil {
-			ctx.AddError(context.ErrorSourceTypecheck, typeName.Position(), err)
-			continue
+			addError(ctx, typeName.Position(), err)
+			return nil, false
 		}
 
-		if _, ok := typeNameSymbol.(*symbol.Type); !ok {
+		if _, ok := typeSymbol.(*symbol.Type); !ok {
 			ctx.AddError(context.ErrorSourceTypecheck, typeName.Position(), fmt.Errorf("symbol %s is not a type", typeName.Value))
+			return nil, false
+		}
+
+		return typeSymbol.(*symbol.Type), true
+	}
+
+	// Element type is the last item of the array type, and it may have index ranges of its own.
+	items := node.(*ast.Branch).Items
+
+	elemType, ok := c.resolveType(ctx, scope, items[len(items)-1])
+	if !ok {
+		return nil, false
+	}
+
+	var ranges []ast.Node
+	for _, item := range items[:len(items)-1] {
+		ranges = append(ranges, item.Query(ast.QueryTypeTop, ast.MarkerIndexRange)...)
+	}
+
+	// The last range is the innermost array.
+	t := elemType
+	for i := len(ranges) - 1; i >= 0; i-- {
+		bounds := ranges[i].Query(ast.QueryTypeTop, ast.MarkerValue)
+
+		low, err := c.constInt(ctx, scope, bounds[0])
+		if err != nil {
+			addError(ctx, bounds[0].Position(), err)
+			return nil, false
+		}
+
+		high, err := c.constInt(ctx, scope, bounds[1])
+		if err != nil {
+			addError(ctx, bounds[1].Position(), err)
+			return nil, false
+		}
+
+		if low > high {
+			ctx.AddError(context.ErrorSourceTypecheck, ranges[i].Position(), fmt.Errorf("array range %d..%d is empty", low, high))
+			return nil, false
+		}
+
+		t = &symbol.Type{BuiltinType: symbol.BuiltinTypeArray, Array: &symbol.Array{Low: low, High: high, Elem: *t}}
+		if elems(*t) > maxArrayElems {
+			ctx.AddError(context.ErrorSourceTypecheck, node.Position(), fmt.Errorf("array has more than %d elements", maxArrayElems))
+			return nil, false
+		}
+	}
+
+	return t, true
+}
+
+// resolveRecordType returns the record type with fields in order of declaration.
+func (c TypeChecker) resolveRecordType(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+) (*symbol.Type, bool) {
+	record := &symbol.Record{}
+
+	ok := true
+	for _, decl := range node.Query(ast.QueryTypeTop, ast.MarkerFieldDecl) {
+		// Field type is the last item of the declaration, and it may have fields of its own.
+		items := decl.(*ast.Branch).Items
+
+		fieldType, resolved := c.resolveType(ctx, scope, items[len(items)-1])
+		if !resolved {
+			ok = false
 			continue
 		}
 
-		for _, name := range decl.Query(ast.QueryTypeTop, ast.MarkerName) {
+		var names []ast.Node
+		for _, item := range items[:len(items)-1] {
+			names = append(names, item.Query(ast.QueryTypeTop, ast.MarkerField)...)
+		}
+
+		for _, name := range names {
 			name := name.(*ast.Leaf)
 
-			if err := scope.Add(&symbol.Var{
-				Token: name.Token,
-				Type:  *typeNameSymbol.(*symbol.Type),
-			}); err != nil {
-				ctx.AddError(context.ErrorSourceTypecheck, name.Position(), err)
+			if _, found := record.Field(name.Value); found {
+				ctx.AddError(context.ErrorSourceTypecheck, name.Position(), fmt.Errorf("field %s was already declared in this record", name.Value))
+				ok = false
 				continue
 			}
+
+			record.Fields = append(record.Fields, symbol.Field{Name: name.Value, Type: *fieldType})
 		}
 	}
+
+	if !ok {
+		return nil, false
+	}
+
+	t := &symbol.Type{BuiltinType: symbol.BuiltinTypeRecord, Record: record}
+	if elems(*t) > maxArrayElems {
+		ctx.AddError(context.ErrorSourceTypecheck, node.Position(), fmt.Errorf("record has more than %d elements", maxArrayElems))
+		return nil, false
+	}
+
+	return t, true
+}
+
+// resolveEnumType returns the enum type and adds its values to the scope as constants.
+// Constants store ordinal values, so they are ints in generated code.
+func (c TypeChecker) resolveEnumType(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+) (*symbol.Type, bool) {
+	enum := &symbol.Enum{}
+	t := &symbol.Type{BuiltinType: symbol.BuiltinTypeEnum, Enum: enum}
+
+	for _, name := range node.Query(ast.QueryTypeTop, ast.MarkerEnumValue) {
+		name := name.(*ast.Leaf)
+
+		// Value is added even if its name is already used, so ordinal values of the next values are the same.
+		enum.Values = append(enum.Values, name.Value)
+
+		if err := scope.Add(&symbol.Const{
+			Token:    name.Token,
+			Type:     *t,
+			RawValue: strconv.Itoa(len(enum.Values) - 1),
+		}); err != nil {
+			addError(ctx, name.Position(), err)
+			continue
+		}
+	}
+
+	return t, true
+}
+
+// resolveSubrangeType returns the subrange type with bounds converted to ordinal values of its base type.
+func (c TypeChecker) resolveSubrangeType(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+) (*symbol.Type, bool) {
+	lookup := func(name string) (symbol.Symbol, error) {
+		return ctx.Neutralizer().NeutralizeUserDefined(scope, name)
+	}
+
+	bounds := node.Query(ast.QueryTypeTop, ast.MarkerValue)
+
+	// Int literals out of range were already reported.
+	low, lowType, err := ordinalConst(lookup, bounds[0])
+	if err != nil {
+		if !errors.Is(err, errIntLiteralRange) {
+			addError(ctx, bounds[0].Position(), err)
+		}
+
+		return nil, false
+	}
+
+	high, highType, err := ordinalConst(lookup, bounds[1])
+	if err != nil {
+		if !errors.Is(err, errIntLiteralRange) {
+			addError(ctx, bounds[1].Position(), err)
+		}
+
+		return nil, false
+	}
+
+	if lowType.BuiltinType != highType.BuiltinType || lowType.Enum != highType.Enum {
+		ctx.AddError(context.ErrorSourceTypecheck, node.Position(), fmt.Errorf("bounds of subrange have different types %s and %s", lowType.Name(), highType.Name()))
+		return nil, false
+	}
+
+	t := &symbol.Type{BuiltinType: lowType.BuiltinType, Enum: lowType.Enum, Range: &symbol.Range{Low: low, High: high}}
+	if low > high {
+		ctx.AddError(context.ErrorSourceTypecheck, node.Position(), fmt.Errorf("subrange %s is empty", t.Name()))
+		return nil, false
+	}
+
+	return t, true
+}
+
+// constInt returns a value of the array bound (an int literal or an int constant with an optional sign).
+func (c TypeChecker) constInt(
+	ctx interface {
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+) (int, error) {
+	sign := 1
+	if branch, ok := node.(*ast.Branch); ok {
+		if branch.Items[0].(*ast.Leaf).ID == token.Minus {
+			sign = -1
+		}
+
+		node = branch.Items[len(branch.Items)-1]
+	}
+
+	leaf := node.(*ast.Leaf)
+
+	raw := leaf.Value
+
+	// Minus is folded into the int literal, so the smallest int can be used.
+	if leaf.ID == token.IntLiteral && sign == -1 {
+		raw, sign = "-"+raw, 1
+	}
+
+	if leaf.ID == token.UserDefined {
+		s, err := ctx.Neutralizer().NeutralizeUserDefined(scope, leaf.Value)
+		if err != nil {
+			return 0, atName(leaf, err)
+		}
+
+		constant, ok := s.(*symbol.Const)
+		if !ok || constant.Type.BuiltinType != symbol.BuiltinTypeInt {
+			return 0, fmt.Errorf("array bound %s must be an int constant", leaf.Value)
+		}
+
+		raw = constant.RawValue
+	}
+
+	v, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array bound %s: %w", raw, err)
+	}
+
+	return sign * int(v), nil
+}
+
+// elems returns a number of values stored in the array or the record including values of nested arrays and records.
+func elems(t symbol.Type) int {
+	switch {
+	case t.Array != nil:
+		return t.Array.Len() * elems(t.Array.Elem)
+	case t.Record != nil:
+		n := 0
+		for _, f := range t.Record.Fields {
+			n += elems(f.Type)
+		}
+
+		return n
+	default:
+		return 1
+	}
 }
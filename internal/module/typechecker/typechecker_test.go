@@ -0,0 +1,368 @@
+package typechecker_test
+
+import (
+	stdcontext "context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
+	"github.com/iskorotkov/compiler/internal/module/syntax_analyzer"
+	"github.com/iskorotkov/compiler/internal/module/typechecker"
+)
+
+func TestTypeChecker_ConstErrors(t *testing.T) {
+	t.Parallel()
+
+	type Test struct {
+		name   string
+		input  string
+		errors []string
+	}
+
+	tests := []Test{
+		{
+			name: "overflow and division by zero",
+			input: `program p;
+const
+  BIG = 65536 * 65536;
+  Z = 0;
+  BAD = 10 div Z;
+  F = 1.5 / (Z * 1.0);
+  MIN = -2147483647 - 1;
+  NEG = -MIN;
+begin
+end.`,
+			errors: []string{
+				`TYPECHECK 3:9-22: integer overflow in constant expression`,
+				`TYPECHECK 5:9-17: division by zero in constant expression`,
+				`TYPECHECK 6:7-21: division by zero in constant expression`,
+				`TYPECHECK 8:9-13: integer overflow in constant expression`,
+			},
+		},
+		{
+			name: "non-constant operands",
+			input: `program p;
+const
+  A = 1;
+  B = A + X;
+  D = 'a' + A;
+var
+  v: integer;
+begin
+end.`,
+			errors: []string{
+				`TYPECHECK 4:11: replace X with A`,
+				`TYPECHECK 5:7-14: left operand has incompatible type char`,
+			},
+		},
+		{
+			name: "int literals out of range",
+			input: `program p;
+const
+  BIG = 2147483648;
+  MAX = 2147483647;
+type
+  r = 0..3000000000;
+var
+  x: integer;
+begin
+  x := 2147483648;
+  case x of
+    9999999999: writeln(1);
+  end;
+  writeln(-2147483649);
+end.`,
+			errors: []string{
+				`TYPECHECK 3:9-19: integer literal is out of range: 2147483648 is greater than 2147483647`,
+				`TYPECHECK 6:10-20: integer literal is out of range: 3000000000 is greater than 2147483647`,
+				`TYPECHECK 10:8-18: integer literal is out of range: 2147483648 is greater than 2147483647`,
+				`TYPECHECK 12:5-15: integer literal is out of range: 9999999999 is greater than 2147483647`,
+				`TYPECHECK 14:12-22: integer literal is out of range: -2147483649 is less than -2147483648`,
+			},
+		},
+		{
+			name: "smallest int literal",
+			input: `program p;
+const
+  MIN = -2147483648;
+  NEXT = -2147483648 + 1;
+type
+  r = -2147483648..0;
+var
+  x: integer;
+  d: r;
+begin
+  x := -2147483648;
+  d := -2147483648;
+  case x of
+    -2147483648: writeln(MIN);
+  end;
+  writeln(NEXT);
+  writeln(d);
+end.`,
+		},
+		{
+			name: "smallest int literal out of subrange",
+			input: `program p;
+type
+  r = 0..10;
+var
+  d: r;
+begin
+  d := -2147483648;
+  writeln(d);
+end.`,
+			errors: []string{
+				`TYPECHECK 7:8-19: value -2147483648 is out of range 0..10`,
+			},
+		},
+		{
+			name: "ordinal functions out of range",
+			input: `program p;
+const
+  LAST = succ(2147483646);
+  OVER = succ(LAST);
+  T = succ(false);
+type
+  color = (red, green, blue);
+var
+  paint: color;
+begin
+  paint := pred(red);
+  paint := succ(blue);
+  paint := pred(succ(red));
+  writeln(pred(T));
+  writeln(succ(T));
+end.`,
+			errors: []string{
+				`TYPECHECK 4:10-20: integer overflow in constant expression`,
+				`TYPECHECK 11:3-21: pred(red) is out of range red..blue`,
+				`TYPECHECK 12:3-22: succ(blue) is out of range red..blue`,
+				`TYPECHECK 15:3-19: succ(true) is out of range false..true`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := check(test.input)
+			assert.Equal(t, test.errors, messages(ctx.Errors()))
+		})
+	}
+}
+
+func TestTypeChecker_MisspelledNames(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+type
+  point = record
+    xy: integer;
+  end;
+var
+  q: point;
+  xx: integer;
+begin
+  xx := 1;
+  q.xy := xy;
+  writeln(q.xy);
+end.`
+
+	// Misspelled names are reported at the name, so the fix doesn't replace the field with the same name.
+	ctx := check(input)
+	assert.Equal(t, []string{
+		`TYPECHECK 11:11-13: replace xy with xx`,
+	}, messages(ctx.Errors()))
+}
+
+func TestTypeChecker_Initialization(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+var
+  a, b, k, d, e, f, g: integer;
+function half(n: integer): integer;
+begin
+  if n > 0 then
+    half := n div 2;
+end
+function sign(n: integer): integer;
+begin
+  if n > 0 then
+    sign := 1
+  else
+    sign := -1;
+end
+procedure init(var x: integer);
+begin
+  x := 1;
+end
+procedure reset;
+begin
+  g := 0;
+end
+begin
+  if a > 0 then
+    b := 1
+  else
+    b := 2;
+  writeln(b);
+  while b > 0 do
+    k := b;
+  writeln(k);
+  repeat
+    d := 1
+  until d > 0;
+  init(e);
+  writeln(d + e);
+  case b of
+    1: f := 1;
+  else
+    f := 2;
+  end;
+  writeln(f);
+  reset;
+  writeln(g);
+end.`
+
+	ctx := check(input)
+
+	assert.Equal(t, []string{
+		`TYPECHECK 4:10-14: function half may return without assigning its result`,
+	}, messages(ctx.Errors()))
+	assert.Equal(t, []string{
+		`warning: TYPECHECK 4:10-14: function half is declared but never used`,
+		`warning: TYPECHECK 9:10-14: function sign is declared but never used`,
+		`warning: TYPECHECK 25:6: variable a may be used before it's assigned`,
+		`warning: TYPECHECK 32:11: variable k may be used before it's assigned`,
+	}, messages(ctx.Warnings()))
+}
+
+func TestTypeChecker_InitializationSyntaxErrors(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+var
+  a, b: integer;
+function f: integer;
+begin
+  f := a +;
+end
+begin
+  writeln(b);
+  b := f();
+  a := b +;
+end.`
+
+	// Assignments with syntax errors aren't parsed, so variables aren't reported as unassigned.
+	ctx := check(input)
+	assert.Equal(t, []string{
+		`SYNTAX 6:11: unexpected token ";"`,
+		`SYNTAX 11:11: unexpected token ";"`,
+	}, messages(ctx.Diagnostics()))
+}
+
+func TestTypeChecker_EmptyBodies(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+var
+  x, i, a, b: integer;
+begin
+  x := 1;
+  if x > 9 then ;
+  if x > 9 then else a := 2;
+  writeln(a);
+  if x > 9 then b := 1 else ;
+  writeln(b);
+  for i := 0 to 9 do ;
+  while x > 9 do ;
+  repeat until x > 0;
+  writeln(i);
+end.`
+
+	ctx := check(input)
+
+	assert.Empty(t, ctx.Errors())
+	assert.Equal(t, []string{
+		`warning: TYPECHECK 8:11: variable a may be used before it's assigned`,
+		`warning: TYPECHECK 10:11: variable b may be used before it's assigned`,
+	}, messages(ctx.Diagnostics()))
+}
+
+func TestTypeChecker_Usage(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+const
+  LIMIT = 10;
+  UNUSED = 1;
+type
+  color = (red, green, blue);
+  digit = 0..9;
+  row = array[1..3] of integer;
+var
+  paint: color;
+  total, written, idle: integer;
+  r: row;
+function twice(n, unused: integer): integer;
+var
+  local: integer;
+begin
+  local := n;
+  twice := n * 2;
+end
+procedure fill(var x: row);
+begin
+  x[1] := LIMIT;
+end
+begin
+  paint := red;
+  writeln(ord(paint));
+  written := 1;
+  fill(r);
+  total := twice(r[1], 0);
+  writeln(total);
+end.`
+
+	ctx := check(input)
+
+	assert.Empty(t, ctx.Errors())
+	assert.Equal(t, []string{
+		`warning: TYPECHECK 4:3-9: constant UNUSED is declared but never used`,
+		`warning: TYPECHECK 7:3-8: type digit is declared but never used`,
+		`warning: TYPECHECK 11:10-17: variable written is assigned but never used`,
+		`warning: TYPECHECK 11:19-23: variable idle is declared but never used`,
+		`warning: TYPECHECK 13:19-25: parameter unused is never used`,
+		`warning: TYPECHECK 15:3-8: variable local is assigned but never used`,
+	}, messages(ctx.Diagnostics()))
+}
+
+// check runs the type checker on the program and returns the context with reported diagnostics.
+func check(input string) context.FullContext {
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	literals := reader.New(0).Read(ctx, strings.NewReader(input))
+	tokens := scanner.New(0).Scan(ctx, literals)
+	programs := syntax_analyzer.New(0).Analyze(ctx, tokens)
+	<-typechecker.NewTypeChecker(0).Check(ctx, programs)
+
+	return ctx
+}
+
+// messages returns texts of diagnostics in order of reporting.
+func messages(diagnostics []context.Error) []string {
+	var res []string
+	for _, d := range diagnostics {
+		res = append(res, d.Error())
+	}
+
+	return res
+}
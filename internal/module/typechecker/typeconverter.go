@@ -27,6 +27,20 @@ func NewTypeConverter() TypeConverter {
 				symbol.BuiltinTypeUnknown: symbol.BuiltinTypeBool,
 				symbol.BuiltinTypeBool:    symbol.BuiltinTypeBool,
 			},
+			symbol.BuiltinTypeString: {
+				symbol.BuiltinTypeUnknown: symbol.BuiltinTypeString,
+				symbol.BuiltinTypeString:  symbol.BuiltinTypeString,
+			},
+			symbol.BuiltinTypeEnum: {
+				symbol.BuiltinTypeUnknown: symbol.BuiltinTypeEnum,
+				symbol.BuiltinTypeEnum:    symbol.BuiltinTypeEnum,
+			},
+			// Char is a string of length 1, so it can be used where string is expected.
+			symbol.BuiltinTypeChar: {
+				symbol.BuiltinTypeUnknown: symbol.BuiltinTypeChar,
+				symbol.BuiltinTypeChar:    symbol.BuiltinTypeChar,
+				symbol.BuiltinTypeString:  symbol.BuiltinTypeString,
+			},
 		},
 	}
 }
@@ -2,9 +2,11 @@ package typechecker
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/iskorotkov/compiler/internal/context"
 	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
 	"github.com/iskorotkov/compiler/internal/data/symbol"
 	"github.com/iskorotkov/compiler/internal/data/token"
 )
@@ -16,7 +18,9 @@ var opGroups = []opGroup{
 			token.Or:  true,
 			token.Xor: true,
 		},
-		expectedType:  symbol.BuiltinTypeBool,
+		operandTypes: map[symbol.BuiltinType]bool{
+			symbol.BuiltinTypeBool: true,
+		},
 		resultingType: symbol.BuiltinTypeBool,
 	},
 	{
@@ -28,17 +32,47 @@ var opGroups = []opGroup{
 			token.Gt:  true,
 			token.Gte: true,
 		},
-		expectedType:  symbol.BuiltinTypeUnknown,
+		// Strings are stored as pointers, so they can't be compared by value.
+		operandTypes: map[symbol.BuiltinType]bool{
+			symbol.BuiltinTypeInt:    true,
+			symbol.BuiltinTypeDouble: true,
+			symbol.BuiltinTypeBool:   true,
+			symbol.BuiltinTypeChar:   true,
+			symbol.BuiltinTypeEnum:   true,
+		},
 		resultingType: symbol.BuiltinTypeBool,
 	},
+	{
+		tokens: map[token.ID]bool{
+			token.Plus:     true,
+			token.Minus:    true,
+			token.Multiply: true,
+			token.Divide:   true,
+		},
+		operandTypes: map[symbol.BuiltinType]bool{
+			symbol.BuiltinTypeInt:    true,
+			symbol.BuiltinTypeDouble: true,
+		},
+		resultingType: symbol.BuiltinTypeUnknown,
+	},
+	{
+		tokens: map[token.ID]bool{
+			token.Div: true,
+			token.Mod: true,
+		},
+		operandTypes: map[symbol.BuiltinType]bool{
+			symbol.BuiltinTypeInt: true,
+		},
+		resultingType: symbol.BuiltinTypeInt,
+	},
 }
 
 type opGroup struct {
 	// Which tokens are in the group.
 	tokens map[token.ID]bool
-	// Expected type of the left and right operands.
-	expectedType symbol.BuiltinType
-	// Resulting type of the operation.
+	// Allowed types of the left and right operands (nil means any type).
+	operandTypes map[symbol.BuiltinType]bool
+	// Resulting type of the operation (unknown means common type of operands).
 	resultingType symbol.BuiltinType
 }
 
@@ -59,103 +93,333 @@ func (r TypeResolver) Resolve(
 	scope symbol.Scope,
 	expr ast.Node,
 ) (symbol.BuiltinType, error) {
-	leafs := linearizeExpressionTokens(expr)
-	return r.resolveLinearExpr(ctx, scope, leafs)
+	tree, err := ast.NewExpr(expr)
+	if err != nil {
+		return symbol.BuiltinTypeUnknown, err
+	}
+
+	return r.resolveTree(ctx, scope, tree)
 }
 
-func (r TypeResolver) resolveLinearExpr(
+// ResolveCall checks arguments of a function call and returns the function return type.
+func (r TypeResolver) ResolveCall(
 	ctx interface {
 		context.LoggerContext
 		context.ErrorsContext
 		context.NeutralizerContext
 	},
 	scope symbol.Scope,
-	leafs []*ast.Leaf,
+	call ast.Node,
 ) (symbol.BuiltinType, error) {
-	for _, group := range opGroups {
-		for i, leaf := range leafs {
-			if leaf.ID == token.Not {
-				t, err := getLeafType(ctx, scope, leafs[i+1])
-				if err != nil {
-					return symbol.BuiltinTypeUnknown, err
-				}
+	name := call.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
 
-				if t != symbol.BuiltinTypeBool {
-					return symbol.BuiltinTypeUnknown, fmt.Errorf("unsupported type for not: %s", t)
+	sym, ok := scope.LookupFunc(name.Value)
+	if !ok {
+		if _, err := ctx.Neutralizer().NeutralizeUserDefined(scope, name.Value); err != nil {
+			return symbol.BuiltinTypeUnknown, atName(name, err)
+		}
+
+		return symbol.BuiltinTypeUnknown, fmt.Errorf("symbol %s is not a function", name.Value)
+	}
+
+	args := ast.CallArgs(call)
+	if len(args) != len(sym.Params) {
+		return symbol.BuiltinTypeUnknown, fmt.Errorf("wrong number of arguments for function %s", name.Value)
+	}
+
+	// Result type of succ and pred is the type of the argument, so they are checked separately.
+	if IsOrdinalBuiltin(sym) {
+		argType, err := r.Resolve(ctx, scope, args[0])
+		if err != nil {
+			return symbol.BuiltinTypeUnknown, err
+		}
+
+		if !isOrdinal(argType) {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("function %s requires an ordinal argument, got %s", name.Value, argType)
+		}
+
+		if name.Value == "ord" {
+			return symbol.BuiltinTypeInt, nil
+		}
+
+		// Values of constant arguments are known, so pred of the first value and succ of the last value are reported.
+		if tree, err := ast.NewExpr(args[0]); err == nil {
+			if v, err := evalConst(scope, tree); err == nil {
+				if _, err := evalOrdinalFunc(name.Value, v); err != nil {
+					return symbol.BuiltinTypeUnknown, err
 				}
 			}
+		}
 
-			if !group.tokens[leaf.ID] {
-				continue
-			}
+		return argType, nil
+	}
 
-			leftType, err := r.resolveLinearExpr(ctx, scope, leafs[:i])
-			if err != nil {
-				return leftType, err
+	for i, arg := range args {
+		if sym.Params[i].ByRef {
+			if err := r.checkRefArg(ctx, scope, arg, sym.Params[i]); err != nil {
+				return symbol.BuiltinTypeUnknown, fmt.Errorf("argument %d for function %s: %w", i+1, name.Value, err)
 			}
 
-			rightType, err := r.resolveLinearExpr(ctx, scope, leafs[i+1:])
-			if err != nil {
-				return rightType, err
-			}
+			continue
+		}
 
-			// Check if left operand has type compatible with given operation.
-			if _, ok := r.converter.IsAssignable(leftType, group.expectedType); !ok {
-				return symbol.BuiltinTypeUnknown, fmt.Errorf("left operand has incompatible type %s", leftType)
-			}
+		argType, err := r.Resolve(ctx, scope, arg)
+		if err != nil {
+			return symbol.BuiltinTypeUnknown, err
+		}
 
-			// Check if right operand has type compatible with given operation.
-			if _, ok := r.converter.IsAssignable(leftType, group.expectedType); !ok {
-				return symbol.BuiltinTypeUnknown, fmt.Errorf("right operand has incompatible type %s", rightType)
-			}
+		if _, ok := r.converter.IsAssignable(argType, sym.Params[i].Type.BuiltinType); !ok {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("wrong type of argument %d for function %s", i+1, name.Value)
+		}
+	}
+
+	return sym.ReturnType.BuiltinType, nil
+}
+
+// IsOrdinalBuiltin checks whether the function is one of builtin ord, succ and pred functions.
+// Builtin functions don't have positions, so user-defined functions with the same names aren't confused with them.
+func IsOrdinalBuiltin(f *symbol.Func) bool {
+	switch f.Value {
+	case "ord", "succ", "pred":
+		return f.Position == literal.Position{}
+	default:
+		return false
+	}
+}
+
+// checkRefArg checks that the argument passed by reference is a variable of the same type as the param.
+func (r TypeResolver) checkRefArg(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	arg ast.Node,
+	param symbol.Var,
+) error {
+	tree, err := ast.NewExpr(arg)
+	if err != nil {
+		return err
+	}
+
+	if !tree.IsOperand() || tree.IsCall() || tree.Leaf.ID != token.UserDefined {
+		return fmt.Errorf("var param %s requires a variable", param.Value)
+	}
+
+	_, t, err := r.ResolveVariable(ctx, scope, arg)
+	if err != nil {
+		return err
+	}
+
+	// Value can't be converted in place, so types must be the same.
+	if !t.Same(param.Type) {
+		return fmt.Errorf("var param %s has type %s, got %s", param.Value, param.Type.Name(), t.Name())
+	}
+
+	return nil
+}
+
+// ResolveVariable returns the variable and the type of the value it refers to.
+// For variables with selectors it's the type of the selected array element or record field.
+func (r TypeResolver) ResolveVariable(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+) (*symbol.Var, symbol.Type, error) {
+	tree, err := ast.NewExpr(node)
+	if err != nil {
+		return nil, symbol.Type{}, err
+	}
+
+	if !tree.IsOperand() || tree.IsCall() || tree.Leaf.ID != token.UserDefined {
+		return nil, symbol.Type{}, fmt.Errorf("expected variable, got %v", tree)
+	}
 
-			// Check if operands have compatible types (can be cast one to another).
-			if _, ok := r.converter.IsAssignable(rightType, leftType); !ok {
-				return symbol.BuiltinTypeUnknown, fmt.Errorf("operands have incompatible types %s and %s", leftType, rightType)
+	s, err := ctx.Neutralizer().NeutralizeUserDefined(scope, tree.Leaf.Value)
+	if err != nil {
+		return nil, symbol.Type{}, atName(tree.Leaf, err)
+	}
+
+	v, ok := s.(*symbol.Var)
+	if !ok {
+		return nil, symbol.Type{}, fmt.Errorf("symbol is not a variable: %s", tree.Leaf.Value)
+	}
+
+	t := v.Type
+	for _, selector := range ast.Selectors(tree.Variable) {
+		if selector.Has(ast.MarkerField) {
+			name := selector.(*ast.Leaf)
+			if t.Record == nil {
+				return nil, symbol.Type{}, fmt.Errorf("can't select field %s of %s value", name.Value, t.Name())
 			}
 
-			if group.resultingType != symbol.BuiltinTypeUnknown {
-				return group.resultingType, nil
+			// Misspelt field is reported, and the closest field is used instead, so the rest of the variable is still checked.
+			f, err := ctx.Neutralizer().NeutralizeField(*t.Record, name.Value)
+			if err != nil {
+				if f.Name == "" {
+					return nil, symbol.Type{}, err
+				}
+
+				addError(ctx, name.Position(), err)
 			}
 
-			return leftType, nil
+			t = f.Type
+			continue
+		}
+
+		if t.Array == nil {
+			return nil, symbol.Type{}, fmt.Errorf("too many indexes for %s", tree.Leaf.Value)
 		}
-	}
 
-	var valuesOnly []*ast.Leaf
-	for _, item := range leafs {
-		switch item.ID {
-		case token.UserDefined, token.IntLiteral, token.DoubleLiteral, token.BoolLiteral:
-			valuesOnly = append(valuesOnly, item)
+		if err := r.checkIndex(ctx, scope, selector, *t.Array); err != nil {
+			return nil, symbol.Type{}, err
 		}
+
+		t = t.Array.Elem
 	}
 
-	return r.subExpressionType(ctx, scope, valuesOnly)
+	return v, t, nil
 }
 
-func (r TypeResolver) subExpressionType(ctx interface {
-	context.ErrorsContext
-	context.NeutralizerContext
-},
+// checkIndex checks that the index has int type.
+// Constant indexes are also checked to be within array bounds.
+func (r TypeResolver) checkIndex(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
 	scope symbol.Scope,
-	linearized []*ast.Leaf,
+	index ast.Node,
+	array symbol.Array,
+) error {
+	indexType, err := r.Resolve(ctx, scope, index)
+	if err != nil {
+		return err
+	}
+
+	if indexType != symbol.BuiltinTypeInt {
+		return fmt.Errorf("array index must have int type, got %s", indexType)
+	}
+
+	tree, err := ast.NewExpr(index)
+	if err != nil {
+		return err
+	}
+
+	if !tree.IsOperand() || tree.Leaf.ID != token.IntLiteral {
+		return nil
+	}
+
+	if v, err := strconv.Atoi(tree.Leaf.Value); err == nil && (v < array.Low || v > array.High) {
+		return fmt.Errorf("index %d is out of bounds %d..%d", v, array.Low, array.High)
+	}
+
+	return nil
+}
+
+func (r TypeResolver) resolveTree(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	tree *ast.Expr,
 ) (symbol.BuiltinType, error) {
-	currentType := symbol.BuiltinTypeUnknown
-	for _, leaf := range linearized {
-		leafType, err := getLeafType(ctx, scope, leaf)
+	switch {
+	case tree.IsCall():
+		returnType, err := r.ResolveCall(ctx, scope, tree.Call)
+		if err != nil {
+			return symbol.BuiltinTypeUnknown, err
+		}
+
+		if returnType == symbol.BuiltinTypeVoid {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("procedure %s can't be used in an expression", tree.Leaf.Value)
+		}
+
+		return returnType, nil
+	case tree.HasSelectors():
+		_, t, err := r.ResolveVariable(ctx, scope, tree.Variable)
+		if err != nil {
+			return symbol.BuiltinTypeUnknown, err
+		}
+
+		if t.IsComposite() {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("%s %v can't be used in an expression", t.BuiltinType, tree)
+		}
+
+		return t.BuiltinType, nil
+	case tree.IsOperand():
+		return getLeafType(ctx, scope, tree.Leaf)
+	case tree.IsUnary():
+		operandType, err := r.resolveTree(ctx, scope, tree.Right)
 		if err != nil {
 			return symbol.BuiltinTypeUnknown, err
 		}
 
-		newType, ok := r.converter.IsAssignable(leafType, currentType)
+		switch tree.Leaf.ID {
+		case token.Not:
+			if operandType != symbol.BuiltinTypeBool {
+				return symbol.BuiltinTypeUnknown, fmt.Errorf("unsupported type for not: %s", operandType)
+			}
+		default:
+			if operandType != symbol.BuiltinTypeInt && operandType != symbol.BuiltinTypeDouble {
+				return symbol.BuiltinTypeUnknown, fmt.Errorf("unsupported type for sign: %s", operandType)
+			}
+		}
+
+		return operandType, nil
+	}
+
+	leftType, err := r.resolveTree(ctx, scope, tree.Left)
+	if err != nil {
+		return leftType, err
+	}
+
+	rightType, err := r.resolveTree(ctx, scope, tree.Right)
+	if err != nil {
+		return rightType, err
+	}
+
+	for _, group := range opGroups {
+		if !group.tokens[tree.Leaf.ID] {
+			continue
+		}
+
+		// Check if left operand has type compatible with given operation.
+		if group.operandTypes != nil && !group.operandTypes[leftType] {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("left operand has incompatible type %s", leftType)
+		}
+
+		// Check if right operand has type compatible with given operation.
+		if group.operandTypes != nil && !group.operandTypes[rightType] {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("right operand has incompatible type %s", rightType)
+		}
+
+		// Check if operands have compatible types (can be cast one to another).
+		commonType, ok := r.converter.IsAssignable(rightType, leftType)
 		if !ok {
-			return symbol.BuiltinTypeUnknown, fmt.Errorf("can't cast type %s to %s", leafType, currentType)
+			commonType, ok = r.converter.IsAssignable(leftType, rightType)
+		}
+
+		if !ok {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("operands have incompatible types %s and %s", leftType, rightType)
+		}
+
+		if group.resultingType != symbol.BuiltinTypeUnknown {
+			return group.resultingType, nil
 		}
 
-		currentType = newType
+		return commonType, nil
 	}
 
-	return currentType, nil
+	return symbol.BuiltinTypeUnknown, fmt.Errorf("unsupported operation %s", tree.Leaf.Value)
 }
 
 func getLeafType(
@@ -173,14 +437,24 @@ func getLeafType(
 		return symbol.BuiltinTypeDouble, nil
 	case token.BoolLiteral:
 		return symbol.BuiltinTypeBool, nil
+	case token.StringLiteral:
+		return StringLiteralType(leaf.Value), nil
 	case token.UserDefined:
 		s, err := ctx.Neutralizer().NeutralizeUserDefined(scope, leaf.Value)
 		if err != nil {
-			return symbol.BuiltinTypeUnknown, err
+			return symbol.BuiltinTypeUnknown, atName(leaf, err)
+		}
+
+		if f, ok := s.(*symbol.Func); ok && f.IsProcedure() {
+			return symbol.BuiltinTypeUnknown, fmt.Errorf("procedure %s can't be used in an expression", leaf.Value)
 		}
 
 		switch s := s.(type) {
 		case *symbol.Var:
+			if s.Type.IsComposite() {
+				return symbol.BuiltinTypeUnknown, fmt.Errorf("%s %s can't be used in an expression", s.Type.BuiltinType, leaf.Value)
+			}
+
 			return s.Type.BuiltinType, nil
 		case *symbol.Const:
 			return s.Type.BuiltinType, nil
@@ -192,19 +466,11 @@ func getLeafType(
 	}
 }
 
-func linearizeExpressionTokens(expr ast.Node) []*ast.Leaf {
-	switch expr := expr.(type) {
-	case *ast.Branch:
-		// DFS.
-		var tokens []*ast.Leaf
-		for _, item := range expr.Items {
-			tokens = append(tokens, linearizeExpressionTokens(item)...)
-		}
-
-		return tokens
-	case *ast.Leaf:
-		return []*ast.Leaf{expr}
-	default:
-		panic(fmt.Errorf("unexpected expression type %T", expr))
+// StringLiteralType returns char for literals with exactly one character, and string otherwise.
+func StringLiteralType(raw string) symbol.BuiltinType {
+	if value, err := token.Unquote(raw); err == nil && len(value) == 1 {
+		return symbol.BuiltinTypeChar
 	}
+
+	return symbol.BuiltinTypeString
 }
@@ -0,0 +1,212 @@
+package typechecker
+
+import (
+	"fmt"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+// usage contains symbols which are read or written anywhere in the program.
+// Symbols of outer blocks can be used in nested functions, so usages are collected for the whole program before reporting.
+type usage struct {
+	ctx interface {
+		context.ErrorsContext
+		context.NeutralizerContext
+	}
+	read    map[symbol.Symbol]bool
+	written map[symbol.Symbol]bool
+	// targets contains names of variables on the left side of assignments.
+	targets map[*ast.Leaf]bool
+	// enumValues contains positions of enum values, as they are added as constants, but are used via their enum type.
+	enumValues map[literal.Position]bool
+}
+
+// checkUsage warns about variables, constants, types, functions and params which are declared but never used,
+// and about variables which are assigned but never read.
+func (c TypeChecker) checkUsage(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	program Result,
+) {
+	u := usage{
+		ctx:        ctx,
+		read:       map[symbol.Symbol]bool{},
+		written:    map[symbol.Symbol]bool{},
+		targets:    map[*ast.Leaf]bool{},
+		enumValues: map[literal.Position]bool{},
+	}
+
+	// Program name isn't a symbol, so only the program block is checked.
+	block := program.Node.Query(ast.QueryTypeOne, ast.MarkerProgramBlock)[0]
+	u.collect(program.Scope, block, block, false)
+	u.collectFuncs(program.Funcs)
+
+	u.report(program.Scope, nil)
+	u.reportFuncs(program.Funcs)
+}
+
+func (u usage) collectFuncs(funcs []FuncResult) {
+	for _, f := range funcs {
+		u.collect(f.Scope, f.Node, f.Node, false)
+		u.collectFuncs(f.Funcs)
+	}
+}
+
+// collect marks symbols used in the node. Nested functions are skipped, as they are collected with their own scopes.
+// Names in declarations aren't usages, so they are skipped as well.
+func (u usage) collect(scope symbol.Scope, root, node ast.Node, decl bool) {
+	switch node := node.(type) {
+	case *ast.Leaf:
+		u.collectLeaf(scope, node, decl)
+	case *ast.Branch:
+		if node != root && node.Has(ast.MarkerFuncDecl) {
+			return
+		}
+
+		decl = decl ||
+			node.Has(ast.MarkerVarDecl) ||
+			node.Has(ast.MarkerConstDecl) ||
+			node.Has(ast.MarkerTypeDecl) ||
+			node.Has(ast.MarkerParamGroupDecl) ||
+			node.Has(ast.MarkerFuncHeader)
+
+		// Variable on the left side of the assignment is written, and its indexes are read.
+		if node.Has(ast.MarkerLeftSide) {
+			if name, ok := firstLeaf(node); ok {
+				u.targets[name] = true
+			}
+		}
+
+		for _, item := range node.Items {
+			u.collect(scope, root, item, decl)
+		}
+	}
+}
+
+func (u usage) collectLeaf(scope symbol.Scope, leaf *ast.Leaf, decl bool) {
+	switch {
+	case leaf.ID != token.UserDefined, leaf.Has(ast.MarkerField):
+		return
+	case leaf.Has(ast.MarkerEnumValue):
+		u.enumValues[leaf.Position()] = true
+		return
+	case decl && leaf.Has(ast.MarkerName):
+		return
+	}
+
+	s, ok := u.lookup(scope, leaf)
+	if !ok {
+		return
+	}
+
+	if leaf.Has(ast.MarkerLeftSide) || u.targets[leaf] {
+		u.written[s] = true
+		return
+	}
+
+	u.read[s] = true
+}
+
+// lookup returns the symbol with the name of the leaf.
+// Misspelled names were already reported, so they are replaced with suggested ones to avoid repeated warnings.
+func (u usage) lookup(scope symbol.Scope, leaf *ast.Leaf) (symbol.Symbol, bool) {
+	// Function result variables hide functions, but calls always refer to functions.
+	if leaf.Has(ast.MarkerFuncName) {
+		if f, ok := scope.LookupFunc(leaf.Value); ok {
+			return f, true
+		}
+	}
+
+	s, _ := u.ctx.Neutralizer().NeutralizeUserDefined(scope, leaf.Value)
+	return s, s != nil
+}
+
+func (u usage) reportFuncs(funcs []FuncResult) {
+	for _, f := range funcs {
+		u.report(f.Scope, f.Symbol)
+		u.reportFuncs(f.Funcs)
+	}
+}
+
+// report warns about unused symbols declared in the scope.
+// Function is nil for the program scope, otherwise its result variable is skipped, as it's read by the caller.
+func (u usage) report(scope symbol.Scope, f *symbol.Func) {
+	for _, s := range scope.Symbols() {
+		var (
+			pos literal.Position
+			err error
+		)
+
+		switch s := s.(type) {
+		case *symbol.Var:
+			pos = s.Position
+			switch {
+			case f != nil && s.Value == f.Value, u.read[s]:
+				continue
+			case s.Param:
+				if u.written[s] {
+					continue
+				}
+
+				err = fmt.Errorf("parameter %s is never used", s.Value)
+			case u.written[s]:
+				err = fmt.Errorf("variable %s is assigned but never used", s.Value)
+			default:
+				err = fmt.Errorf("variable %s is declared but never used", s.Value)
+			}
+		case *symbol.Const:
+			pos = s.Position
+			if u.read[s] || u.enumValues[s.Position] {
+				continue
+			}
+
+			err = fmt.Errorf("constant %s is declared but never used", s.Value)
+		case *symbol.Type:
+			pos = s.Position
+			// Builtin types don't have positions.
+			if u.read[s] || s.Position == (literal.Position{}) {
+				continue
+			}
+
+			err = fmt.Errorf("type %s is declared but never used", s.Value)
+		case *symbol.Func:
+			pos = s.Position
+			if u.read[s] || s.Position == (literal.Position{}) {
+				continue
+			}
+
+			if s.IsProcedure() {
+				err = fmt.Errorf("procedure %s is declared but never used", s.Value)
+			} else {
+				err = fmt.Errorf("function %s is declared but never used", s.Value)
+			}
+		default:
+			continue
+		}
+
+		u.ctx.AddWarning(context.ErrorSourceTypecheck, pos, err)
+	}
+}
+
+// firstLeaf returns the first leaf of the node, which is the name of the variable for variables with selectors.
+func firstLeaf(node ast.Node) (*ast.Leaf, bool) {
+	switch node := node.(type) {
+	case *ast.Leaf:
+		return node, node.ID == token.UserDefined
+	case *ast.Branch:
+		if len(node.Items) == 0 {
+			return nil, false
+		}
+
+		return firstLeaf(node.Items[0])
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,159 @@
+package typechecker
+
+import (
+	"fmt"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/data/ast"
+	"github.com/iskorotkov/compiler/internal/data/literal"
+	"github.com/iskorotkov/compiler/internal/data/symbol"
+	"github.com/iskorotkov/compiler/internal/data/token"
+)
+
+// withVar is a record variable of the with operator.
+type withVar struct {
+	node   ast.Node
+	record *symbol.Record
+}
+
+// expandWith replaces names of record fields inside with operators by variables with field selectors,
+// so r.x is used in place of x inside of "with r do". After that, with operators are usual blocks.
+// Fields of the last variable take precedence, so "with a, b do" is the same as "with a do with b do".
+// Indexes in record variables are evaluated on each access to the field.
+func (c TypeChecker) expandWith(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+	vars []withVar,
+) ast.Node {
+	switch node := node.(type) {
+	case *ast.Leaf:
+		if v, ok := findWithVar(node, vars); ok {
+			return &ast.Branch{
+				Items:   selectField(v.node, node),
+				Markers: node.Markers.Merge(ast.Markers{ast.MarkerVariable: true}),
+			}
+		}
+
+		return node
+	case *ast.Branch:
+		if node.Has(ast.MarkerWith) {
+			// Variables of nested with operators may be fields of outer records.
+			withVars := append([]withVar(nil), vars...)
+
+			last := len(node.Items) - 1
+			for i, item := range node.Items[:last] {
+				node.Items[i] = c.expandWithHeader(ctx, scope, item, &withVars)
+			}
+
+			node.Items[last] = c.expandWith(ctx, scope, node.Items[last], withVars)
+
+			return node
+		}
+
+		items := node.Items
+		if node.Has(ast.MarkerVariable) {
+			// Selectors are expanded in place, and the variable name is replaced
+			// with the record variable and the field selector.
+			selectors := len(node.Items) - 1
+			if name, ok := node.Items[0].(*ast.Leaf); ok {
+				if v, ok := findWithVar(name, vars); ok {
+					node.Items = append(selectField(v.node, name), node.Items[1:]...)
+				}
+			}
+
+			items = node.Items[len(node.Items)-selectors:]
+		}
+
+		for i, item := range items {
+			items[i] = c.expandWith(ctx, scope, item, vars)
+		}
+
+		return node
+	default:
+		return node
+	}
+}
+
+// expandWithHeader expands record variables of the with operator one by one,
+// so each of them can use fields of the previous ones.
+func (c TypeChecker) expandWithHeader(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+		context.NeutralizerContext
+	},
+	scope symbol.Scope,
+	node ast.Node,
+	vars *[]withVar,
+) ast.Node {
+	if node.Has(ast.MarkerWithVar) {
+		node = c.expandWith(ctx, scope, node, *vars)
+
+		_, t, err := c.resolver.ResolveVariable(ctx, scope, node)
+		if err != nil {
+			addError(ctx, node.Position(), err)
+			return node
+		}
+
+		if t.Record == nil {
+			ctx.AddError(context.ErrorSourceTypecheck, node.Position(), fmt.Errorf("with requires a record variable, got %s", t.Name()))
+			return node
+		}
+
+		*vars = append(*vars, withVar{node: node, record: t.Record})
+
+		return node
+	}
+
+	if branch, ok := node.(*ast.Branch); ok {
+		for i, item := range branch.Items {
+			branch.Items[i] = c.expandWithHeader(ctx, scope, item, vars)
+		}
+	}
+
+	return node
+}
+
+// findWithVar returns the innermost record variable which has a field with the name of the leaf.
+// Function names, field names and for loop variables are never replaced.
+func findWithVar(leaf *ast.Leaf, vars []withVar) (withVar, bool) {
+	if leaf.ID != token.UserDefined || leaf.Has(ast.MarkerFuncName) || leaf.Has(ast.MarkerField) || leaf.Has(ast.MarkerForVar) {
+		return withVar{}, false
+	}
+
+	for i := len(vars) - 1; i >= 0; i-- {
+		if _, ok := vars[i].record.Field(leaf.Value); ok {
+			return vars[i], true
+		}
+	}
+
+	return withVar{}, false
+}
+
+// selectField returns items of the variable with the field selector for the given field name.
+// Record variable name gets position of the field name, so errors point to the place where the field is used.
+func selectField(variable ast.Node, name *ast.Leaf) []ast.Node {
+	var items []ast.Node
+	if branch, ok := variable.(*ast.Branch); ok && variable.Has(ast.MarkerVariable) {
+		items = append(items, branch.Items...)
+	} else {
+		items = append(items, variable)
+	}
+
+	items[0] = &ast.Leaf{Token: withPosition(items[0].(*ast.Leaf).Token, name.Position())}
+
+	return append(items,
+		&ast.Leaf{Token: token.Token{ID: token.Period, Literal: literal.Literal{Value: ".", Position: name.Position()}}},
+		&ast.Leaf{Token: name.Token, Markers: ast.Markers{ast.MarkerField: true}},
+	)
+}
+
+func withPosition(t token.Token, position literal.Position) token.Token {
+	t.Position = position
+	return t
+}
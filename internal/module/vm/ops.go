@@ -0,0 +1,133 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/iskorotkov/compiler/internal/module/wasm"
+)
+
+var (
+	ErrDivideByZero      = errors.New("integer divide by zero")
+	ErrIntegerOverflow   = errors.New("integer overflow")
+	ErrInvalidConversion = errors.New("invalid conversion to integer")
+)
+
+func binaryOp(t wasm.Type, op wasm.Op, left, right Value) (Value, error) {
+	switch t {
+	case wasm.TypeI32:
+		return binaryOpI32(op, left.I32(), right.I32())
+	case wasm.TypeF64:
+		return binaryOpF64(op, left.F64(), right.F64())
+	default:
+		return Value{}, fmt.Errorf("unsupported operation %s.%s", t, op)
+	}
+}
+
+func binaryOpI32(op wasm.Op, l, r int32) (Value, error) {
+	switch op {
+	case wasm.OpAdd:
+		return I32(l + r), nil
+	case wasm.OpSub:
+		return I32(l - r), nil
+	case wasm.OpMul:
+		return I32(l * r), nil
+	case wasm.OpDivSigned:
+		if r == 0 {
+			return Value{}, ErrDivideByZero
+		}
+
+		if l == math.MinInt32 && r == -1 {
+			return Value{}, ErrIntegerOverflow
+		}
+
+		return I32(l / r), nil
+	case wasm.OpRemSigned:
+		if r == 0 {
+			return Value{}, ErrDivideByZero
+		}
+
+		// Unlike division, remainder of the smallest int and -1 doesn't trap.
+		if r == -1 {
+			return I32(0), nil
+		}
+
+		return I32(l % r), nil
+	case wasm.OpAnd:
+		return I32(l & r), nil
+	case wasm.OpOr:
+		return I32(l | r), nil
+	case wasm.OpXor:
+		return I32(l ^ r), nil
+	case wasm.OpEq:
+		return Bool(l == r), nil
+	case wasm.OpNe:
+		return Bool(l != r), nil
+	case wasm.OpLtSigned:
+		return Bool(l < r), nil
+	case wasm.OpGtSigned:
+		return Bool(l > r), nil
+	case wasm.OpLeSigned:
+		return Bool(l <= r), nil
+	case wasm.OpGeSigned:
+		return Bool(l >= r), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported operation i32.%s", op)
+	}
+}
+
+func binaryOpF64(op wasm.Op, l, r float64) (Value, error) {
+	switch op {
+	case wasm.OpAdd:
+		return F64(l + r), nil
+	case wasm.OpSub:
+		return F64(l - r), nil
+	case wasm.OpMul:
+		return F64(l * r), nil
+	case wasm.OpDiv:
+		return F64(l / r), nil
+	case wasm.OpEq:
+		return Bool(l == r), nil
+	case wasm.OpNe:
+		return Bool(l != r), nil
+	case wasm.OpLt:
+		return Bool(l < r), nil
+	case wasm.OpGt:
+		return Bool(l > r), nil
+	case wasm.OpLe:
+		return Bool(l <= r), nil
+	case wasm.OpGe:
+		return Bool(l >= r), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported operation f64.%s", op)
+	}
+}
+
+func unaryOp(t wasm.Type, op wasm.Op, v Value) (Value, error) {
+	switch {
+	case t == wasm.TypeI32 && op == wasm.OpEqz:
+		return Bool(v.I32() == 0), nil
+	case t == wasm.TypeF64 && op == wasm.OpNeg:
+		return F64(-v.F64()), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported operation %s.%s", t, op)
+	}
+}
+
+func conversion(t wasm.Type, v Value) (Value, error) {
+	switch t {
+	case wasm.TypeI32:
+		// Truncation traps if the result can't be represented as i32.
+		f := math.Trunc(v.F64())
+		if math.IsNaN(f) || f < math.MinInt32 || f > math.MaxInt32 {
+			return Value{}, ErrInvalidConversion
+		}
+
+		return I32(int32(f)), nil
+	case wasm.TypeF64:
+		return F64(float64(v.I32())), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported conversion to %s", t)
+	}
+}
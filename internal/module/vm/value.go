@@ -0,0 +1,83 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/iskorotkov/compiler/internal/module/wasm"
+)
+
+// Value is a typed WASM value.
+// Like in WASM, the value is stored as raw bits and interpreted according to its type.
+type Value struct {
+	Type wasm.Type
+	bits uint64
+}
+
+func I32(v int32) Value {
+	return Value{Type: wasm.TypeI32, bits: uint64(uint32(v))}
+}
+
+func F64(v float64) Value {
+	return Value{Type: wasm.TypeF64, bits: math.Float64bits(v)}
+}
+
+func Bool(v bool) Value {
+	if v {
+		return I32(1)
+	}
+
+	return I32(0)
+}
+
+func (v Value) I32() int32 {
+	return int32(uint32(v.bits))
+}
+
+func (v Value) F64() float64 {
+	return math.Float64frombits(v.bits)
+}
+
+func (v Value) String() string {
+	switch v.Type {
+	case wasm.TypeI32:
+		return strconv.FormatInt(int64(v.I32()), 10)
+	case wasm.TypeF64:
+		return strconv.FormatFloat(v.F64(), 'f', -1, 64)
+	default:
+		return fmt.Sprintf("<%s value>", v.Type)
+	}
+}
+
+// zero returns the default value of the type.
+func zero(t wasm.Type) (Value, error) {
+	switch t {
+	case wasm.TypeI32, wasm.TypeF64:
+		return Value{Type: t}, nil
+	default:
+		return Value{}, fmt.Errorf("unsupported value type %s", t)
+	}
+}
+
+// parseConst parses a value of WASM const instruction.
+func parseConst(c *wasm.Const) (Value, error) {
+	switch c.Type {
+	case wasm.TypeI32:
+		v, err := strconv.ParseInt(c.Value, 10, 32)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid i32 constant %s: %w", c.Value, err)
+		}
+
+		return I32(int32(v)), nil
+	case wasm.TypeF64:
+		v, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid f64 constant %s: %w", c.Value, err)
+		}
+
+		return F64(v), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported constant type %s", c.Type)
+	}
+}
@@ -0,0 +1,553 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/iskorotkov/compiler/internal/module/wasm"
+)
+
+// maxCallDepth limits recursion, so infinite recursion in a program traps instead of crashing the VM.
+const maxCallDepth = 10000
+
+const pageSize = 65536
+
+var (
+	ErrCallStackExhausted = errors.New("call stack exhausted")
+	ErrOutOfBounds        = errors.New("out of bounds memory access")
+	ErrUnreachable        = errors.New("unreachable executed")
+	ErrIndexOutOfBounds   = errors.New("index out of bounds")
+	ErrOutOfRange         = errors.New("value out of range")
+	ErrStackOverflow      = errors.New("stack overflow")
+)
+
+// HostFunc is a function imported from the host environment.
+// It has access to the linear memory of the module (nil if the module doesn't declare memory).
+type HostFunc func(memory []byte, args []Value) error
+
+// Imports maps import paths (for example, "console.log") to host functions.
+type Imports map[string]HostFunc
+
+// ConsoleImports returns imports used by generated modules with output written to w.
+func ConsoleImports(w io.Writer) Imports {
+	return Imports{
+		"console.log": func(_ []byte, args []Value) error {
+			var strs []string
+			for _, arg := range args {
+				strs = append(strs, arg.String())
+			}
+
+			_, err := fmt.Fprintln(w, strings.Join(strs, " "))
+			return err
+		},
+		// Prints len bytes of memory starting at ptr.
+		"env.writeln_str": func(memory []byte, args []Value) error {
+			if len(args) != 2 {
+				return fmt.Errorf("writeln_str: expected 2 arguments, got %d", len(args))
+			}
+
+			ptr, length := uint32(args[0].I32()), uint32(args[1].I32())
+			if uint64(ptr)+uint64(length) > uint64(len(memory)) {
+				return ErrOutOfBounds
+			}
+
+			_, err := fmt.Fprintln(w, string(memory[ptr:ptr+length]))
+			return err
+		},
+		// Reports an array index out of bounds with the source position of the access.
+		"env.bounds_error": func(_ []byte, args []Value) error {
+			if len(args) != 3 {
+				return fmt.Errorf("bounds_error: expected 3 arguments, got %d", len(args))
+			}
+
+			return fmt.Errorf("%w: %d at %d:%d", ErrIndexOutOfBounds, args[0].I32(), args[1].I32(), args[2].I32())
+		},
+		// Reports a value out of range of the subrange variable with the source position of the assignment.
+		"env.range_error": func(_ []byte, args []Value) error {
+			if len(args) != 3 {
+				return fmt.Errorf("range_error: expected 3 arguments, got %d", len(args))
+			}
+
+			return fmt.Errorf("%w: %d at %d:%d", ErrOutOfRange, args[0].I32(), args[1].I32(), args[2].I32())
+		},
+		// Reports that the stack frame of the called function doesn't fit in the stack with the source position of the call.
+		"env.stack_error": func(_ []byte, args []Value) error {
+			if len(args) != 2 {
+				return fmt.Errorf("stack_error: expected 2 arguments, got %d", len(args))
+			}
+
+			return fmt.Errorf("%w at %d:%d", ErrStackOverflow, args[0].I32(), args[1].I32())
+		},
+	}
+}
+
+// VM executes WASM modules produced by the code generator.
+//
+// It interprets the module structure directly instead of decoding the binary format,
+// and supports only the subset of instructions the generator emits.
+type VM struct {
+	imports Imports
+}
+
+func New(imports Imports) *VM {
+	return &VM{
+		imports: imports,
+	}
+}
+
+// Run instantiates the module and calls the exported function.
+func (vm *VM) Run(m wasm.Module, entry string) error {
+	inst, err := vm.instantiate(m)
+	if err != nil {
+		return err
+	}
+
+	f, ok := inst.funcs[entry]
+	if !ok || !f.Export {
+		return fmt.Errorf("exported function %s not found", entry)
+	}
+
+	if len(f.Params) != 0 {
+		return fmt.Errorf("function %s must not have params", entry)
+	}
+
+	_, err = inst.call(f, nil, 0)
+	return err
+}
+
+func (vm *VM) instantiate(m wasm.Module) (*instance, error) {
+	inst := &instance{
+		hostFuncs: map[string]hostFunc{},
+		funcs:     map[string]*wasm.Func{},
+		globals:   map[string]Value{},
+	}
+
+	for _, i := range m.Imports {
+		path := strings.Join(i.Path, ".")
+
+		f, ok := vm.imports[path]
+		if !ok {
+			return nil, fmt.Errorf("import %s not found", path)
+		}
+
+		inst.hostFuncs[i.Name] = hostFunc{
+			HostFunc: f,
+			result:   i.Return,
+		}
+	}
+
+	for i := range m.Funcs {
+		inst.funcs[m.Funcs[i].Name] = &m.Funcs[i]
+	}
+
+	for _, g := range m.Globals {
+		v, err := parseConst(&wasm.Const{Type: g.Type, Value: g.Value})
+		if err != nil {
+			return nil, fmt.Errorf("global %s: %w", g.Name, err)
+		}
+
+		inst.globals[g.Name] = v
+	}
+
+	if m.Memory != nil {
+		inst.memory = make([]byte, m.Memory.Pages*pageSize)
+	}
+
+	for _, d := range m.Data {
+		if d.Offset < 0 || d.Offset+len(d.Bytes) > len(inst.memory) {
+			return nil, fmt.Errorf("data segment at %d: %w", d.Offset, ErrOutOfBounds)
+		}
+
+		copy(inst.memory[d.Offset:], d.Bytes)
+	}
+
+	return inst, nil
+}
+
+type hostFunc struct {
+	HostFunc
+	result *wasm.Return
+}
+
+// instance holds the state of the running module.
+type instance struct {
+	hostFuncs map[string]hostFunc
+	funcs     map[string]*wasm.Func
+	globals   map[string]Value
+	memory    []byte
+}
+
+// frame holds params and locals of the function being executed.
+type frame struct {
+	locals map[string]Value
+	depth  int
+}
+
+// signal describes how the control leaves a statement.
+type signal struct {
+	kind signalKind
+	// label is a relative depth of the block or loop the branch targets.
+	label int
+	// value is a value returned from the function.
+	value *Value
+}
+
+type signalKind int
+
+const (
+	signalNone signalKind = iota
+	signalBranch
+	signalReturn
+)
+
+func (inst *instance) call(f *wasm.Func, args []Value, depth int) (*Value, error) {
+	if depth == maxCallDepth {
+		return nil, ErrCallStackExhausted
+	}
+
+	if len(args) != len(f.Params) {
+		return nil, fmt.Errorf("function %s: expected %d arguments, got %d", f.Name, len(f.Params), len(args))
+	}
+
+	fr := &frame{
+		locals: map[string]Value{},
+		depth:  depth,
+	}
+
+	for i, p := range f.Params {
+		fr.locals[p.Name] = args[i]
+	}
+
+	for _, l := range f.Locals {
+		v, err := zero(l.Type)
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", f.Name, err)
+		}
+
+		fr.locals[l.Name] = v
+	}
+
+	s, err := inst.execStatements(fr, f.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Function body is an implicit block, so branching out of it returns from the function.
+	if s.kind == signalReturn {
+		return s.value, nil
+	}
+
+	if f.Return != nil {
+		return nil, fmt.Errorf("function %s: missing return value", f.Name)
+	}
+
+	return nil, nil
+}
+
+func (inst *instance) execStatements(fr *frame, statements []wasm.Statement) (signal, error) {
+	for _, stmt := range statements {
+		s, err := inst.exec(fr, stmt)
+		if err != nil || s.kind != signalNone {
+			return s, err
+		}
+	}
+
+	return signal{}, nil
+}
+
+// leaveBlock handles a signal which leaves the block.
+// Branch to the block itself is consumed, and branches to outer blocks get their label decremented.
+func leaveBlock(s signal) signal {
+	if s.kind != signalBranch {
+		return s
+	}
+
+	if s.label == 0 {
+		return signal{}
+	}
+
+	s.label--
+	return s
+}
+
+func (inst *instance) exec(fr *frame, stmt wasm.Statement) (signal, error) {
+	switch stmt := stmt.(type) {
+	case *wasm.If:
+		cond, err := inst.eval(fr, stmt.Cond)
+		if err != nil {
+			return signal{}, err
+		}
+
+		body := stmt.FalseBody
+		if cond.I32() != 0 {
+			body = stmt.TrueBody
+		}
+
+		s, err := inst.execStatements(fr, body)
+		if err != nil {
+			return signal{}, err
+		}
+
+		return leaveBlock(s), nil
+	case *wasm.Loop:
+		for {
+			s, err := inst.execStatements(fr, stmt.Body)
+			if err != nil {
+				return signal{}, err
+			}
+
+			// Branch to the loop continues it.
+			if s.kind == signalBranch && s.label == 0 {
+				continue
+			}
+
+			if s.kind != signalNone {
+				return leaveBlock(s), nil
+			}
+
+			if stmt.BranchCond == nil {
+				return signal{}, nil
+			}
+
+			cond, err := inst.eval(fr, stmt.BranchCond)
+			if err != nil {
+				return signal{}, err
+			}
+
+			if cond.I32() == 0 {
+				return signal{}, nil
+			}
+		}
+	case *wasm.Block:
+		s, err := inst.execStatements(fr, stmt.Body)
+		if err != nil {
+			return signal{}, err
+		}
+
+		return leaveBlock(s), nil
+	case *wasm.Br:
+		return signal{kind: signalBranch, label: stmt.Label}, nil
+	case *wasm.BrIf:
+		cond, err := inst.eval(fr, stmt.Cond)
+		if err != nil {
+			return signal{}, err
+		}
+
+		if cond.I32() == 0 {
+			return signal{}, nil
+		}
+
+		return signal{kind: signalBranch, label: stmt.Label}, nil
+	case *wasm.BrTable:
+		index, err := inst.eval(fr, stmt.Index)
+		if err != nil {
+			return signal{}, err
+		}
+
+		// Index is treated as unsigned, so negative values select the default label.
+		if i := uint32(index.I32()); int64(i) < int64(len(stmt.Labels)) {
+			return signal{kind: signalBranch, label: stmt.Labels[i]}, nil
+		}
+
+		return signal{kind: signalBranch, label: stmt.Default}, nil
+	case *wasm.FuncCall:
+		_, err := inst.evalCall(fr, stmt)
+		return signal{}, err
+	case *wasm.FuncReturn:
+		v, err := inst.eval(fr, stmt.Expr)
+		if err != nil {
+			return signal{}, err
+		}
+
+		return signal{kind: signalReturn, value: &v}, nil
+	case *wasm.Drop:
+		_, err := inst.eval(fr, stmt.Expr)
+		return signal{}, err
+	case *wasm.LocalSet:
+		if _, ok := fr.locals[stmt.Name]; !ok {
+			return signal{}, fmt.Errorf("local %s not found", stmt.Name)
+		}
+
+		v, err := inst.eval(fr, stmt.Expr)
+		if err != nil {
+			return signal{}, err
+		}
+
+		fr.locals[stmt.Name] = v
+		return signal{}, nil
+	case *wasm.GlobalSet:
+		if _, ok := inst.globals[stmt.Name]; !ok {
+			return signal{}, fmt.Errorf("global %s not found", stmt.Name)
+		}
+
+		v, err := inst.eval(fr, stmt.Expr)
+		if err != nil {
+			return signal{}, err
+		}
+
+		inst.globals[stmt.Name] = v
+		return signal{}, nil
+	case *wasm.Store:
+		addr, err := inst.eval(fr, stmt.Addr)
+		if err != nil {
+			return signal{}, err
+		}
+
+		v, err := inst.eval(fr, stmt.Value)
+		if err != nil {
+			return signal{}, err
+		}
+
+		return signal{}, inst.store(stmt.Type, uint32(addr.I32()), v)
+	case *wasm.Unreachable:
+		return signal{}, ErrUnreachable
+	default:
+		return signal{}, fmt.Errorf("unsupported statement %T", stmt)
+	}
+}
+
+func (inst *instance) eval(fr *frame, expr wasm.Expr) (Value, error) {
+	switch expr := expr.(type) {
+	case *wasm.BinaryOp:
+		left, err := inst.eval(fr, expr.Left)
+		if err != nil {
+			return Value{}, err
+		}
+
+		right, err := inst.eval(fr, expr.Right)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return binaryOp(expr.Type, expr.Op, left, right)
+	case *wasm.UnaryOp:
+		v, err := inst.eval(fr, expr.Expr)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return unaryOp(expr.Type, expr.Op, v)
+	case *wasm.Conversion:
+		v, err := inst.eval(fr, expr.Expr)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return conversion(expr.ResultingType, v)
+	case *wasm.Const:
+		return parseConst(expr)
+	case *wasm.LocalGet:
+		v, ok := fr.locals[expr.Name]
+		if !ok {
+			return Value{}, fmt.Errorf("local %s not found", expr.Name)
+		}
+
+		return v, nil
+	case *wasm.GlobalGet:
+		v, ok := inst.globals[expr.Name]
+		if !ok {
+			return Value{}, fmt.Errorf("global %s not found", expr.Name)
+		}
+
+		return v, nil
+	case *wasm.Load:
+		addr, err := inst.eval(fr, expr.Addr)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return inst.load(expr.Type, uint32(addr.I32()))
+	case *wasm.FuncCall:
+		v, err := inst.evalCall(fr, expr)
+		if err != nil {
+			return Value{}, err
+		}
+
+		if v == nil {
+			return Value{}, fmt.Errorf("function %s doesn't return a value", expr.Name)
+		}
+
+		return *v, nil
+	default:
+		return Value{}, fmt.Errorf("unsupported expression %T", expr)
+	}
+}
+
+func (inst *instance) evalCall(fr *frame, call *wasm.FuncCall) (*Value, error) {
+	var args []Value
+	for _, arg := range call.Args {
+		v, err := inst.eval(fr, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, v)
+	}
+
+	if f, ok := inst.hostFuncs[call.Name]; ok {
+		if f.result != nil {
+			return nil, fmt.Errorf("host function %s: results are not supported", call.Name)
+		}
+
+		return nil, f.HostFunc(inst.memory, args)
+	}
+
+	f, ok := inst.funcs[call.Name]
+	if !ok {
+		return nil, fmt.Errorf("function %s not found", call.Name)
+	}
+
+	return inst.call(f, args, fr.depth+1)
+}
+
+// load reads a little endian value from linear memory.
+func (inst *instance) load(t wasm.Type, addr uint32) (Value, error) {
+	size := uint64(4)
+	if t == wasm.TypeF64 {
+		size = 8
+	}
+
+	if uint64(addr)+size > uint64(len(inst.memory)) {
+		return Value{}, ErrOutOfBounds
+	}
+
+	bytes := inst.memory[addr : uint64(addr)+size]
+
+	switch t {
+	case wasm.TypeI32:
+		return I32(int32(binary.LittleEndian.Uint32(bytes))), nil
+	case wasm.TypeF64:
+		return F64(math.Float64frombits(binary.LittleEndian.Uint64(bytes))), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported load type %s", t)
+	}
+}
+
+// store writes a little endian value to linear memory.
+func (inst *instance) store(t wasm.Type, addr uint32, v Value) error {
+	size := uint64(4)
+	if t == wasm.TypeF64 {
+		size = 8
+	}
+
+	if uint64(addr)+size > uint64(len(inst.memory)) {
+		return ErrOutOfBounds
+	}
+
+	bytes := inst.memory[addr : uint64(addr)+size]
+
+	switch t {
+	case wasm.TypeI32:
+		binary.LittleEndian.PutUint32(bytes, uint32(v.I32()))
+	case wasm.TypeF64:
+		binary.LittleEndian.PutUint64(bytes, math.Float64bits(v.F64()))
+	default:
+		return fmt.Errorf("unsupported store type %s", t)
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package vm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/module/vm"
+	"github.com/iskorotkov/compiler/internal/module/wasm"
+)
+
+var writelnImports = []wasm.Import{
+	{
+		Path:   []string{"console", "log"},
+		Name:   "writeln_i32",
+		Params: []wasm.Param{{Name: "value", Type: wasm.TypeI32}},
+	},
+	{
+		Path:   []string{"console", "log"},
+		Name:   "writeln_f64",
+		Params: []wasm.Param{{Name: "value", Type: wasm.TypeF64}},
+	},
+}
+
+func TestVM_Run(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		name     string
+		module   wasm.Module
+		expected string
+		err      error
+	}
+
+	tests := []test{
+		{
+			name: "arithmetic and conversions",
+			module: wasm.Module{
+				Imports: writelnImports,
+				Globals: []wasm.Global{{Name: "x", Type: wasm.TypeI32, Value: "7", Mutable: true}},
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []wasm.Statement{
+							&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{
+								&wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpRemSigned, Left: &wasm.GlobalGet{Name: "x"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "-3"}},
+							}},
+							&wasm.FuncCall{Name: "writeln_f64", Args: []wasm.Expr{
+								&wasm.BinaryOp{
+									Type:  wasm.TypeF64,
+									Op:    wasm.OpDiv,
+									Left:  &wasm.Conversion{ResultingType: wasm.TypeF64, Expr: &wasm.GlobalGet{Name: "x"}},
+									Right: &wasm.Const{Type: wasm.TypeF64, Value: "2.0"},
+								},
+							}},
+							&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{
+								&wasm.BinaryOp{Type: wasm.TypeF64, Op: wasm.OpLt, Left: &wasm.Const{Type: wasm.TypeF64, Value: "1.5"}, Right: &wasm.Const{Type: wasm.TypeF64, Value: "2"}},
+							}},
+						},
+					},
+				},
+			},
+			expected: "1\n3.5\n1\n",
+		},
+		{
+			name: "loops and branches",
+			module: wasm.Module{
+				Imports: writelnImports,
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Locals: []wasm.Local{{Name: "i", Type: wasm.TypeI32}},
+						Body: []wasm.Statement{
+							&wasm.Block{Body: []wasm.Statement{
+								&wasm.Loop{Body: []wasm.Statement{
+									&wasm.LocalSet{Name: "i", Expr: &wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpAdd, Left: &wasm.LocalGet{Name: "i"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "1"}}},
+									&wasm.If{
+										Cond: &wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpEq, Left: &wasm.LocalGet{Name: "i"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "2"}},
+										TrueBody: []wasm.Statement{
+											// Skip the rest of the iteration.
+											&wasm.Br{Label: 1},
+										},
+									},
+									&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{&wasm.LocalGet{Name: "i"}}},
+									&wasm.BrIf{Label: 1, Cond: &wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpGeSigned, Left: &wasm.LocalGet{Name: "i"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "4"}}},
+									&wasm.Br{Label: 0},
+								}},
+							}},
+							&wasm.Loop{
+								BranchCond: &wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpNe, Left: &wasm.LocalGet{Name: "i"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "0"}},
+								Body: []wasm.Statement{
+									&wasm.LocalSet{Name: "i", Expr: &wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpSub, Left: &wasm.LocalGet{Name: "i"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "2"}}},
+									&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{&wasm.LocalGet{Name: "i"}}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: "1\n3\n4\n2\n0\n",
+		},
+		{
+			name: "recursive calls",
+			module: wasm.Module{
+				Imports: writelnImports,
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []wasm.Statement{
+							&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{
+								&wasm.FuncCall{Name: "factorial", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "5"}}},
+							}},
+						},
+					},
+					{
+						Name:   "factorial",
+						Params: []wasm.Param{{Name: "n", Type: wasm.TypeI32}},
+						Return: &wasm.Return{Type: wasm.TypeI32},
+						Body: []wasm.Statement{
+							&wasm.If{
+								Cond: &wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpLeSigned, Left: &wasm.LocalGet{Name: "n"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "1"}},
+								TrueBody: []wasm.Statement{
+									&wasm.FuncReturn{Expr: &wasm.Const{Type: wasm.TypeI32, Value: "1"}},
+								},
+							},
+							&wasm.FuncReturn{Expr: &wasm.BinaryOp{
+								Type: wasm.TypeI32,
+								Op:   wasm.OpMul,
+								Left: &wasm.LocalGet{Name: "n"},
+								Right: &wasm.FuncCall{Name: "factorial", Args: []wasm.Expr{
+									&wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpSub, Left: &wasm.LocalGet{Name: "n"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "1"}},
+								}},
+							}},
+						},
+					},
+				},
+			},
+			expected: "120\n",
+		},
+		{
+			name: "branch table",
+			module: wasm.Module{
+				Imports: writelnImports,
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []wasm.Statement{
+							&wasm.FuncCall{Name: "pick", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "0"}}},
+							&wasm.FuncCall{Name: "pick", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "1"}}},
+							&wasm.FuncCall{Name: "pick", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "5"}}},
+							&wasm.FuncCall{Name: "pick", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "-1"}}},
+						},
+					},
+					{
+						Name:   "pick",
+						Params: []wasm.Param{{Name: "n", Type: wasm.TypeI32}},
+						Body: []wasm.Statement{
+							&wasm.Block{Body: []wasm.Statement{
+								&wasm.Block{Body: []wasm.Statement{
+									&wasm.Block{Body: []wasm.Statement{
+										// Out of range indexes (including negative ones) skip both branches.
+										&wasm.BrTable{Labels: []int{0, 1}, Default: 2, Index: &wasm.LocalGet{Name: "n"}},
+									}},
+									&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "10"}}},
+									&wasm.Br{Label: 1},
+								}},
+								&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "20"}}},
+							}},
+						},
+					},
+				},
+			},
+			expected: "10\n20\n",
+		},
+		{
+			name: "division by zero",
+			module: wasm.Module{
+				Imports: writelnImports,
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []wasm.Statement{
+							&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{&wasm.Const{Type: wasm.TypeI32, Value: "1"}}},
+							&wasm.Drop{Expr: &wasm.BinaryOp{Type: wasm.TypeI32, Op: wasm.OpDivSigned, Left: &wasm.Const{Type: wasm.TypeI32, Value: "1"}, Right: &wasm.Const{Type: wasm.TypeI32, Value: "0"}}},
+						},
+					},
+				},
+			},
+			expected: "1\n",
+			err:      vm.ErrDivideByZero,
+		},
+		{
+			name: "memory",
+			module: wasm.Module{
+				Imports: append(writelnImports, wasm.Import{
+					Path:   []string{"env", "writeln_str"},
+					Name:   "writeln_str",
+					Params: []wasm.Param{{Name: "ptr", Type: wasm.TypeI32}, {Name: "len", Type: wasm.TypeI32}},
+				}),
+				Memory: &wasm.Memory{Pages: 1},
+				Data:   []wasm.Data{{Offset: 8, Bytes: []byte{2, 0, 0, 0, 'h', 'i'}}},
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []wasm.Statement{
+							&wasm.FuncCall{Name: "writeln_str", Args: []wasm.Expr{
+								&wasm.Const{Type: wasm.TypeI32, Value: "12"},
+								&wasm.Load{Type: wasm.TypeI32, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "8"}},
+							}},
+							&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{
+								&wasm.Load{Type: wasm.TypeI32, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "65532"}},
+							}},
+							&wasm.Drop{Expr: &wasm.Load{Type: wasm.TypeI32, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "65533"}}},
+						},
+					},
+				},
+			},
+			expected: "hi\n0\n",
+			err:      vm.ErrOutOfBounds,
+		},
+		{
+			name: "store",
+			module: wasm.Module{
+				Imports: writelnImports,
+				Memory:  &wasm.Memory{Pages: 1},
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []wasm.Statement{
+							&wasm.Store{Type: wasm.TypeI32, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "0"}, Value: &wasm.Const{Type: wasm.TypeI32, Value: "-7"}},
+							&wasm.Store{Type: wasm.TypeF64, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "8"}, Value: &wasm.Const{Type: wasm.TypeF64, Value: "2.5"}},
+							&wasm.FuncCall{Name: "writeln_i32", Args: []wasm.Expr{
+								&wasm.Load{Type: wasm.TypeI32, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "0"}},
+							}},
+							&wasm.FuncCall{Name: "writeln_f64", Args: []wasm.Expr{
+								&wasm.Load{Type: wasm.TypeF64, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "8"}},
+							}},
+							&wasm.Store{Type: wasm.TypeF64, Addr: &wasm.Const{Type: wasm.TypeI32, Value: "65530"}, Value: &wasm.Const{Type: wasm.TypeF64, Value: "0"}},
+						},
+					},
+				},
+			},
+			expected: "-7\n2.5\n",
+			err:      vm.ErrOutOfBounds,
+		},
+		{
+			name: "infinite recursion",
+			module: wasm.Module{
+				Funcs: []wasm.Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []wasm.Statement{
+							&wasm.FuncCall{Name: "main"},
+						},
+					},
+				},
+			},
+			err: vm.ErrCallStackExhausted,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			err := vm.New(vm.ConsoleImports(&out)).Run(test.module, "main")
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.expected, out.String())
+		})
+	}
+}
@@ -0,0 +1,753 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	sectionType     byte = 1
+	sectionImport   byte = 2
+	sectionFunction byte = 3
+	sectionMemory   byte = 5
+	sectionGlobal   byte = 6
+	sectionExport   byte = 7
+	sectionCode     byte = 10
+	sectionData     byte = 11
+)
+
+const (
+	kindFunc   byte = 0x00
+	kindMemory byte = 0x02
+
+	limitsMin         byte = 0x00
+	dataSegmentActive byte = 0x00
+
+	funcTypeTag    byte = 0x60
+	blockTypeEmpty byte = 0x40
+)
+
+const (
+	opcodeUnreachable byte = 0x00
+	opcodeBlock       byte = 0x02
+	opcodeLoop        byte = 0x03
+	opcodeIf          byte = 0x04
+	opcodeElse        byte = 0x05
+	opcodeEnd         byte = 0x0B
+	opcodeBr          byte = 0x0C
+	opcodeBrIf        byte = 0x0D
+	opcodeBrTable     byte = 0x0E
+	opcodeReturn      byte = 0x0F
+	opcodeCall        byte = 0x10
+	opcodeDrop        byte = 0x1A
+	opcodeLocalGet    byte = 0x20
+	opcodeLocalSet    byte = 0x21
+	opcodeGlobalGet   byte = 0x23
+	opcodeGlobalSet   byte = 0x24
+	opcodeI32Load     byte = 0x28
+	opcodeF64Load     byte = 0x2B
+	opcodeI32Store    byte = 0x36
+	opcodeF64Store    byte = 0x39
+	opcodeI32Const    byte = 0x41
+	opcodeF64Const    byte = 0x44
+)
+
+var (
+	magic   = []byte{0x00, 0x61, 0x73, 0x6D}
+	version = []byte{0x01, 0x00, 0x00, 0x00}
+
+	valueTypes = map[Type]byte{
+		TypeI32: 0x7F,
+		TypeF64: 0x7C,
+	}
+
+	opcodesI32 = map[Op]byte{
+		OpEqz:         0x45,
+		OpEq:          0x46,
+		OpNe:          0x47,
+		OpLtSigned:    0x48,
+		OpGtSigned:    0x4A,
+		OpLeSigned:    0x4C,
+		OpGeSigned:    0x4E,
+		OpAdd:         0x6A,
+		OpSub:         0x6B,
+		OpMul:         0x6C,
+		OpDivSigned:   0x6D,
+		OpRemSigned:   0x6F,
+		OpAnd:         0x71,
+		OpOr:          0x72,
+		OpXor:         0x73,
+		OpTruncateF64: 0xAA,
+	}
+	opcodesF64 = map[Op]byte{
+		OpEq:         0x61,
+		OpNe:         0x62,
+		OpLt:         0x63,
+		OpGt:         0x64,
+		OpLe:         0x65,
+		OpGe:         0x66,
+		OpNeg:        0x9A,
+		OpAdd:        0xA0,
+		OpSub:        0xA1,
+		OpMul:        0xA2,
+		OpDiv:        0xA3,
+		OpConvertI32: 0xB7,
+	}
+)
+
+// Binary encodes the module in WASM binary format.
+func (m Module) Binary() ([]byte, error) {
+	e, err := newEncoder(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Write(magic)
+	b.Write(version)
+
+	sections := []struct {
+		id     byte
+		encode func() ([]byte, error)
+	}{
+		{sectionType, e.typeSection},
+		{sectionImport, e.importSection},
+		{sectionFunction, e.functionSection},
+		{sectionMemory, e.memorySection},
+		{sectionGlobal, e.globalSection},
+		{sectionExport, e.exportSection},
+		{sectionCode, e.codeSection},
+		{sectionData, e.dataSection},
+	}
+
+	for _, section := range sections {
+		content, err := section.encode()
+		if err != nil {
+			return nil, err
+		}
+
+		// Empty sections are omitted.
+		if content == nil {
+			continue
+		}
+
+		b.WriteByte(section.id)
+		b.Write(appendUleb128(nil, uint64(len(content))))
+		b.Write(content)
+	}
+
+	return b.Bytes(), nil
+}
+
+// encoder holds indices of functions, globals and function types which are referenced by name in the module.
+type encoder struct {
+	module    Module
+	funcs     map[string]int
+	globals   map[string]int
+	types     [][]byte
+	funcTypes map[string]int
+}
+
+func newEncoder(m Module) (*encoder, error) {
+	e := &encoder{
+		module:    m,
+		funcs:     map[string]int{},
+		globals:   map[string]int{},
+		funcTypes: map[string]int{},
+	}
+
+	// Imported functions are indexed before functions declared in the module.
+	for _, i := range m.Imports {
+		e.funcs[i.Name] = len(e.funcs)
+		if err := e.addFuncType(i.Params, i.Return); err != nil {
+			return nil, fmt.Errorf("import %s: %w", i.Name, err)
+		}
+	}
+
+	for _, f := range m.Funcs {
+		e.funcs[f.Name] = len(e.funcs)
+		if err := e.addFuncType(f.Params, f.Return); err != nil {
+			return nil, fmt.Errorf("func %s: %w", f.Name, err)
+		}
+	}
+
+	for i, g := range m.Globals {
+		e.globals[g.Name] = i
+	}
+
+	return e, nil
+}
+
+// addFuncType encodes the function type if it wasn't encoded before.
+// Function types are identified by their signatures, so functions with the same signature share the type.
+func (e *encoder) addFuncType(params []Param, r *Return) error {
+	signature := paramsString(params) + resultString(r)
+	if _, ok := e.funcTypes[signature]; ok {
+		return nil
+	}
+
+	var b []byte
+	b = append(b, funcTypeTag)
+	b = appendUleb128(b, uint64(len(params)))
+	for _, p := range params {
+		t, err := valueType(p.Type)
+		if err != nil {
+			return err
+		}
+
+		b = append(b, t)
+	}
+
+	if r == nil {
+		b = append(b, 0)
+	} else {
+		t, err := valueType(r.Type)
+		if err != nil {
+			return err
+		}
+
+		b = append(b, 1, t)
+	}
+
+	e.funcTypes[signature] = len(e.types)
+	e.types = append(e.types, b)
+
+	return nil
+}
+
+func (e *encoder) funcType(params []Param, r *Return) int {
+	return e.funcTypes[paramsString(params)+resultString(r)]
+}
+
+func (e *encoder) typeSection() ([]byte, error) {
+	if len(e.types) == 0 {
+		return nil, nil
+	}
+
+	b := appendUleb128(nil, uint64(len(e.types)))
+	for _, t := range e.types {
+		b = append(b, t...)
+	}
+
+	return b, nil
+}
+
+func (e *encoder) importSection() ([]byte, error) {
+	if len(e.module.Imports) == 0 {
+		return nil, nil
+	}
+
+	b := appendUleb128(nil, uint64(len(e.module.Imports)))
+	for _, i := range e.module.Imports {
+		if len(i.Path) != 2 {
+			return nil, fmt.Errorf("import %s: path must contain module and field names", i.Name)
+		}
+
+		b = appendName(b, i.Path[0])
+		b = appendName(b, i.Path[1])
+		b = append(b, kindFunc)
+		b = appendUleb128(b, uint64(e.funcType(i.Params, i.Return)))
+	}
+
+	return b, nil
+}
+
+func (e *encoder) functionSection() ([]byte, error) {
+	if len(e.module.Funcs) == 0 {
+		return nil, nil
+	}
+
+	b := appendUleb128(nil, uint64(len(e.module.Funcs)))
+	for _, f := range e.module.Funcs {
+		b = appendUleb128(b, uint64(e.funcType(f.Params, f.Return)))
+	}
+
+	return b, nil
+}
+
+func (e *encoder) memorySection() ([]byte, error) {
+	if e.module.Memory == nil {
+		return nil, nil
+	}
+
+	b := appendUleb128(nil, 1)
+	b = append(b, limitsMin)
+	return appendUleb128(b, uint64(e.module.Memory.Pages)), nil
+}
+
+func (e *encoder) globalSection() ([]byte, error) {
+	if len(e.module.Globals) == 0 {
+		return nil, nil
+	}
+
+	b := appendUleb128(nil, uint64(len(e.module.Globals)))
+	for _, g := range e.module.Globals {
+		t, err := valueType(g.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		var mutable byte
+		if g.Mutable {
+			mutable = 1
+		}
+
+		b = append(b, t, mutable)
+
+		// Globals are initialized with constant expressions.
+		b, err = e.appendExpr(b, &Const{Type: g.Type, Value: g.Value}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("global %s: %w", g.Name, err)
+		}
+
+		b = append(b, opcodeEnd)
+	}
+
+	return b, nil
+}
+
+func (e *encoder) exportSection() ([]byte, error) {
+	var (
+		count int
+		b     []byte
+	)
+
+	for _, f := range e.module.Funcs {
+		if f.Export {
+			b = appendName(b, f.Name)
+			b = append(b, kindFunc)
+			b = appendUleb128(b, uint64(e.funcs[f.Name]))
+			count++
+		}
+	}
+
+	if e.module.Memory != nil && e.module.Memory.Export != "" {
+		b = appendName(b, e.module.Memory.Export)
+		b = append(b, kindMemory, 0)
+		count++
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+
+	return append(appendUleb128(nil, uint64(count)), b...), nil
+}
+
+func (e *encoder) codeSection() ([]byte, error) {
+	if len(e.module.Funcs) == 0 {
+		return nil, nil
+	}
+
+	b := appendUleb128(nil, uint64(len(e.module.Funcs)))
+	for _, f := range e.module.Funcs {
+		body, err := e.funcBody(f)
+		if err != nil {
+			return nil, fmt.Errorf("func %s: %w", f.Name, err)
+		}
+
+		b = appendUleb128(b, uint64(len(body)))
+		b = append(b, body...)
+	}
+
+	return b, nil
+}
+
+func (e *encoder) dataSection() ([]byte, error) {
+	if len(e.module.Data) == 0 {
+		return nil, nil
+	}
+
+	b := appendUleb128(nil, uint64(len(e.module.Data)))
+	for _, d := range e.module.Data {
+		b = append(b, dataSegmentActive)
+		b = append(b, opcodeI32Const)
+		b = appendSleb128(b, int64(d.Offset))
+		b = append(b, opcodeEnd)
+		b = appendUleb128(b, uint64(len(d.Bytes)))
+		b = append(b, d.Bytes...)
+	}
+
+	return b, nil
+}
+
+func (e *encoder) funcBody(f Func) ([]byte, error) {
+	// Params and locals share the same index space.
+	locals := map[string]int{}
+	for _, p := range f.Params {
+		locals[p.Name] = len(locals)
+	}
+
+	for _, l := range f.Locals {
+		locals[l.Name] = len(locals)
+	}
+
+	// Consecutive locals of the same type are declared together.
+	type localGroup struct {
+		count int
+		t     byte
+	}
+
+	var groups []localGroup
+	for _, l := range f.Locals {
+		t, err := valueType(l.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(groups) > 0 && groups[len(groups)-1].t == t {
+			groups[len(groups)-1].count++
+			continue
+		}
+
+		groups = append(groups, localGroup{count: 1, t: t})
+	}
+
+	b := appendUleb128(nil, uint64(len(groups)))
+	for _, g := range groups {
+		b = appendUleb128(b, uint64(g.count))
+		b = append(b, g.t)
+	}
+
+	b, err := e.appendStatements(b, f.Body, locals)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, opcodeEnd), nil
+}
+
+func (e *encoder) appendStatements(b []byte, statements []Statement, locals map[string]int) ([]byte, error) {
+	for _, stmt := range statements {
+		var err error
+		b, err = e.appendStatement(b, stmt, locals)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func (e *encoder) appendStatement(b []byte, stmt Statement, locals map[string]int) ([]byte, error) {
+	var err error
+
+	switch stmt := stmt.(type) {
+	case *If:
+		if b, err = e.appendExpr(b, stmt.Cond, locals); err != nil {
+			return nil, err
+		}
+
+		b = append(b, opcodeIf, blockTypeEmpty)
+		if b, err = e.appendStatements(b, stmt.TrueBody, locals); err != nil {
+			return nil, err
+		}
+
+		if len(stmt.FalseBody) != 0 {
+			b = append(b, opcodeElse)
+			if b, err = e.appendStatements(b, stmt.FalseBody, locals); err != nil {
+				return nil, err
+			}
+		}
+
+		return append(b, opcodeEnd), nil
+	case *Loop:
+		b = append(b, opcodeLoop, blockTypeEmpty)
+		if b, err = e.appendStatements(b, stmt.Body, locals); err != nil {
+			return nil, err
+		}
+
+		if stmt.BranchCond != nil {
+			if b, err = e.appendExpr(b, stmt.BranchCond, locals); err != nil {
+				return nil, err
+			}
+
+			b = append(b, opcodeBrIf, 0)
+		}
+
+		return append(b, opcodeEnd), nil
+	case *Block:
+		b = append(b, opcodeBlock, blockTypeEmpty)
+		if b, err = e.appendStatements(b, stmt.Body, locals); err != nil {
+			return nil, err
+		}
+
+		return append(b, opcodeEnd), nil
+	case *Br:
+		b = append(b, opcodeBr)
+		return appendUleb128(b, uint64(stmt.Label)), nil
+	case *BrIf:
+		if b, err = e.appendExpr(b, stmt.Cond, locals); err != nil {
+			return nil, err
+		}
+
+		b = append(b, opcodeBrIf)
+		return appendUleb128(b, uint64(stmt.Label)), nil
+	case *BrTable:
+		if b, err = e.appendExpr(b, stmt.Index, locals); err != nil {
+			return nil, err
+		}
+
+		b = append(b, opcodeBrTable)
+		b = appendUleb128(b, uint64(len(stmt.Labels)))
+		for _, label := range stmt.Labels {
+			b = appendUleb128(b, uint64(label))
+		}
+
+		return appendUleb128(b, uint64(stmt.Default)), nil
+	case *FuncCall:
+		return e.appendExpr(b, stmt, locals)
+	case *FuncReturn:
+		if b, err = e.appendExpr(b, stmt.Expr, locals); err != nil {
+			return nil, err
+		}
+
+		return append(b, opcodeReturn), nil
+	case *Drop:
+		if b, err = e.appendExpr(b, stmt.Expr, locals); err != nil {
+			return nil, err
+		}
+
+		return append(b, opcodeDrop), nil
+	case *LocalSet:
+		index, ok := locals[stmt.Name]
+		if !ok {
+			return nil, fmt.Errorf("local %s not found", stmt.Name)
+		}
+
+		if b, err = e.appendExpr(b, stmt.Expr, locals); err != nil {
+			return nil, err
+		}
+
+		b = append(b, opcodeLocalSet)
+		return appendUleb128(b, uint64(index)), nil
+	case *GlobalSet:
+		index, ok := e.globals[stmt.Name]
+		if !ok {
+			return nil, fmt.Errorf("global %s not found", stmt.Name)
+		}
+
+		if b, err = e.appendExpr(b, stmt.Expr, locals); err != nil {
+			return nil, err
+		}
+
+		b = append(b, opcodeGlobalSet)
+		return appendUleb128(b, uint64(index)), nil
+	case *Store:
+		if b, err = e.appendExpr(b, stmt.Addr, locals); err != nil {
+			return nil, err
+		}
+
+		if b, err = e.appendExpr(b, stmt.Value, locals); err != nil {
+			return nil, err
+		}
+
+		// Memory argument is the same as for loads.
+		switch stmt.Type {
+		case TypeI32:
+			return append(b, opcodeI32Store, 2, 0), nil
+		case TypeF64:
+			return append(b, opcodeF64Store, 3, 0), nil
+		default:
+			return nil, fmt.Errorf("unsupported store type %s", stmt.Type)
+		}
+	case *Unreachable:
+		return append(b, opcodeUnreachable), nil
+	default:
+		return nil, fmt.Errorf("unsupported statement %T", stmt)
+	}
+}
+
+func (e *encoder) appendExpr(b []byte, expr Expr, locals map[string]int) ([]byte, error) {
+	var err error
+
+	switch expr := expr.(type) {
+	case *BinaryOp:
+		if b, err = e.appendExpr(b, expr.Left, locals); err != nil {
+			return nil, err
+		}
+
+		if b, err = e.appendExpr(b, expr.Right, locals); err != nil {
+			return nil, err
+		}
+
+		opcode, err := opcode(expr.Type, expr.Op)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(b, opcode), nil
+	case *UnaryOp:
+		if b, err = e.appendExpr(b, expr.Expr, locals); err != nil {
+			return nil, err
+		}
+
+		opcode, err := opcode(expr.Type, expr.Op)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(b, opcode), nil
+	case *Conversion:
+		if b, err = e.appendExpr(b, expr.Expr, locals); err != nil {
+			return nil, err
+		}
+
+		op := OpConvertI32
+		if expr.ResultingType == TypeI32 {
+			op = OpTruncateF64
+		}
+
+		opcode, err := opcode(expr.ResultingType, op)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(b, opcode), nil
+	case *Const:
+		return appendConst(b, expr)
+	case *LocalGet:
+		index, ok := locals[expr.Name]
+		if !ok {
+			return nil, fmt.Errorf("local %s not found", expr.Name)
+		}
+
+		b = append(b, opcodeLocalGet)
+		return appendUleb128(b, uint64(index)), nil
+	case *GlobalGet:
+		index, ok := e.globals[expr.Name]
+		if !ok {
+			return nil, fmt.Errorf("global %s not found", expr.Name)
+		}
+
+		b = append(b, opcodeGlobalGet)
+		return appendUleb128(b, uint64(index)), nil
+	case *Load:
+		if b, err = e.appendExpr(b, expr.Addr, locals); err != nil {
+			return nil, err
+		}
+
+		// Memory argument is an alignment (log2 of the value size) and an offset.
+		switch expr.Type {
+		case TypeI32:
+			return append(b, opcodeI32Load, 2, 0), nil
+		case TypeF64:
+			return append(b, opcodeF64Load, 3, 0), nil
+		default:
+			return nil, fmt.Errorf("unsupported load type %s", expr.Type)
+		}
+	case *FuncCall:
+		index, ok := e.funcs[expr.Name]
+		if !ok {
+			return nil, fmt.Errorf("func %s not found", expr.Name)
+		}
+
+		if b, err = e.appendExprs(b, expr.Args, locals); err != nil {
+			return nil, err
+		}
+
+		b = append(b, opcodeCall)
+		return appendUleb128(b, uint64(index)), nil
+	default:
+		return nil, fmt.Errorf("unsupported expression %T", expr)
+	}
+}
+
+func (e *encoder) appendExprs(b []byte, exprs []Expr, locals map[string]int) ([]byte, error) {
+	for _, expr := range exprs {
+		var err error
+		b, err = e.appendExpr(b, expr, locals)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func appendConst(b []byte, c *Const) ([]byte, error) {
+	switch c.Type {
+	case TypeI32:
+		value, err := strconv.ParseInt(c.Value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid i32 constant %s: %w", c.Value, err)
+		}
+
+		b = append(b, opcodeI32Const)
+		return appendSleb128(b, value), nil
+	case TypeF64:
+		value, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid f64 constant %s: %w", c.Value, err)
+		}
+
+		var bits [8]byte
+		binary.LittleEndian.PutUint64(bits[:], math.Float64bits(value))
+
+		b = append(b, opcodeF64Const)
+		return append(b, bits[:]...), nil
+	default:
+		return nil, fmt.Errorf("unsupported constant type %s", c.Type)
+	}
+}
+
+func opcode(t Type, op Op) (byte, error) {
+	opcodes := opcodesI32
+	if t == TypeF64 {
+		opcodes = opcodesF64
+	}
+
+	opcode, ok := opcodes[op]
+	if !ok {
+		return 0, fmt.Errorf("unsupported operation %s.%s", t, op)
+	}
+
+	return opcode, nil
+}
+
+func valueType(t Type) (byte, error) {
+	b, ok := valueTypes[t]
+	if !ok {
+		return 0, fmt.Errorf("unsupported value type %s", t)
+	}
+
+	return b, nil
+}
+
+// appendName appends a string prefixed with its length.
+func appendName(b []byte, name string) []byte {
+	b = appendUleb128(b, uint64(len(name)))
+	return append(b, name...)
+}
+
+// appendUleb128 appends an unsigned integer in LEB128 encoding.
+func appendUleb128(b []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+
+		if v == 0 {
+			return append(b, c)
+		}
+
+		b = append(b, c|0x80)
+	}
+}
+
+// appendSleb128 appends a signed integer in LEB128 encoding.
+func appendSleb128(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+
+		// Encoding is finished when the remaining bits are the same as the sign bit of the last byte.
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+
+		b = append(b, c|0x80)
+	}
+}
@@ -0,0 +1,185 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModule_Binary(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		name     string
+		expected []byte
+		module   Module
+	}
+
+	tests := []test{
+		{
+			name:     "empty module",
+			expected: []byte{0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00},
+			module:   Module{},
+		},
+		{
+			name: "non-empty module",
+			expected: []byte{
+				0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+				// Type section: (func (param i32)), (func).
+				0x01, 0x08, 0x02, 0x60, 0x01, 0x7F, 0x00, 0x60, 0x00, 0x00,
+				// Import section: "console" "log" (func (type 0)).
+				0x02, 0x0F, 0x01, 0x07, 'c', 'o', 'n', 's', 'o', 'l', 'e', 0x03, 'l', 'o', 'g', 0x00, 0x00,
+				// Function section: main has type 1.
+				0x03, 0x02, 0x01, 0x01,
+				// Global section: (global (mut i32) (i32.const -200)).
+				0x06, 0x07, 0x01, 0x7F, 0x01, 0x41, 0xB8, 0x7E, 0x0B,
+				// Export section: "main" is function 1.
+				0x07, 0x08, 0x01, 0x04, 'm', 'a', 'i', 'n', 0x00, 0x01,
+				// Code section: one i32 local, call $writeln_i32 with (global.get $x + local.get $i).
+				0x0A, 0x0D, 0x01, 0x0B, 0x01, 0x01, 0x7F,
+				0x23, 0x00, 0x20, 0x00, 0x6A, 0x10, 0x00, 0x0B,
+			},
+			module: Module{
+				Imports: []Import{
+					{
+						Path:   []string{"console", "log"},
+						Name:   "writeln_i32",
+						Params: []Param{{"value", "i32"}},
+					},
+				},
+				Globals: []Global{
+					{
+						Name:    "x",
+						Type:    "i32",
+						Value:   "-200",
+						Mutable: true,
+					},
+				},
+				Funcs: []Func{
+					{
+						Name:   "main",
+						Export: true,
+						Locals: []Local{{"i", "i32"}},
+						Body: []Statement{
+							&FuncCall{
+								Name: "writeln_i32",
+								Args: []Expr{&BinaryOp{"i32", OpAdd, &GlobalGet{"x"}, &LocalGet{"i"}}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "module with memory",
+			expected: []byte{
+				0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+				// Type section: (func).
+				0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+				// Function section: main has type 0.
+				0x03, 0x02, 0x01, 0x00,
+				// Memory section: (memory 1).
+				0x05, 0x03, 0x01, 0x00, 0x01,
+				// Export section: "main" is function 0, "memory" is memory 0.
+				0x07, 0x11, 0x02, 0x04, 'm', 'a', 'i', 'n', 0x00, 0x00, 0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+				// Code section: drop (i32.load (i32.const 0)).
+				0x0A, 0x0A, 0x01, 0x08, 0x00, 0x41, 0x00, 0x28, 0x02, 0x00, 0x1A, 0x0B,
+				// Data section: (data (i32.const 4) "hi").
+				0x0B, 0x08, 0x01, 0x00, 0x41, 0x04, 0x0B, 0x02, 'h', 'i',
+			},
+			module: Module{
+				Memory: &Memory{Pages: 1, Export: "memory"},
+				Funcs: []Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []Statement{
+							&Drop{Expr: &Load{"i32", &Const{"i32", "0"}}},
+						},
+					},
+				},
+				Data: []Data{{Offset: 4, Bytes: []byte("hi")}},
+			},
+		},
+		{
+			name: "module with store",
+			expected: []byte{
+				0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+				// Type section: (func).
+				0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+				// Function section: main has type 0.
+				0x03, 0x02, 0x01, 0x00,
+				// Memory section: (memory 1).
+				0x05, 0x03, 0x01, 0x00, 0x01,
+				// Export section: "main" is function 0.
+				0x07, 0x08, 0x01, 0x04, 'm', 'a', 'i', 'n', 0x00, 0x00,
+				// Code section: (i32.store (i32.const 0) (i32.const 7)).
+				0x0A, 0x0B, 0x01, 0x09, 0x00, 0x41, 0x00, 0x41, 0x07, 0x36, 0x02, 0x00, 0x0B,
+			},
+			module: Module{
+				Memory: &Memory{Pages: 1},
+				Funcs: []Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []Statement{
+							&Store{"i32", &Const{"i32", "0"}, &Const{"i32", "7"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := test.module.Binary()
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, b)
+		})
+	}
+}
+
+func TestModule_BinaryErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Module{
+		Funcs: []Func{
+			{
+				Name: "main",
+				Body: []Statement{&LocalSet{Name: "x", Expr: &Const{"i32", "1"}}},
+			},
+		},
+	}.Binary()
+	assert.EqualError(t, err, "func main: local x not found")
+}
+
+func TestLEB128(t *testing.T) {
+	t.Parallel()
+
+	unsigned := map[uint64][]byte{
+		0:      {0x00},
+		127:    {0x7F},
+		128:    {0x80, 0x01},
+		624485: {0xE5, 0x8E, 0x26},
+	}
+	for value, expected := range unsigned {
+		assert.Equal(t, expected, appendUleb128(nil, value), "unsigned %d", value)
+	}
+
+	signed := map[int64][]byte{
+		0:       {0x00},
+		63:      {0x3F},
+		64:      {0xC0, 0x00},
+		-1:      {0x7F},
+		-64:     {0x40},
+		-65:     {0xBF, 0x7F},
+		-123456: {0xC0, 0xBB, 0x78},
+	}
+	for value, expected := range signed {
+		assert.Equal(t, expected, appendSleb128(nil, value), "signed %d", value)
+	}
+}
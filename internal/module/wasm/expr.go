@@ -13,12 +13,23 @@ const (
 	OpAdd Op = "add"
 	OpSub Op = "sub"
 	OpMul Op = "mul"
-	OpDiv Op = "div_s"
+	OpNeg Op = "neg"
+
+	// Math (float only).
+
+	OpDiv Op = "div"
+
+	// Math (int only).
+
+	OpDivSigned Op = "div_s"
+	OpRemSigned Op = "rem_s"
 
 	// Logical.
 
 	OpAnd Op = "and"
+	OpOr  Op = "or"
 	OpXor Op = "xor"
+	OpEqz Op = "eqz"
 
 	// Comparison.
 
@@ -55,23 +66,27 @@ var (
 		token.Plus:     OpAdd,
 		token.Minus:    OpSub,
 		token.Multiply: OpMul,
-		token.Divide:   OpDiv,
-		token.And:      OpAnd,
-		token.Xor:      OpXor,
 		token.Eq:       OpEq,
 		token.Ne:       OpNe,
 	}
 	tokensToWASMOpsInt = map[token.ID]Op{
-		token.Lt:  OpLtSigned,
-		token.Gt:  OpGtSigned,
-		token.Lte: OpLeSigned,
-		token.Gte: OpGeSigned,
+		token.Divide: OpDivSigned,
+		token.Div:    OpDivSigned,
+		token.Mod:    OpRemSigned,
+		token.And:    OpAnd,
+		token.Or:     OpOr,
+		token.Xor:    OpXor,
+		token.Lt:     OpLtSigned,
+		token.Gt:     OpGtSigned,
+		token.Lte:    OpLeSigned,
+		token.Gte:    OpGeSigned,
 	}
 	tokensToWASMOpsFloat = map[token.ID]Op{
-		token.Lt:  OpLt,
-		token.Gt:  OpGt,
-		token.Lte: OpLe,
-		token.Gte: OpGe,
+		token.Divide: OpDiv,
+		token.Lt:     OpLt,
+		token.Gt:     OpGt,
+		token.Lte:    OpLe,
+		token.Gte:    OpGe,
 	}
 )
 
@@ -81,7 +96,7 @@ func MapTokenToWASMOp(t token.ID, builtinType symbol.BuiltinType) (Op, error) {
 	}
 
 	switch builtinType {
-	case symbol.BuiltinTypeInt, symbol.BuiltinTypeBool:
+	case symbol.BuiltinTypeInt, symbol.BuiltinTypeBool, symbol.BuiltinTypeChar, symbol.BuiltinTypeEnum:
 		if op, ok := tokensToWASMOpsInt[t]; ok {
 			return op, nil
 		}
@@ -116,6 +131,16 @@ func (o *BinaryOp) String() string {
 		o.Type, o.Op, o.Left, o.Right)
 }
 
+type UnaryOp struct {
+	Type Type
+	Op   Op
+	Expr Expr
+}
+
+func (o *UnaryOp) String() string {
+	return fmt.Sprintf("(%s.%s %s)", o.Type, o.Op, o.Expr)
+}
+
 type Conversion struct {
 	ResultingType Type
 	Expr          Expr
@@ -156,3 +181,13 @@ type GlobalGet struct {
 func (g *GlobalGet) String() string {
 	return fmt.Sprintf("(global.get $%s)", g.Name)
 }
+
+// Load reads a value of the given type from linear memory.
+type Load struct {
+	Type Type
+	Addr Expr
+}
+
+func (l *Load) String() string {
+	return fmt.Sprintf("(%s.load %s)", l.Type, l.Addr)
+}
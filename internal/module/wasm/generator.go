@@ -2,7 +2,8 @@ package wasm
 
 import (
 	"fmt"
-	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/iskorotkov/compiler/internal/context"
@@ -13,37 +14,229 @@ import (
 	"github.com/iskorotkov/compiler/internal/module/typechecker"
 )
 
-var opsByPriority = []map[token.ID]bool{
-	{
-		token.Xor: true,
-		token.Or:  true,
-	},
-	{
-		token.And: true,
-	},
-	{
-		token.Eq:  true,
-		token.Ne:  true,
-		token.Lt:  true,
-		token.Lte: true,
-		token.Gt:  true,
-		token.Gte: true,
-	},
-	{
-		token.Plus:  true,
-		token.Minus: true,
-	},
-	{
-		token.Multiply: true,
-		token.Divide:   true,
-		token.Mod:      true,
-	},
+// Generator generates WASM modules from type checked programs.
+// It holds the state of the module being generated, so it must not be used for several inputs concurrently.
+type Generator struct {
+	// memory lays out strings, global variables (including arrays and records) and the stack in linear memory.
+	memory *linearMemory
+	// printsStrings is true if the program passes strings to writeln.
+	printsStrings bool
+	// checksBounds is true if the program accesses array elements with bounds checks.
+	checksBounds bool
+	// checksRanges is true if the program assigns values to subrange variables with range checks.
+	checksRanges bool
+	// addressTaken contains variables passed by reference, so they must be stored in memory.
+	addressTaken map[*symbol.Var]bool
+	// slots contains addresses of global variables stored in memory.
+	slots map[*symbol.Var]int
+	// frame describes local variables of the function being generated which are stored on the stack.
+	frame *stackFrame
+	// captured contains variables used by nested functions, so they must be stored in frames of their functions.
+	captured map[*symbol.Var]*symbol.Func
+	// frames contains stack frames of functions which store variables on the stack.
+	// Frames are laid out before code generation, so calls know which functions allocate frames.
+	frames map[*symbol.Func]*stackFrame
+	// displays contains names of globals with frame addresses of the latest calls of functions with captured variables.
+	displays []string
+
+	// BoundsChecks enables runtime checks of array indexes.
+	// Access to an element outside of array bounds traps with the source position of the access.
+	BoundsChecks bool
+	// RangeChecks enables runtime checks of values assigned to subrange variables.
+	// Assignment of a value outside of the range traps with the source position of the assignment.
+	RangeChecks bool
+}
+
+// stackFrame describes local variables of a function which are stored on the stack.
+// Frame is allocated when the function is called and freed when it returns,
+// so each recursive call has its own copy of the variables.
+type stackFrame struct {
+	// base is a name of the local with the frame address.
+	base string
+	// counter is a name of the local used for zeroing arrays (empty if there are no arrays in the frame).
+	counter string
+	// display is a name of the global with the frame address used by nested functions (empty if no variables are captured).
+	display string
+	// savedDisplay is a name of the local with the display value of the previous call, so it's restored on return.
+	savedDisplay string
+	// hasComposites is true if arrays or records are stored in the frame, so they must be zeroed.
+	hasComposites bool
+	vars          []*symbol.Var
+	offsets       map[*symbol.Var]int
+	size          int
 }
 
-type Generator struct{}
+func (f *stackFrame) Size() int {
+	return f.size
+}
+
+// hiddenLocals collects locals which are required by generated code, but aren't declared in the source code.
+type hiddenLocals struct {
+	locals []Local
+}
+
+// Add declares a new local and returns its name.
+// Names contain a dot, so they can't clash with identifiers from the source code.
+func (h *hiddenLocals) Add(prefix string, t Type) string {
+	name := fmt.Sprintf("%s.%d", prefix, len(h.locals))
+	h.locals = append(h.locals, Local{
+		Name: name,
+		Type: t,
+	})
+
+	return name
+}
+
+const (
+	// caseTableMinValues is the minimum number of case label values for which a jump table is used.
+	caseTableMinValues = 3
+	// caseTableMaxSize is the maximum number of entries in a jump table of case operator.
+	caseTableMaxSize = 1024
+)
+
+var (
+	// stackPointer is a name of the global with the address of the stack top.
+	// Name contains a dot, so it can't clash with variables from the source code.
+	stackPointer = "stack.pointer"
+
+	// stackLimit is a name of the global with the lowest address the stack may grow to.
+	stackLimit = "stack.limit"
+
+	// callLine and callCol are names of hidden params of functions with stack frames.
+	// They contain the source position of the call, so stack overflow is reported where it happens.
+	callLine = "call.line"
+	callCol  = "call.col"
+
+	// stackErrorImport reports that the stack frame doesn't fit in the stack with the source position of the call.
+	stackErrorImport = Import{
+		Path: []string{"env", "stack_error"},
+		Name: "stack_error",
+		Params: []Param{
+			{Name: "line", Type: TypeI32},
+			{Name: "col", Type: TypeI32},
+		},
+	}
+
+	// writelnStringImport prints a string with the given address and length of its bytes.
+	writelnStringImport = Import{
+		Path: []string{"env", "writeln_str"},
+		Name: "writeln_str",
+		Params: []Param{
+			{Name: "ptr", Type: TypeI32},
+			{Name: "len", Type: TypeI32},
+		},
+	}
+
+	// boundsErrorImport reports an array index out of bounds with the source position of the access.
+	boundsErrorImport = Import{
+		Path: []string{"env", "bounds_error"},
+		Name: "bounds_error",
+		Params: []Param{
+			{Name: "index", Type: TypeI32},
+			{Name: "line", Type: TypeI32},
+			{Name: "col", Type: TypeI32},
+		},
+	}
+
+	// boundsCheckFunc checks that the index is within bounds and returns its offset from the lower bound.
+	// Out of bounds index is reported to the host, and the module traps.
+	boundsCheckFunc = Func{
+		Name: "bounds.check",
+		Params: []Param{
+			{Name: "index", Type: TypeI32},
+			{Name: "low", Type: TypeI32},
+			{Name: "high", Type: TypeI32},
+			{Name: "line", Type: TypeI32},
+			{Name: "col", Type: TypeI32},
+		},
+		Return: &Return{Type: TypeI32},
+		Body: []Statement{
+			&If{
+				Cond: &BinaryOp{
+					Type:  TypeI32,
+					Op:    OpOr,
+					Left:  &BinaryOp{Type: TypeI32, Op: OpLtSigned, Left: &LocalGet{Name: "index"}, Right: &LocalGet{Name: "low"}},
+					Right: &BinaryOp{Type: TypeI32, Op: OpGtSigned, Left: &LocalGet{Name: "index"}, Right: &LocalGet{Name: "high"}},
+				},
+				TrueBody: []Statement{
+					&FuncCall{
+						Name: boundsErrorImport.Name,
+						Args: []Expr{&LocalGet{Name: "index"}, &LocalGet{Name: "line"}, &LocalGet{Name: "col"}},
+					},
+					&Unreachable{},
+				},
+			},
+			&FuncReturn{
+				Expr: &BinaryOp{Type: TypeI32, Op: OpSub, Left: &LocalGet{Name: "index"}, Right: &LocalGet{Name: "low"}},
+			},
+		},
+	}
+
+	// rangeErrorImport reports a value out of range of the subrange variable with the source position of the assignment.
+	rangeErrorImport = Import{
+		Path: []string{"env", "range_error"},
+		Name: "range_error",
+		Params: []Param{
+			{Name: "value", Type: TypeI32},
+			{Name: "line", Type: TypeI32},
+			{Name: "col", Type: TypeI32},
+		},
+	}
+
+	// rangeCheckFunc checks that the value is within the range and returns it.
+	// Out of range value is reported to the host, and the module traps.
+	rangeCheckFunc = Func{
+		Name: "range.check",
+		Params: []Param{
+			{Name: "value", Type: TypeI32},
+			{Name: "low", Type: TypeI32},
+			{Name: "high", Type: TypeI32},
+			{Name: "line", Type: TypeI32},
+			{Name: "col", Type: TypeI32},
+		},
+		Return: &Return{Type: TypeI32},
+		Body: []Statement{
+			&If{
+				Cond: &BinaryOp{
+					Type:  TypeI32,
+					Op:    OpOr,
+					Left:  &BinaryOp{Type: TypeI32, Op: OpLtSigned, Left: &LocalGet{Name: "value"}, Right: &LocalGet{Name: "low"}},
+					Right: &BinaryOp{Type: TypeI32, Op: OpGtSigned, Left: &LocalGet{Name: "value"}, Right: &LocalGet{Name: "high"}},
+				},
+				TrueBody: []Statement{
+					&FuncCall{
+						Name: rangeErrorImport.Name,
+						Args: []Expr{&LocalGet{Name: "value"}, &LocalGet{Name: "line"}, &LocalGet{Name: "col"}},
+					},
+					&Unreachable{},
+				},
+			},
+			&FuncReturn{Expr: &LocalGet{Name: "value"}},
+		},
+	}
+
+	// writelnStringFunc prints a string stored in linear memory.
+	// Name contains a dot, so it can't clash with functions from the source code.
+	writelnStringFunc = Func{
+		Name:   "writeln.string",
+		Params: []Param{{Name: "s", Type: TypeI32}},
+		Body: []Statement{
+			&FuncCall{
+				Name: writelnStringImport.Name,
+				Args: []Expr{
+					&BinaryOp{Type: TypeI32, Op: OpAdd, Left: &LocalGet{Name: "s"}, Right: &Const{Type: TypeI32, Value: "4"}},
+					&Load{Type: TypeI32, Addr: &LocalGet{Name: "s"}},
+				},
+			},
+		},
+	}
+)
 
 func NewGenerator() *Generator {
-	return &Generator{}
+	return &Generator{
+		BoundsChecks: true,
+		RangeChecks:  true,
+	}
 }
 
 func (g *Generator) Generate(
@@ -59,14 +252,29 @@ func (g *Generator) Generate(
 		defer close(ch)
 
 		for program := range input {
+			// Only errors prevent generation, warnings and notes are reported along with the module.
 			if len(ctx.Errors()) != 0 {
 				ctx.Logger().Infof("WASM generation skipped due to errors")
 				ctx.AddError(context.ErrorSourceCodegen, literal.Position{}, fmt.Errorf("WASM generation skipped due to errors"))
 				return
 			}
 
+			g.memory = newLinearMemory()
+			g.printsStrings = false
+			g.checksBounds = false
+			g.addressTaken = map[*symbol.Var]bool{}
+			g.slots = map[*symbol.Var]int{}
+			g.frame = nil
+			g.captured = map[*symbol.Var]*symbol.Func{}
+			g.frames = map[*symbol.Func]*stackFrame{}
+			g.displays = nil
+
+			g.findAddressTaken(program.Scope, program.Node, program.Funcs)
+			g.findCaptured(program.Funcs, nil)
+			g.findFrames(program.Funcs)
+
 			var globals []Global
-			for _, s := range program.Scope.Symbols() {
+			for _, s := range g.sortedSymbols(program.Scope) {
 				switch s := s.(type) {
 				case *symbol.Type:
 					// We don't declare typedefs in generated code.
@@ -75,13 +283,24 @@ func (g *Generator) Generate(
 					globals = append(globals, Global{
 						Name:    s.Value,
 						Type:    g.convertToWASMType(s.Type.BuiltinType),
-						Value:   s.RawValue,
+						Value:   g.constValue(s.Type.BuiltinType, s.RawValue),
 						Mutable: false,
 					})
 				case *symbol.Func:
-					ctx.Logger().Errorf("%s: function declaration in wasm module is not supported for now", s.Value)
+					// Functions are generated from type checker results.
 					continue
 				case *symbol.Var:
+					// Arrays, records and variables passed by reference are stored in memory instead of WASM globals.
+					if s.Type.IsComposite() {
+						g.slots[s] = g.memory.AddBlock(g.sizeOf(s.Type))
+						continue
+					}
+
+					if g.addressTaken[s] {
+						g.slots[s] = g.memory.AddSlot()
+						continue
+					}
+
 					globals = append(globals, Global{
 						Name:    s.Value,
 						Type:    g.convertToWASMType(s.Type.BuiltinType),
@@ -93,6 +312,10 @@ func (g *Generator) Generate(
 				}
 			}
 
+			mainLocals := &hiddenLocals{}
+			mainBody := g.buildFuncBody(ctx, program.Scope, mainLocals, ast.Operators(program.Node))
+			funcs := g.buildFuncs(ctx, program.Funcs)
+
 			m := Module{
 				Imports: []Import{
 					// Add writeln function that invokes console.log imported from JS.
@@ -122,18 +345,63 @@ func (g *Generator) Generate(
 					},
 				},
 				Globals: globals,
-				Funcs: []Func{
+				Funcs: append([]Func{
 					// Main function (program execution starts here).
 					{
 						Name:   "main",
+						Export: true,
 						Params: nil,
+						Locals: mainLocals.locals,
 						Return: nil,
-						Body:   g.buildFuncBody(ctx, program.Scope, program.Node),
+						Body:   mainBody,
 					},
-					// TODO: Add support for other functions
-				},
+				}, funcs...),
+			}
+
+			for _, display := range g.displays {
+				m.Globals = append(m.Globals, Global{
+					Name:    display,
+					Type:    TypeI32,
+					Value:   "0",
+					Mutable: true,
+				})
+			}
+
+			if top := g.memory.StackTop(); top != 0 {
+				m.Globals = append(m.Globals,
+					Global{
+						Name:    stackPointer,
+						Type:    TypeI32,
+						Value:   strconv.Itoa(top),
+						Mutable: true,
+					},
+					Global{
+						Name:  stackLimit,
+						Type:  TypeI32,
+						Value: strconv.Itoa(g.memory.StackLimit()),
+					},
+				)
+				m.Imports = append(m.Imports, stackErrorImport)
+			}
+
+			if g.printsStrings {
+				m.Imports = append(m.Imports, writelnStringImport)
+				m.Funcs = append(m.Funcs, writelnStringFunc)
+			}
+
+			if g.checksBounds {
+				m.Imports = append(m.Imports, boundsErrorImport)
+				m.Funcs = append(m.Funcs, boundsCheckFunc)
+			}
+
+			if g.checksRanges {
+				m.Imports = append(m.Imports, rangeErrorImport)
+				m.Funcs = append(m.Funcs, rangeCheckFunc)
 			}
 
+			m.Memory = g.memory.Memory()
+			m.Data = g.memory.Data()
+
 			ch <- m
 		}
 	}()
@@ -141,19 +409,390 @@ func (g *Generator) Generate(
 	return ch
 }
 
+func (g *Generator) buildFuncs(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+	},
+	funcs []typechecker.FuncResult,
+) []Func {
+	var res []Func
+	for _, f := range funcs {
+		params := map[string]bool{}
+
+		var wasmParams []Param
+		for _, param := range f.Symbol.Params {
+			params[param.Value] = true
+
+			// Params passed by reference are addresses of variables.
+			paramType := g.convertToWASMType(param.Type.BuiltinType)
+			if param.ByRef {
+				paramType = TypeI32
+			}
+
+			wasmParams = append(wasmParams, Param{
+				Name: param.Value,
+				Type: paramType,
+			})
+		}
+
+		hidden := &hiddenLocals{}
+		g.frame = g.frames[f.Symbol]
+		if g.frame != nil {
+			g.declareFrameLocals(hidden)
+			wasmParams = append(wasmParams, Param{Name: callLine, Type: TypeI32}, Param{Name: callCol, Type: TypeI32})
+		}
+
+		// Function result is stored in a local variable with the function name.
+		var locals []Local
+		for _, s := range g.sortedSymbols(f.Scope) {
+			if v, ok := s.(*symbol.Var); ok && !params[v.Value] && !g.onStack(v) {
+				locals = append(locals, Local{
+					Name: v.Value,
+					Type: g.convertToWASMType(v.Type.BuiltinType),
+				})
+			}
+		}
+
+		body := append(g.framePrologue(params), g.buildFuncBody(ctx, f.Scope, hidden, ast.Operators(f.Node))...)
+		body = append(body, g.frameEpilogue()...)
+
+		// Procedures don't return values.
+		var wasmReturn *Return
+		if !f.Symbol.IsProcedure() {
+			var result Expr = &LocalGet{Name: f.Symbol.Value}
+			if addr, t, ok := g.varAddr(f.Scope, f.Symbol.Value); ok {
+				result = &Load{Type: t, Addr: addr}
+			}
+
+			wasmReturn = &Return{Type: g.convertToWASMType(f.Symbol.ReturnType.BuiltinType)}
+			body = append(body, &FuncReturn{
+				Expr: result,
+			})
+		}
+
+		res = append(res, Func{
+			Name:   f.Symbol.Value,
+			Params: wasmParams,
+			Locals: append(locals, hidden.locals...),
+			Return: wasmReturn,
+			Body:   body,
+		})
+
+		// Nested functions are declared at the module level.
+		res = append(res, g.buildFuncs(ctx, f.Funcs)...)
+	}
+
+	return res
+}
+
+// findCaptured collects variables of enclosing functions used by nested functions.
+// Enclosing functions are passed from the outermost to the innermost one.
+func (g *Generator) findCaptured(funcs []typechecker.FuncResult, enclosing []typechecker.FuncResult) {
+	for _, f := range funcs {
+		g.capture(f.Scope, ast.Operators(f.Node), enclosing)
+		g.findCaptured(f.Funcs, append(enclosing[:len(enclosing):len(enclosing)], f))
+	}
+}
+
+// capture marks variables used in the node as captured if they are declared in one of enclosing functions.
+func (g *Generator) capture(scope symbol.Scope, node ast.Node, enclosing []typechecker.FuncResult) {
+	if b, ok := node.(*ast.Branch); ok {
+		for _, item := range b.Items {
+			g.capture(scope, item, enclosing)
+		}
+
+		return
+	}
+
+	leaf, ok := node.(*ast.Leaf)
+	if !ok || leaf.ID != token.UserDefined || leaf.Has(ast.MarkerField) {
+		return
+	}
+
+	if _, ok := scope.LookupLocal(&symbol.Name{Name: leaf.Value}); ok {
+		return
+	}
+
+	s, ok := scope.Lookup(&symbol.Name{Name: leaf.Value})
+	if !ok {
+		return
+	}
+
+	v, ok := s.(*symbol.Var)
+	if !ok {
+		return
+	}
+
+	// The innermost function declaring the variable owns it.
+	for i := len(enclosing) - 1; i >= 0; i-- {
+		if local, ok := enclosing[i].Scope.LookupLocal(v); ok && local == s {
+			g.captured[v] = enclosing[i].Symbol
+			return
+		}
+	}
+}
+
+// findAddressTaken collects variables passed by reference in the block and in nested functions.
+func (g *Generator) findAddressTaken(scope symbol.Scope, node ast.Node, funcs []typechecker.FuncResult) {
+	for _, call := range ast.Operators(node).Query(ast.QueryTypeRecursive, ast.MarkerFuncCall) {
+		name := call.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
+
+		f, ok := scope.LookupFunc(name.Value)
+		if !ok {
+			continue
+		}
+
+		for i, arg := range ast.CallArgs(call) {
+			if i >= len(f.Params) || !f.Params[i].ByRef {
+				continue
+			}
+
+			// Params passed by reference already contain addresses.
+			if v, err := g.refVar(scope, arg); err == nil && !v.ByRef {
+				g.addressTaken[v] = true
+			}
+		}
+	}
+
+	for _, f := range funcs {
+		g.findAddressTaken(f.Scope, f.Node, f.Funcs)
+	}
+}
+
+// findFrames lays out stack frames of functions and nested functions.
+func (g *Generator) findFrames(funcs []typechecker.FuncResult) {
+	for _, f := range funcs {
+		if frame := g.newStackFrame(f.Symbol, f.Scope); frame != nil {
+			g.frames[f.Symbol] = frame
+		}
+
+		g.findFrames(f.Funcs)
+	}
+}
+
+// newStackFrame lays out local arrays, records, variables passed by reference and variables used by nested functions on the stack.
+// It returns nil if all variables are stored in WASM locals.
+func (g *Generator) newStackFrame(f *symbol.Func, scope symbol.Scope) *stackFrame {
+	frame := &stackFrame{
+		offsets: map[*symbol.Var]int{},
+	}
+
+	hasComposites := false
+	for _, s := range g.sortedSymbols(scope) {
+		v, ok := s.(*symbol.Var)
+		if !ok {
+			continue
+		}
+
+		// Array and record params passed by reference already contain addresses.
+		isComposite := v.Type.IsComposite() && !v.ByRef
+		if !isComposite && !g.addressTaken[v] && g.captured[v] == nil {
+			continue
+		}
+
+		size := slotSize
+		if isComposite {
+			hasComposites = true
+			size = alignTo(g.sizeOf(v.Type), slotSize)
+		}
+
+		frame.offsets[v] = frame.size
+		frame.vars = append(frame.vars, v)
+		frame.size += size
+	}
+
+	if len(frame.vars) == 0 {
+		return nil
+	}
+
+	g.memory.UseStack(frame.Size())
+	frame.hasComposites = hasComposites
+
+	for _, v := range frame.vars {
+		if g.captured[v] == f {
+			frame.display = "display." + f.Value
+			g.displays = append(g.displays, frame.display)
+			break
+		}
+	}
+
+	return frame
+}
+
+// declareFrameLocals declares locals used by the prologue and the epilogue of the current frame.
+func (g *Generator) declareFrameLocals(locals *hiddenLocals) {
+	g.frame.base = locals.Add("frame", TypeI32)
+
+	if g.frame.hasComposites {
+		g.frame.counter = locals.Add("frame.counter", TypeI32)
+	}
+
+	if g.frame.display != "" {
+		g.frame.savedDisplay = locals.Add("display.saved", TypeI32)
+	}
+}
+
+func (g *Generator) onStack(v *symbol.Var) bool {
+	if g.frame == nil {
+		return false
+	}
+
+	_, ok := g.frame.offsets[v]
+	return ok
+}
+
+// framePrologue allocates the stack frame and initializes variables stored in it.
+// Params are copied to the frame, and other variables are set to default values.
+func (g *Generator) framePrologue(params map[string]bool) []Statement {
+	if g.frame == nil {
+		return nil
+	}
+
+	statements := []Statement{
+		&GlobalSet{Name: stackPointer, Expr: &BinaryOp{
+			Type:  TypeI32,
+			Op:    OpSub,
+			Left:  &GlobalGet{Name: stackPointer},
+			Right: &Const{Type: TypeI32, Value: strconv.Itoa(g.frame.Size())},
+		}},
+		// Stack is located right after global variables, so stack overflow is reported instead of overwriting them.
+		&If{
+			Cond: &BinaryOp{
+				Type:  TypeI32,
+				Op:    OpLtSigned,
+				Left:  &GlobalGet{Name: stackPointer},
+				Right: &GlobalGet{Name: stackLimit},
+			},
+			TrueBody: []Statement{
+				&FuncCall{
+					Name: stackErrorImport.Name,
+					Args: []Expr{&LocalGet{Name: callLine}, &LocalGet{Name: callCol}},
+				},
+				&Unreachable{},
+			},
+		},
+		&LocalSet{Name: g.frame.base, Expr: &GlobalGet{Name: stackPointer}},
+	}
+
+	// Nested functions find captured variables by the frame address of the latest call of the function.
+	if g.frame.display != "" {
+		statements = append(statements,
+			&LocalSet{Name: g.frame.savedDisplay, Expr: &GlobalGet{Name: g.frame.display}},
+			&GlobalSet{Name: g.frame.display, Expr: &LocalGet{Name: g.frame.base}},
+		)
+	}
+
+	for _, v := range g.frame.vars {
+		if v.Type.IsComposite() && !v.ByRef {
+			statements = append(statements, g.zeroMemory(v)...)
+			continue
+		}
+
+		// Captured params passed by reference are stored as addresses.
+		if v.ByRef {
+			statements = append(statements, &Store{Type: TypeI32, Addr: g.frameAddr(v), Value: &LocalGet{Name: v.Value}})
+			continue
+		}
+
+		t := g.convertToWASMType(v.Type.BuiltinType)
+
+		var value Expr = &Const{Type: t, Value: g.defaultValue(v.Type.BuiltinType)}
+		if params[v.Value] {
+			value = &LocalGet{Name: v.Value}
+		}
+
+		statements = append(statements, &Store{Type: t, Addr: g.frameAddr(v), Value: value})
+	}
+
+	return statements
+}
+
+// zeroMemory fills the array or the record stored in the frame with zeros 8 bytes at a time.
+// Default values of all types are zeros, so it initializes all elements and fields.
+func (g *Generator) zeroMemory(v *symbol.Var) []Statement {
+	size := alignTo(g.sizeOf(v.Type), slotSize)
+	counter := &LocalGet{Name: g.frame.counter}
+
+	return []Statement{
+		&LocalSet{Name: g.frame.counter, Expr: &Const{Type: TypeI32, Value: strconv.Itoa(size)}},
+		&Block{
+			Body: []Statement{
+				&Loop{
+					Body: []Statement{
+						&BrIf{Label: 1, Cond: &UnaryOp{Type: TypeI32, Op: OpEqz, Expr: counter}},
+						&LocalSet{Name: g.frame.counter, Expr: &BinaryOp{
+							Type:  TypeI32,
+							Op:    OpSub,
+							Left:  counter,
+							Right: &Const{Type: TypeI32, Value: strconv.Itoa(slotSize)},
+						}},
+						&Store{
+							Type:  TypeF64,
+							Addr:  &BinaryOp{Type: TypeI32, Op: OpAdd, Left: g.frameAddr(v), Right: counter},
+							Value: &Const{Type: TypeF64, Value: "0.0"},
+						},
+						&Br{Label: 0},
+					},
+				},
+			},
+		},
+	}
+}
+
+// frameEpilogue frees the stack frame and restores the display of the previous call.
+func (g *Generator) frameEpilogue() []Statement {
+	if g.frame == nil {
+		return nil
+	}
+
+	statements := []Statement{
+		&GlobalSet{Name: stackPointer, Expr: &BinaryOp{
+			Type:  TypeI32,
+			Op:    OpAdd,
+			Left:  &LocalGet{Name: g.frame.base},
+			Right: &Const{Type: TypeI32, Value: strconv.Itoa(g.frame.Size())},
+		}},
+	}
+
+	if g.frame.display != "" {
+		statements = append(statements, &GlobalSet{Name: g.frame.display, Expr: &LocalGet{Name: g.frame.savedDisplay}})
+	}
+
+	return statements
+}
+
+func (g *Generator) frameAddr(v *symbol.Var) Expr {
+	return &BinaryOp{
+		Type:  TypeI32,
+		Op:    OpAdd,
+		Left:  &LocalGet{Name: g.frame.base},
+		Right: &Const{Type: TypeI32, Value: strconv.Itoa(g.frame.offsets[v])},
+	}
+}
+
 func (g *Generator) buildFuncBody(
 	ctx interface {
 		context.LoggerContext
+		context.ErrorsContext
 	},
 	scope symbol.Scope,
+	locals *hiddenLocals,
 	node ast.Node,
 ) []Statement {
+	// Empty operators have no blocks.
+	if node == nil {
+		return nil
+	}
+
 	nodes := node.Query(ast.QueryTypeTop,
 		ast.MarkerAssign,
 		ast.MarkerIf,
 		ast.MarkerFor,
 		ast.MarkerWhile,
 		ast.MarkerRepeat,
+		ast.MarkerWith,
+		ast.MarkerCase,
 		ast.MarkerFuncCall,
 	)
 
@@ -162,60 +801,69 @@ func (g *Generator) buildFuncBody(
 		switch {
 		case node.Has(ast.MarkerAssign):
 			variable := node.
-				Query(ast.QueryTypeOne, ast.MarkerLeftSide)[0].(*ast.Leaf)
+				Query(ast.QueryTypeOne, ast.MarkerLeftSide)[0]
 
 			expr := node.
 				Query(ast.QueryTypeOne, ast.MarkerRightSide)[0].
 				Query(ast.QueryTypeOne, ast.MarkerExpr)[0]
 
-			wasmExpr, _, err := g.buildExpression(scope, expr)
+			wasmExpr, exprType, err := g.buildExpression(scope, expr)
 			if err != nil {
-				ctx.Logger().Errorf("%s: %s", variable.Value, err)
+				ctx.AddError(context.ErrorSourceCodegen, node.Position(), err)
 				continue
 			}
 
-			statements = append(statements, &GlobalSet{
-				Name: variable.Value,
-				Expr: wasmExpr,
-			})
+			set, err := g.buildAssignment(scope, variable, wasmExpr, exprType)
+			if err != nil {
+				ctx.AddError(context.ErrorSourceCodegen, node.Position(), err)
+				continue
+			}
+
+			statements = append(statements, set)
 		case node.Has(ast.MarkerIf):
 			expr := node.
 				Query(ast.QueryTypeOne, ast.MarkerExpr)[0]
 
 			wasmExpr, _, err := g.buildExpression(scope, expr)
 			if err != nil {
-				ctx.Logger().Errorf("%v: %s", expr, err)
+				ctx.AddError(context.ErrorSourceCodegen, expr.Position(), err)
 				continue
 			}
 
-			operators := node.
-				Query(ast.QueryTypeTop, ast.MarkerBlock)
-
-			var falseBody []Statement
-			if len(operators) > 1 {
-				falseBody = g.buildFuncBody(ctx, scope, operators[1])
-			}
+			then, otherwise := ast.IfBlocks(node)
 
 			statements = append(statements, &If{
 				Cond:      wasmExpr,
-				TrueBody:  g.buildFuncBody(ctx, scope, operators[0]),
-				FalseBody: falseBody,
+				TrueBody:  g.buildFuncBody(ctx, scope, locals, then),
+				FalseBody: g.buildFuncBody(ctx, scope, locals, otherwise),
 			})
+		case node.Has(ast.MarkerCase):
+			c, err := g.buildCase(ctx, scope, locals, node)
+			if err != nil {
+				ctx.AddError(context.ErrorSourceCodegen, node.Position(), err)
+				continue
+			}
+
+			statements = append(statements, c...)
 		case node.Has(ast.MarkerFor):
-			// TODO: Add support for for loops.
-			panic("for loop is not supported yet")
+			loop, err := g.buildFor(ctx, scope, locals, node)
+			if err != nil {
+				ctx.AddError(context.ErrorSourceCodegen, node.Position(), err)
+				continue
+			}
+
+			statements = append(statements, loop...)
 		case node.Has(ast.MarkerWhile):
 			expr := node.
 				Query(ast.QueryTypeOne, ast.MarkerExpr)[0]
 
 			wasmExpr, _, err := g.buildExpression(scope, expr)
 			if err != nil {
-				ctx.Logger().Errorf("%v: %s", expr, err)
+				ctx.AddError(context.ErrorSourceCodegen, expr.Position(), err)
 				continue
 			}
 
-			body := node.
-				Query(ast.QueryTypeOne, ast.MarkerBlock)[0]
+			body := ast.Body(node)
 
 			// We use a loop to implement while loop.
 			// We need to use If to skip loop entirely if condition is false.
@@ -225,36 +873,49 @@ func (g *Generator) buildFuncBody(
 				TrueBody: []Statement{
 					&Loop{
 						BranchCond: wasmExpr,
-						Body:       g.buildFuncBody(ctx, scope, body),
+						Body:       g.buildFuncBody(ctx, scope, locals, body),
 					},
 				},
 			})
 		case node.Has(ast.MarkerRepeat):
-			// TODO: Add support for repeat loops.
-			panic("repeat loop is not supported yet")
-		case node.Has(ast.MarkerFuncCall):
-			name := node.Query(ast.QueryTypeOne, ast.MarkerName)[0].(*ast.Leaf)
-			if name.Value != "writeln" {
-				panic("only writeln function is supported")
-			}
+			expr := node.
+				Query(ast.QueryTypeOne, ast.MarkerRepeatExpr)[0]
 
-			args := node.Query(ast.QueryTypeTop, ast.MarkerFuncArg)
-			if len(args) != 1 {
-				panic("only one argument is supported")
+			wasmExpr, _, err := g.buildExpression(scope, expr)
+			if err != nil {
+				ctx.AddError(context.ErrorSourceCodegen, expr.Position(), err)
+				continue
 			}
 
-			argExpr, builtinType, err := g.buildExpression(scope, args[0])
+			body := ast.Body(node)
+
+			// Repeat loop executes its body at least once, so we don't need to wrap it with If.
+			// Loop continues while the condition is false, so we invert it.
+			statements = append(statements, &Loop{
+				BranchCond: &UnaryOp{
+					Type: TypeI32,
+					Op:   OpEqz,
+					Expr: wasmExpr,
+				},
+				Body: g.buildFuncBody(ctx, scope, locals, body),
+			})
+		case node.Has(ast.MarkerWith):
+			// Fields in the block are replaced with selectors of record variables by the type checker.
+			statements = append(statements, g.buildFuncBody(ctx, scope, locals, ast.Body(node))...)
+		case node.Has(ast.MarkerFuncCall):
+			call, returnType, err := g.buildCall(scope, node)
 			if err != nil {
-				ctx.Logger().Errorf("%v: %s", args[0], err)
+				ctx.AddError(context.ErrorSourceCodegen, node.Position(), err)
 				continue
 			}
 
-			funcName := fmt.Sprintf("%s_%s", name.Value, g.convertToWASMType(builtinType))
+			// Function result must be dropped if the function is used as an operator.
+			if returnType != symbol.BuiltinTypeVoid {
+				statements = append(statements, &Drop{Expr: call})
+				continue
+			}
 
-			statements = append(statements, &FuncCall{
-				Name: funcName,
-				Args: []Expr{argExpr},
-			})
+			statements = append(statements, call)
 		default:
 			panic("unknown node marker")
 		}
@@ -263,177 +924,856 @@ func (g *Generator) buildFuncBody(
 	return statements
 }
 
-type exprTree struct {
-	builtinType symbol.BuiltinType
-	node        *ast.Leaf
-	left, right *exprTree
-}
-
-func (e exprTree) Leaf() bool {
-	return e.left == nil && e.right == nil
-}
-
-func (g *Generator) buildExpression(scope symbol.Scope, node ast.Node) (Expr, symbol.BuiltinType, error) {
-	leafs := g.linearizeExpression(node)
-	tree := g.buildExprTree(scope, leafs)
-	expr, err := g.convertToWASMExpr(scope, *tree)
-	return expr, tree.builtinType, err
-}
-
-func (g *Generator) convertToWASMExpr(scope symbol.Scope, tree exprTree) (Expr, error) {
-	if tree.Leaf() {
-		if tree.node.ID == token.UserDefined {
-			// TODO: Add support for function params and locals.
-			return &GlobalGet{
-				Name: tree.node.Value,
-			}, nil
-		}
-
-		switch tree.builtinType {
-		case symbol.BuiltinTypeBool:
-			value := 0
-			if tree.node.Value == "true" {
-				value = 1
-			}
+// buildFor lowers for loop to a block with a loop inside.
+// Bounds are evaluated once before the loop, and the loop is skipped entirely if the range is empty.
+// Control variable is compared with the final value before it's incremented (or decremented),
+// so it doesn't overflow when the final value is the largest (or the smallest) int.
+func (g *Generator) buildFor(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+	},
+	scope symbol.Scope,
+	locals *hiddenLocals,
+	node ast.Node,
+) ([]Statement, error) {
+	header := node.Query(ast.QueryTypeOne, ast.MarkerForHeader)[0]
+	variable := header.Query(ast.QueryTypeOne, ast.MarkerForVar)[0].(*ast.Leaf)
+	direction := header.Query(ast.QueryTypeOne, ast.MarkerForDirection)[0].(*ast.Leaf)
+	expressions := header.Query(ast.QueryTypeTop, ast.MarkerExpr)
 
-			return &Const{
-				Type:  TypeI32,
-				Value: fmt.Sprint(value),
-			}, nil
-		case symbol.BuiltinTypeInt:
-			return &Const{
-				Type:  TypeI32,
-				Value: tree.node.Value,
-			}, nil
-		case symbol.BuiltinTypeDouble:
-			return &Const{
-				Type:  TypeF64,
-				Value: tree.node.Value,
-			}, nil
-		default:
-			panic("unexpected token id")
-		}
+	from, _, err := g.buildExpression(scope, expressions[0])
+	if err != nil {
+		return nil, err
 	}
 
-	left, err := g.convertToWASMExpr(scope, *tree.left)
+	to, _, err := g.buildExpression(scope, expressions[1])
 	if err != nil {
 		return nil, err
 	}
 
-	right, err := g.convertToWASMExpr(scope, *tree.right)
+	get, err := g.buildVarGet(scope, variable.Value)
 	if err != nil {
 		return nil, err
 	}
 
-	left, right, exprType := g.wrapWithTypeConversions(left, right, tree)
+	first := locals.Add("for.first", TypeI32)
+	last := locals.Add("for.last", TypeI32)
 
-	op, err := MapTokenToWASMOp(tree.node.ID, exprType)
+	init, err := g.buildVarSet(scope, variable.Value, &LocalGet{Name: first})
 	if err != nil {
 		return nil, err
 	}
 
-	return &BinaryOp{
-		Type:  g.convertToWASMType(exprType),
-		Op:    op,
-		Left:  left,
-		Right: right,
+	skipOp, stepOp := OpGtSigned, OpAdd
+	if direction.ID == token.Downto {
+		skipOp, stepOp = OpLtSigned, OpSub
+	}
+
+	step, err := g.buildVarSet(scope, variable.Value, &BinaryOp{
+		Type:  TypeI32,
+		Op:    stepOp,
+		Left:  get,
+		Right: &Const{Type: TypeI32, Value: "1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body := g.buildFuncBody(ctx, scope, locals, ast.Body(node))
+	body = append(body,
+		// Exit the enclosing block after the iteration with the final value.
+		&BrIf{
+			Label: 1,
+			Cond: &BinaryOp{
+				Type:  TypeI32,
+				Op:    OpEq,
+				Left:  get,
+				Right: &LocalGet{Name: last},
+			},
+		},
+		step,
+		&Br{Label: 0},
+	)
+
+	return []Statement{
+		&LocalSet{Name: first, Expr: from},
+		&LocalSet{Name: last, Expr: to},
+		init,
+		&Block{
+			Body: []Statement{
+				&BrIf{
+					Label: 0,
+					Cond: &BinaryOp{
+						Type:  TypeI32,
+						Op:    skipOp,
+						Left:  get,
+						Right: &LocalGet{Name: last},
+					},
+				},
+				&Loop{Body: body},
+			},
+		},
 	}, nil
 }
 
-func (g *Generator) wrapWithTypeConversions(left, right Expr, tree exprTree) (Expr, Expr, symbol.BuiltinType) {
-	if tree.left.builtinType != symbol.BuiltinTypeDouble && tree.right.builtinType == symbol.BuiltinTypeDouble {
-		return &Conversion{
-			ResultingType: TypeF64,
-			Expr:          left,
-		}, right, symbol.BuiltinTypeDouble
+// buildCase lowers case operator to a jump table if labels are dense, or to a chain of ifs otherwise.
+func (g *Generator) buildCase(
+	ctx interface {
+		context.LoggerContext
+		context.ErrorsContext
+	},
+	scope symbol.Scope,
+	locals *hiddenLocals,
+	node ast.Node,
+) ([]Statement, error) {
+	selector, _, err := g.buildExpression(scope, node.Query(ast.QueryTypeOne, ast.MarkerCaseExpr)[0])
+	if err != nil {
+		return nil, err
 	}
 
-	if tree.left.builtinType == symbol.BuiltinTypeDouble && tree.right.builtinType != symbol.BuiltinTypeDouble {
-		return left, &Conversion{
-			ResultingType: TypeF64,
-			Expr:          right,
-		}, symbol.BuiltinTypeDouble
+	branches, elseBlock, err := typechecker.CaseBranches(scope, node)
+	if err != nil {
+		return nil, err
+	}
+
+	bodies := make([][]Statement, len(branches))
+	for i, branch := range branches {
+		if branch.Block != nil {
+			bodies[i] = g.buildFuncBody(ctx, scope, locals, branch.Block)
+		}
+	}
+
+	var elseBody []Statement
+	if elseBlock != nil {
+		elseBody = g.buildFuncBody(ctx, scope, locals, elseBlock)
+	}
+
+	if low, high, ok := denseLabels(branches); ok {
+		return g.buildCaseTable(selector, branches, bodies, elseBody, low, high), nil
+	}
+
+	value := locals.Add("case", TypeI32)
+
+	// Chain is built from the last branch, so each branch is checked in the else body of the previous one.
+	chain := elseBody
+	for i := len(branches) - 1; i >= 0; i-- {
+		var cond Expr
+		for _, label := range branches[i].Labels {
+			labelCond := caseLabelCond(value, label)
+			if cond != nil {
+				labelCond = &BinaryOp{Type: TypeI32, Op: OpOr, Left: cond, Right: labelCond}
+			}
+
+			cond = labelCond
+		}
+
+		chain = []Statement{&If{Cond: cond, TrueBody: bodies[i], FalseBody: chain}}
 	}
 
-	return left, right, tree.left.builtinType
+	return append([]Statement{&LocalSet{Name: value, Expr: selector}}, chain...), nil
 }
 
-func (g *Generator) buildExprTree(scope symbol.Scope, leafs []*ast.Leaf) *exprTree {
-	if len(leafs) == 1 {
-		var builtinType symbol.BuiltinType
-		switch leafs[0].ID {
-		case token.BoolLiteral:
-			builtinType = symbol.BuiltinTypeBool
-		case token.IntLiteral:
-			builtinType = symbol.BuiltinTypeInt
-		case token.DoubleLiteral:
-			builtinType = symbol.BuiltinTypeDouble
-		case token.UserDefined:
-			s, ok := scope.Lookup(&symbol.Name{Name: leafs[0].Value})
-			if !ok {
-				panic(fmt.Sprintf("%s: symbol not found", leafs[0].Value))
+// buildCaseTable lowers case operator to nested blocks with a jump table in the innermost one.
+// Each branch is placed after the end of its block, and it exits the outermost block when it's done.
+// Values without labels and values out of range of labels jump to the else branch.
+func (g *Generator) buildCaseTable(selector Expr, branches []typechecker.CaseBranch, bodies [][]Statement, elseBody []Statement, low, high int) []Statement {
+	// Block of branch i has depth i from the jump table, the else block has depth len(branches).
+	elseLabel := len(branches)
+
+	labels := make([]int, high-low+1)
+	for i := range labels {
+		labels[i] = elseLabel
+	}
+
+	for i, branch := range branches {
+		for _, label := range branch.Labels {
+			for v := label.Low; v <= label.High; v++ {
+				labels[v-low] = i
+			}
+		}
+	}
+
+	var index Expr = selector
+	if low != 0 {
+		index = &BinaryOp{Type: TypeI32, Op: OpSub, Left: selector, Right: &Const{Type: TypeI32, Value: strconv.Itoa(low)}}
+	}
+
+	inner := []Statement{&BrTable{Labels: labels, Default: elseLabel, Index: index}}
+	for i := range branches {
+		// Branch i is followed by blocks of the next branches and the else block, so the outermost block has depth len(branches)-i.
+		inner = append([]Statement{&Block{Body: inner}}, bodies[i]...)
+		inner = append(inner, &Br{Label: len(branches) - i})
+	}
+
+	inner = append([]Statement{&Block{Body: inner}}, elseBody...)
+
+	return []Statement{&Block{Body: inner}}
+}
+
+// denseLabels checks whether a jump table for case labels is small enough, and returns the range of label values.
+func denseLabels(branches []typechecker.CaseBranch) (int, int, bool) {
+	values := 0
+	low, high := 0, 0
+	for _, branch := range branches {
+		for _, label := range branch.Labels {
+			if values == 0 || label.Low < low {
+				low = label.Low
 			}
 
-			switch s := s.(type) {
-			case *symbol.Const:
-				builtinType = s.Type.BuiltinType
-			case *symbol.Var:
-				builtinType = s.Type.BuiltinType
-			case *symbol.Func:
-				panic("function call not implemented yet")
-			default:
-				panic("unknown symbol type")
+			if values == 0 || label.High > high {
+				high = label.High
 			}
-		default:
-			panic(fmt.Sprintf("unexpected token id: %s", leafs[0].ID))
+
+			values += label.High - label.Low + 1
 		}
+	}
 
-		return &exprTree{
-			builtinType: builtinType,
-			node:        leafs[0],
+	if values < caseTableMinValues {
+		return 0, 0, false
+	}
+
+	size := high - low + 1
+	return low, high, size <= caseTableMaxSize && size <= values*2
+}
+
+// caseLabelCond returns a condition which is true if the value matches the case label.
+func caseLabelCond(value string, label typechecker.CaseLabel) Expr {
+	if label.Low == label.High {
+		return &BinaryOp{Type: TypeI32, Op: OpEq, Left: &LocalGet{Name: value}, Right: &Const{Type: TypeI32, Value: strconv.Itoa(label.Low)}}
+	}
+
+	return &BinaryOp{
+		Type:  TypeI32,
+		Op:    OpAnd,
+		Left:  &BinaryOp{Type: TypeI32, Op: OpGeSigned, Left: &LocalGet{Name: value}, Right: &Const{Type: TypeI32, Value: strconv.Itoa(label.Low)}},
+		Right: &BinaryOp{Type: TypeI32, Op: OpLeSigned, Left: &LocalGet{Name: value}, Right: &Const{Type: TypeI32, Value: strconv.Itoa(label.High)}},
+	}
+}
+
+// buildAssignment writes the expression to the variable or to the array element.
+func (g *Generator) buildAssignment(scope symbol.Scope, variable ast.Node, expr Expr, exprType symbol.BuiltinType) (Statement, error) {
+	tree, err := ast.NewExpr(variable)
+	if err != nil {
+		return nil, err
+	}
+
+	if tree.HasSelectors() {
+		addr, t, err := g.buildElemAddr(scope, tree)
+		if err != nil {
+			return nil, err
 		}
+
+		return &Store{
+			Type:  g.convertToWASMType(t.BuiltinType),
+			Addr:  addr,
+			Value: g.checkRange(g.convertExpr(expr, exprType, t.BuiltinType), t, variable.Position()),
+		}, nil
 	}
 
-	for _, ops := range opsByPriority {
-		for i, leaf := range leafs {
-			if !ops[leaf.ID] {
-				continue
+	s, ok := scope.Lookup(&symbol.Name{Name: tree.Leaf.Value})
+	if !ok {
+		return nil, fmt.Errorf("%s: symbol not found", tree.Leaf.Value)
+	}
+
+	t := s.(*symbol.Var).Type
+
+	return g.buildVarSet(scope, tree.Leaf.Value, g.checkRange(g.convertExpr(expr, exprType, t.BuiltinType), t, variable.Position()))
+}
+
+// checkRange wraps the value assigned to the variable of subrange type with a range check.
+// Constants are checked by the type checker, so they are used as is.
+func (g *Generator) checkRange(value Expr, t symbol.Type, pos literal.Position) Expr {
+	if t.Range == nil || !g.RangeChecks {
+		return value
+	}
+
+	if _, ok := value.(*Const); ok {
+		return value
+	}
+
+	g.checksRanges = true
+
+	return &FuncCall{
+		Name: rangeCheckFunc.Name,
+		Args: []Expr{
+			value,
+			&Const{Type: TypeI32, Value: strconv.Itoa(t.Range.Low)},
+			&Const{Type: TypeI32, Value: strconv.Itoa(t.Range.High)},
+			&Const{Type: TypeI32, Value: strconv.Itoa(int(pos.Line))},
+			&Const{Type: TypeI32, Value: strconv.Itoa(int(pos.StartCol))},
+		},
+	}
+}
+
+func (g *Generator) buildExpression(scope symbol.Scope, node ast.Node) (Expr, symbol.BuiltinType, error) {
+	tree, err := ast.NewExpr(node)
+	if err != nil {
+		return nil, symbol.BuiltinTypeUnknown, err
+	}
+
+	return g.convertToWASMExpr(scope, tree)
+}
+
+func (g *Generator) buildCall(scope symbol.Scope, call ast.Node) (*FuncCall, symbol.BuiltinType, error) {
+	name := call.Query(ast.QueryTypeOne, ast.MarkerFuncName)[0].(*ast.Leaf)
+
+	f, ok := scope.LookupFunc(name.Value)
+	if !ok {
+		return nil, symbol.BuiltinTypeUnknown, fmt.Errorf("%s: function not found", name.Value)
+	}
+
+	args := ast.CallArgs(call)
+	if len(args) != len(f.Params) {
+		return nil, symbol.BuiltinTypeUnknown, fmt.Errorf("%s: wrong number of arguments", name.Value)
+	}
+
+	var wasmArgs []Expr
+	for i, arg := range args {
+		if f.Params[i].ByRef {
+			addr, err := g.buildVarAddr(scope, arg)
+			if err != nil {
+				return nil, symbol.BuiltinTypeUnknown, err
+			}
+
+			wasmArgs = append(wasmArgs, addr)
+			continue
+		}
+
+		argExpr, argType, err := g.buildExpression(scope, arg)
+		if err != nil {
+			return nil, symbol.BuiltinTypeUnknown, err
+		}
+
+		// Strings are printed by the helper function which passes string bytes to the imported function.
+		if name.Value == "writeln" && (argType == symbol.BuiltinTypeString || argType == symbol.BuiltinTypeChar) {
+			g.memory.Use()
+			g.printsStrings = true
+
+			return &FuncCall{
+				Name: writelnStringFunc.Name,
+				Args: []Expr{g.convertExpr(argExpr, argType, symbol.BuiltinTypeString)},
+			}, symbol.BuiltinTypeVoid, nil
+		}
+
+		// writeln is imported for each supported type separately.
+		if name.Value == "writeln" {
+			return &FuncCall{
+				Name: fmt.Sprintf("%s_%s", name.Value, g.convertToWASMType(argType)),
+				Args: []Expr{argExpr},
+			}, symbol.BuiltinTypeVoid, nil
+		}
+
+		wasmArgs = append(wasmArgs, g.convertExpr(argExpr, argType, f.Params[i].Type.BuiltinType))
+	}
+
+	if g.frames[f] != nil {
+		pos := call.Position()
+		wasmArgs = append(wasmArgs,
+			&Const{Type: TypeI32, Value: strconv.Itoa(int(pos.Line))},
+			&Const{Type: TypeI32, Value: strconv.Itoa(int(pos.StartCol))},
+		)
+	}
+
+	return &FuncCall{
+		Name: name.Value,
+		Args: wasmArgs,
+	}, f.ReturnType.BuiltinType, nil
+}
+
+// buildOrdinalCall computes ord, succ or pred in place.
+// All ordinal values are ints in generated code, so ord returns the argument as is.
+func (g *Generator) buildOrdinalCall(scope symbol.Scope, tree *ast.Expr) (Expr, symbol.BuiltinType, error) {
+	args := ast.CallArgs(tree.Call)
+	if len(args) != 1 {
+		return nil, symbol.BuiltinTypeUnknown, fmt.Errorf("%s: wrong number of arguments", tree.Leaf.Value)
+	}
+
+	arg, argType, err := g.buildExpression(scope, args[0])
+	if err != nil {
+		return nil, symbol.BuiltinTypeUnknown, err
+	}
+
+	switch tree.Leaf.Value {
+	case "succ":
+		return &BinaryOp{Type: TypeI32, Op: OpAdd, Left: arg, Right: &Const{Type: TypeI32, Value: "1"}}, argType, nil
+	case "pred":
+		return &BinaryOp{Type: TypeI32, Op: OpSub, Left: arg, Right: &Const{Type: TypeI32, Value: "1"}}, argType, nil
+	default:
+		return arg, symbol.BuiltinTypeInt, nil
+	}
+}
+
+func (g *Generator) convertToWASMExpr(scope symbol.Scope, tree *ast.Expr) (Expr, symbol.BuiltinType, error) {
+	switch {
+	case tree.IsCall():
+		if f, ok := scope.LookupFunc(tree.Leaf.Value); ok && typechecker.IsOrdinalBuiltin(f) {
+			return g.buildOrdinalCall(scope, tree)
+		}
+
+		return g.buildCall(scope, tree.Call)
+	case tree.HasSelectors():
+		addr, t, err := g.buildElemAddr(scope, tree)
+		if err != nil {
+			return nil, symbol.BuiltinTypeUnknown, err
+		}
+
+		return &Load{Type: g.convertToWASMType(t.BuiltinType), Addr: addr}, t.BuiltinType, nil
+	case tree.IsOperand():
+		return g.convertOperand(scope, tree.Leaf)
+	case tree.IsUnary():
+		return g.convertUnaryOp(scope, tree)
+	}
+
+	left, leftType, err := g.convertToWASMExpr(scope, tree.Left)
+	if err != nil {
+		return nil, symbol.BuiltinTypeUnknown, err
+	}
+
+	right, rightType, err := g.convertToWASMExpr(scope, tree.Right)
+	if err != nil {
+		return nil, symbol.BuiltinTypeUnknown, err
+	}
+
+	left, right, exprType := g.wrapWithTypeConversions(left, right, leftType, rightType)
+
+	op, err := MapTokenToWASMOp(tree.Leaf.ID, exprType)
+	if err != nil {
+		return nil, symbol.BuiltinTypeUnknown, err
+	}
+
+	resultType := exprType
+	switch tree.Leaf.ID {
+	case token.Eq, token.Ne, token.Lt, token.Lte, token.Gt, token.Gte:
+		resultType = symbol.BuiltinTypeBool
+	}
+
+	return &BinaryOp{
+		Type:  g.convertToWASMType(exprType),
+		Op:    op,
+		Left:  left,
+		Right: right,
+	}, resultType, nil
+}
+
+func (g *Generator) convertOperand(scope symbol.Scope, leaf *ast.Leaf) (Expr, symbol.BuiltinType, error) {
+	switch leaf.ID {
+	case token.BoolLiteral:
+		return &Const{
+			Type:  TypeI32,
+			Value: g.constValue(symbol.BuiltinTypeBool, leaf.Value),
+		}, symbol.BuiltinTypeBool, nil
+	case token.IntLiteral:
+		return &Const{
+			Type:  TypeI32,
+			Value: leaf.Value,
+		}, symbol.BuiltinTypeInt, nil
+	case token.DoubleLiteral:
+		return &Const{
+			Type:  TypeF64,
+			Value: leaf.Value,
+		}, symbol.BuiltinTypeDouble, nil
+	case token.StringLiteral:
+		t := typechecker.StringLiteralType(leaf.Value)
+		return &Const{
+			Type:  TypeI32,
+			Value: g.constValue(t, leaf.Value),
+		}, t, nil
+	case token.UserDefined:
+		s, ok := scope.Lookup(&symbol.Name{Name: leaf.Value})
+		if !ok {
+			return nil, symbol.BuiltinTypeUnknown, fmt.Errorf("%s: symbol not found", leaf.Value)
+		}
+
+		switch s := s.(type) {
+		case *symbol.Const:
+			// Local constants are not declared in generated code, so we use their values directly.
+			if g.isLocal(scope, leaf.Value) {
+				return &Const{
+					Type:  g.convertToWASMType(s.Type.BuiltinType),
+					Value: g.constValue(s.Type.BuiltinType, s.RawValue),
+				}, s.Type.BuiltinType, nil
 			}
 
-			left := g.buildExprTree(scope, leafs[:i])
-			right := g.buildExprTree(scope, leafs[i+1:])
+			return &GlobalGet{
+				Name: leaf.Value,
+			}, s.Type.BuiltinType, nil
+		case *symbol.Var:
+			get, err := g.buildVarGet(scope, leaf.Value)
+			if err != nil {
+				return nil, symbol.BuiltinTypeUnknown, err
+			}
+
+			return get, s.Type.BuiltinType, nil
+		default:
+			return nil, symbol.BuiltinTypeUnknown, fmt.Errorf("%s: unexpected symbol type %T", leaf.Value, s)
+		}
+	default:
+		return nil, symbol.BuiltinTypeUnknown, fmt.Errorf("unexpected token id: %s", leaf.ID)
+	}
+}
+
+// isLocal checks whether the symbol is declared in the function scope or in scopes of enclosing functions instead of the global scope.
+func (g *Generator) isLocal(scope symbol.Scope, name string) bool {
+	return !scope.IsGlobal(&symbol.Name{Name: name})
+}
+
+// refVar returns the variable passed as an argument by reference.
+func (g *Generator) refVar(scope symbol.Scope, arg ast.Node) (*symbol.Var, error) {
+	tree, err := ast.NewExpr(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tree.IsOperand() {
+		return nil, fmt.Errorf("argument passed by reference must be a variable")
+	}
+
+	s, ok := scope.Lookup(&symbol.Name{Name: tree.Leaf.Value})
+	if !ok {
+		return nil, fmt.Errorf("%s: symbol not found", tree.Leaf.Value)
+	}
+
+	v, ok := s.(*symbol.Var)
+	if !ok {
+		return nil, fmt.Errorf("%s: argument passed by reference must be a variable", tree.Leaf.Value)
+	}
+
+	return v, nil
+}
+
+// varAddr returns an address of the variable if it's stored in memory.
+// Params passed by reference contain addresses of variables themselves.
+func (g *Generator) varAddr(scope symbol.Scope, name string) (Expr, Type, bool) {
+	s, ok := scope.Lookup(&symbol.Name{Name: name})
+	if !ok {
+		return nil, "", false
+	}
+
+	v, ok := s.(*symbol.Var)
+	if !ok {
+		return nil, "", false
+	}
+
+	t := g.convertToWASMType(v.Type.BuiltinType)
+
+	// Nested functions are declared at the module level, so they access variables of enclosing functions
+	// in the frame of the latest call of the enclosing function.
+	if owner, ok := g.captured[v]; ok && !g.onStack(v) {
+		frame := g.frames[owner]
+		addr := &BinaryOp{
+			Type:  TypeI32,
+			Op:    OpAdd,
+			Left:  &GlobalGet{Name: frame.display},
+			Right: &Const{Type: TypeI32, Value: strconv.Itoa(frame.offsets[v])},
+		}
+
+		// Captured params passed by reference are stored as addresses.
+		if v.ByRef {
+			return &Load{Type: TypeI32, Addr: addr}, t, true
+		}
+
+		return addr, t, true
+	}
+
+	if v.ByRef {
+		return &LocalGet{Name: name}, t, true
+	}
+
+	if addr, ok := g.slots[v]; ok {
+		return &Const{Type: TypeI32, Value: strconv.Itoa(addr)}, t, true
+	}
+
+	if g.onStack(v) {
+		return g.frameAddr(v), t, true
+	}
+
+	return nil, "", false
+}
+
+// buildVarAddr returns an address of the variable or the array element passed by reference.
+func (g *Generator) buildVarAddr(scope symbol.Scope, arg ast.Node) (Expr, error) {
+	tree, err := ast.NewExpr(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if tree.HasSelectors() {
+		addr, _, err := g.buildElemAddr(scope, tree)
+		return addr, err
+	}
+
+	v, err := g.refVar(scope, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, _, ok := g.varAddr(scope, v.Value)
+	if !ok {
+		return nil, fmt.Errorf("%s: variable isn't stored in memory", v.Value)
+	}
+
+	return addr, nil
+}
+
+// buildElemAddr returns an address and a type of the array element or the record field.
+// Element address is computed from the variable address by adding offsets for each index and field.
+func (g *Generator) buildElemAddr(scope symbol.Scope, tree *ast.Expr) (Expr, symbol.Type, error) {
+	name := tree.Leaf.Value
+
+	s, ok := scope.Lookup(&symbol.Name{Name: name})
+	if !ok {
+		return nil, symbol.Type{}, fmt.Errorf("%s: symbol not found", name)
+	}
+
+	v, ok := s.(*symbol.Var)
+	if !ok {
+		return nil, symbol.Type{}, fmt.Errorf("%s: symbol is not a variable", name)
+	}
 
-			builtinType := left.builtinType
-			if left.builtinType == symbol.BuiltinTypeDouble || right.builtinType == symbol.BuiltinTypeDouble {
-				builtinType = symbol.BuiltinTypeDouble
+	addr, _, ok := g.varAddr(scope, name)
+	if !ok {
+		return nil, symbol.Type{}, fmt.Errorf("%s: variable isn't stored in memory", name)
+	}
+
+	t := v.Type
+	for _, selector := range ast.Selectors(tree.Variable) {
+		if selector.Has(ast.MarkerField) {
+			field := selector.(*ast.Leaf).Value
+			if t.Record == nil {
+				return nil, symbol.Type{}, fmt.Errorf("%s: field %s of non-record value", name, field)
 			}
 
-			return &exprTree{
-				builtinType: builtinType,
-				node:        leaf,
-				left:        left,
-				right:       right,
+			offset, f, ok := g.fieldOffset(*t.Record, field)
+			if !ok {
+				return nil, symbol.Type{}, fmt.Errorf("%s: field %s not found", name, field)
 			}
+
+			addr = g.addOffset(addr, &Const{Type: TypeI32, Value: strconv.Itoa(offset)}, 1)
+			t = f.Type
+			continue
+		}
+
+		if t.Array == nil {
+			return nil, symbol.Type{}, fmt.Errorf("%s: too many indexes", name)
+		}
+
+		indexExpr, _, err := g.buildExpression(scope, selector)
+		if err != nil {
+			return nil, symbol.Type{}, err
+		}
+
+		offset := g.buildIndexOffset(indexExpr, *t.Array, selector.Position())
+		addr = g.addOffset(addr, offset, g.sizeOf(t.Array.Elem))
+		t = t.Array.Elem
+	}
+
+	return addr, t, nil
+}
+
+// buildIndexOffset returns an offset of the element from the start of the array in elements.
+// Constant indexes within bounds are computed at compile time, other indexes are checked at runtime if checks are enabled.
+func (g *Generator) buildIndexOffset(index Expr, array symbol.Array, pos literal.Position) Expr {
+	if c, ok := index.(*Const); ok {
+		if v, err := strconv.Atoi(c.Value); err == nil && v >= array.Low && v <= array.High {
+			return &Const{Type: TypeI32, Value: strconv.Itoa(v - array.Low)}
+		}
+	}
+
+	if g.BoundsChecks {
+		g.checksBounds = true
+
+		return &FuncCall{
+			Name: boundsCheckFunc.Name,
+			Args: []Expr{
+				index,
+				&Const{Type: TypeI32, Value: strconv.Itoa(array.Low)},
+				&Const{Type: TypeI32, Value: strconv.Itoa(array.High)},
+				&Const{Type: TypeI32, Value: strconv.Itoa(int(pos.Line))},
+				&Const{Type: TypeI32, Value: strconv.Itoa(int(pos.StartCol))},
+			},
+		}
+	}
+
+	return &BinaryOp{
+		Type:  TypeI32,
+		Op:    OpSub,
+		Left:  index,
+		Right: &Const{Type: TypeI32, Value: strconv.Itoa(array.Low)},
+	}
+}
+
+// addOffset adds the offset in elements of the given size to the address.
+// Constant offsets are added at compile time.
+func (g *Generator) addOffset(addr, offset Expr, size int) Expr {
+	if c, ok := offset.(*Const); ok {
+		v, _ := strconv.Atoi(c.Value)
+		if v == 0 {
+			return addr
+		}
+
+		if a, ok := addr.(*Const); ok {
+			base, _ := strconv.Atoi(a.Value)
+			return &Const{Type: TypeI32, Value: strconv.Itoa(base + v*size)}
+		}
+
+		return &BinaryOp{Type: TypeI32, Op: OpAdd, Left: addr, Right: &Const{Type: TypeI32, Value: strconv.Itoa(v * size)}}
+	}
+
+	return &BinaryOp{
+		Type: TypeI32,
+		Op:   OpAdd,
+		Left: addr,
+		Right: &BinaryOp{
+			Type:  TypeI32,
+			Op:    OpMul,
+			Left:  offset,
+			Right: &Const{Type: TypeI32, Value: strconv.Itoa(size)},
+		},
+	}
+}
+
+// buildVarGet reads the variable from a WASM local or global, or from memory.
+func (g *Generator) buildVarGet(scope symbol.Scope, name string) (Expr, error) {
+	if addr, t, ok := g.varAddr(scope, name); ok {
+		return &Load{Type: t, Addr: addr}, nil
+	}
+
+	// Params, locals and function results are stored in WASM locals.
+	if g.isLocal(scope, name) {
+		if _, ok := scope.LookupLocal(&symbol.Name{Name: name}); !ok {
+			return nil, fmt.Errorf("%s: variable of the enclosing function isn't stored in memory", name)
 		}
+
+		return &LocalGet{Name: name}, nil
 	}
 
-	panic("found several consecutive non-operator leafs in expressions")
+	return &GlobalGet{Name: name}, nil
 }
 
-func (g *Generator) linearizeExpression(node ast.Node) []*ast.Leaf {
-	switch node := node.(type) {
-	case *ast.Leaf:
-		return []*ast.Leaf{node}
-	case *ast.Branch:
-		var res []*ast.Leaf
-		for _, items := range node.Items {
-			res = append(res, g.linearizeExpression(items)...)
+// buildVarSet writes the expression to the variable stored in a WASM local or global, or in memory.
+func (g *Generator) buildVarSet(scope symbol.Scope, name string, expr Expr) (Statement, error) {
+	if addr, t, ok := g.varAddr(scope, name); ok {
+		return &Store{Type: t, Addr: addr, Value: expr}, nil
+	}
+
+	if g.isLocal(scope, name) {
+		if _, ok := scope.LookupLocal(&symbol.Name{Name: name}); !ok {
+			return nil, fmt.Errorf("%s: variable of the enclosing function isn't stored in memory", name)
+		}
+
+		return &LocalSet{Name: name, Expr: expr}, nil
+	}
+
+	return &GlobalSet{Name: name, Expr: expr}, nil
+}
+
+func (g *Generator) convertUnaryOp(scope symbol.Scope, tree *ast.Expr) (Expr, symbol.BuiltinType, error) {
+	operand, operandType, err := g.convertToWASMExpr(scope, tree.Right)
+	if err != nil {
+		return nil, symbol.BuiltinTypeUnknown, err
+	}
+
+	switch tree.Leaf.ID {
+	case token.Not:
+		return &UnaryOp{
+			Type: TypeI32,
+			Op:   OpEqz,
+			Expr: operand,
+		}, operandType, nil
+	case token.Minus:
+		// Negative literals are used as is.
+		if c, ok := operand.(*Const); ok && tree.Right.IsOperand() && !strings.HasPrefix(c.Value, "-") {
+			return &Const{
+				Type:  c.Type,
+				Value: "-" + c.Value,
+			}, operandType, nil
+		}
+
+		// There is no neg instruction for ints, so we subtract the value from zero.
+		if operandType == symbol.BuiltinTypeInt {
+			return &BinaryOp{
+				Type:  TypeI32,
+				Op:    OpSub,
+				Left:  &Const{Type: TypeI32, Value: "0"},
+				Right: operand,
+			}, operandType, nil
 		}
 
-		return res
+		return &UnaryOp{
+			Type: TypeF64,
+			Op:   OpNeg,
+			Expr: operand,
+		}, operandType, nil
 	default:
-		panic("unknown node type")
+		return operand, operandType, nil
+	}
+}
+
+func (g *Generator) wrapWithTypeConversions(left, right Expr, leftType, rightType symbol.BuiltinType) (Expr, Expr, symbol.BuiltinType) {
+	if leftType != symbol.BuiltinTypeDouble && rightType == symbol.BuiltinTypeDouble {
+		return &Conversion{
+			ResultingType: TypeF64,
+			Expr:          left,
+		}, right, symbol.BuiltinTypeDouble
+	}
+
+	if leftType == symbol.BuiltinTypeDouble && rightType != symbol.BuiltinTypeDouble {
+		return left, &Conversion{
+			ResultingType: TypeF64,
+			Expr:          right,
+		}, symbol.BuiltinTypeDouble
+	}
+
+	return left, right, leftType
+}
+
+// convertExpr converts the expression to the type of variable or param it's assigned to.
+func (g *Generator) convertExpr(expr Expr, from, to symbol.BuiltinType) Expr {
+	if from == symbol.BuiltinTypeInt && to == symbol.BuiltinTypeDouble {
+		return &Conversion{
+			ResultingType: TypeF64,
+			Expr:          expr,
+		}
+	}
+
+	if from == symbol.BuiltinTypeChar && to == symbol.BuiltinTypeString {
+		// Char constants are stored as separate strings.
+		if c, ok := expr.(*Const); ok {
+			code, err := strconv.Atoi(c.Value)
+			if err == nil {
+				return &Const{
+					Type:  TypeI32,
+					Value: strconv.Itoa(g.memory.AddString(string([]byte{byte(code)}))),
+				}
+			}
+		}
+
+		// Other chars are converted using the table of all 1-character strings.
+		return &BinaryOp{
+			Type: TypeI32,
+			Op:   OpAdd,
+			Left: &Const{Type: TypeI32, Value: strconv.Itoa(g.memory.CharTable())},
+			Right: &BinaryOp{
+				Type:  TypeI32,
+				Op:    OpMul,
+				Left:  expr,
+				Right: &Const{Type: TypeI32, Value: strconv.Itoa(charTableEntrySize)},
+			},
+		}
 	}
+
+	return expr
+}
+
+func (g *Generator) sortedSymbols(scope symbol.Scope) []symbol.Symbol {
+	symbols := scope.Symbols()
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbol.Position(symbols[i]).Before(symbol.Position(symbols[j]))
+	})
+
+	return symbols
 }
 
 func (g *Generator) convertToWASMType(t symbol.BuiltinType) Type {
@@ -442,15 +1782,79 @@ func (g *Generator) convertToWASMType(t symbol.BuiltinType) Type {
 		return TypeI32
 	case symbol.BuiltinTypeDouble:
 		return TypeF64
-	case symbol.BuiltinTypeString:
-		panic("string usage in WASM is not supported for now")
-	case symbol.BuiltinTypeBool:
+	case symbol.BuiltinTypeString, symbol.BuiltinTypeChar:
+		// Strings are addresses in linear memory, and chars are character codes.
+		return TypeI32
+	case symbol.BuiltinTypeBool, symbol.BuiltinTypeEnum:
+		// Bools and enums are ordinal values.
+		return TypeI32
+	case symbol.BuiltinTypeArray, symbol.BuiltinTypeRecord:
+		// Arrays and records are addresses of their first elements and fields.
 		return TypeI32
 	default:
 		panic("unknown type")
 	}
 }
 
+// sizeOf returns a size of the value of the given type stored in memory.
+// Size of a record is padded to its alignment, so fields of all elements of arrays of records are aligned.
+func (g *Generator) sizeOf(t symbol.Type) int {
+	switch {
+	case t.Array != nil:
+		return t.Array.Len() * g.sizeOf(t.Array.Elem)
+	case t.Record != nil:
+		size := 0
+		for _, f := range t.Record.Fields {
+			size = alignTo(size, g.alignOf(f.Type)) + g.sizeOf(f.Type)
+		}
+
+		return alignTo(size, g.alignOf(t))
+	case t.BuiltinType == symbol.BuiltinTypeDouble:
+		return 8
+	default:
+		return 4
+	}
+}
+
+// alignOf returns an alignment of the value of the given type stored in memory.
+func (g *Generator) alignOf(t symbol.Type) int {
+	switch {
+	case t.Array != nil:
+		return g.alignOf(t.Array.Elem)
+	case t.Record != nil:
+		align := 4
+		for _, f := range t.Record.Fields {
+			if a := g.alignOf(f.Type); a > align {
+				align = a
+			}
+		}
+
+		return align
+	default:
+		return g.sizeOf(t)
+	}
+}
+
+// fieldOffset returns an offset of the field from the start of the record.
+// Fields are stored in order of declaration, and each field is aligned to its own alignment.
+func (g *Generator) fieldOffset(record symbol.Record, name string) (int, symbol.Field, bool) {
+	offset := 0
+	for _, f := range record.Fields {
+		offset = alignTo(offset, g.alignOf(f.Type))
+		if f.Name == name {
+			return offset, f, true
+		}
+
+		offset += g.sizeOf(f.Type)
+	}
+
+	return 0, symbol.Field{}, false
+}
+
+func alignTo(offset, align int) int {
+	return (offset + align - 1) / align * align
+}
+
 func (g *Generator) defaultValue(t symbol.BuiltinType) string {
 	switch t {
 	case symbol.BuiltinTypeInt:
@@ -458,27 +1862,33 @@ func (g *Generator) defaultValue(t symbol.BuiltinType) string {
 	case symbol.BuiltinTypeDouble:
 		return "0.0"
 	case symbol.BuiltinTypeString:
-		panic("strings are not supported")
-	case symbol.BuiltinTypeBool:
+		return strconv.Itoa(emptyStringAddr)
+	case symbol.BuiltinTypeChar:
+		return "0"
+	case symbol.BuiltinTypeBool, symbol.BuiltinTypeEnum:
 		return "0"
 	default:
 		panic("unknown type")
 	}
 }
 
-func (g *Generator) WAT2WASM(watFilename string) (string, error) {
-	file, err := exec.LookPath("wat2wasm")
-	if err != nil {
-		return "", err
-	}
-
-	wasmFilename := strings.Replace(watFilename, ".wat", ".wasm", 1)
+// constValue converts a constant value to its WASM representation.
+func (g *Generator) constValue(t symbol.BuiltinType, value string) string {
+	switch t {
+	case symbol.BuiltinTypeBool:
+		if value == "true" {
+			return "1"
+		}
 
-	cmd := exec.Command(file, watFilename, "-o", wasmFilename)
-	if err := cmd.Run(); err != nil {
-		b, _ := cmd.CombinedOutput()
-		return string(b), err
+		return "0"
+	case symbol.BuiltinTypeString:
+		// String literals are validated by the scanner.
+		s, _ := token.Unquote(value)
+		return strconv.Itoa(g.memory.AddString(s))
+	case symbol.BuiltinTypeChar:
+		s, _ := token.Unquote(value)
+		return strconv.Itoa(int(s[0]))
+	default:
+		return value
 	}
-
-	return "", nil
 }
@@ -0,0 +1,104 @@
+package wasm_test
+
+import (
+	"bytes"
+	stdcontext "context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iskorotkov/compiler/internal/context"
+	"github.com/iskorotkov/compiler/internal/module/reader"
+	"github.com/iskorotkov/compiler/internal/module/scanner"
+	"github.com/iskorotkov/compiler/internal/module/syntax_analyzer"
+	"github.com/iskorotkov/compiler/internal/module/typechecker"
+	"github.com/iskorotkov/compiler/internal/module/vm"
+	"github.com/iskorotkov/compiler/internal/module/wasm"
+)
+
+func TestGenerator_StackOverflow(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+var
+  g: array[1..10000] of integer;
+procedure deep(n: integer);
+var
+  a: array[1..1000] of integer;
+begin
+  a[1] := n;
+  if n < 1000 then
+    deep(n + 1);
+end
+begin
+  g[10000] := 7;
+  deep(1);
+  writeln(g[10000]);
+end.`
+
+	// Frames of recursive calls don't fit in the stack, so overflow is reported instead of overwriting the global array.
+	output, err := run(t, wasm.NewGenerator(), input)
+	assert.ErrorIs(t, err, vm.ErrStackOverflow)
+	assert.EqualError(t, err, "stack overflow at 10:5")
+	assert.Empty(t, output)
+}
+
+func TestGenerator_DisabledChecks(t *testing.T) {
+	t.Parallel()
+
+	input := `program p;
+type
+  digit = 0..9;
+var
+  a: array[1..3] of integer;
+  d: digit;
+  i: integer;
+begin
+  i := 12;
+  d := i;
+  writeln(d);
+  i := 4;
+  a[i] := 5;
+  writeln(a[i]);
+end.`
+
+	_, err := run(t, wasm.NewGenerator(), input)
+	assert.ErrorIs(t, err, vm.ErrOutOfRange)
+
+	g := wasm.NewGenerator()
+	g.RangeChecks = false
+
+	_, err = run(t, g, input)
+	assert.ErrorIs(t, err, vm.ErrIndexOutOfBounds)
+
+	// Without checks values are stored as is, so the program runs to the end.
+	g.BoundsChecks = false
+
+	output, err := run(t, g, input)
+	assert.NoError(t, err)
+	assert.Equal(t, "12\n5\n", output)
+}
+
+// run compiles the program with the generator and runs it in the VM.
+// It returns the output of the program and the runtime error.
+func run(t *testing.T, g *wasm.Generator, input string) (string, error) {
+	ctx := context.NewEnvContext(stdcontext.Background())
+
+	literals := reader.New(0).Read(ctx, strings.NewReader(input))
+	tokens := scanner.New(0).Scan(ctx, literals)
+	programs := syntax_analyzer.New(0).Analyze(ctx, tokens)
+	results := typechecker.NewTypeChecker(0).Check(ctx, programs)
+
+	m, ok := <-g.Generate(ctx, results)
+	assert.True(t, ok)
+	assert.Empty(t, ctx.Errors())
+
+	_, err := m.Binary()
+	assert.NoError(t, err)
+
+	var output bytes.Buffer
+	err = vm.New(vm.ConsoleImports(&output)).Run(m, "main")
+
+	return output.String(), err
+}
@@ -0,0 +1,195 @@
+package wasm
+
+import (
+	"encoding/binary"
+)
+
+const (
+	pageSize = 65536
+
+	// emptyStringAddr is an address of the empty string.
+	// Uninitialized string variables are zero, so they point to it.
+	emptyStringAddr = 0
+
+	// charTableEntrySize is a size of a single string in the char table.
+	charTableEntrySize = 8
+
+	// stackSize is the minimum size of the stack for variables of functions which are stored in memory.
+	stackSize = 16 * pageSize
+
+	// stackFrames is the minimum number of frames of the largest function which fit in the stack,
+	// so recursive functions with large local arrays can be called as deep as functions with small frames.
+	stackFrames = 256
+
+	// slotSize is a size of a variable stored in memory. It's large enough for values of all types.
+	slotSize = 8
+)
+
+// linearMemory lays out strings and variables in linear memory.
+//
+// Each string is stored as a 4-byte little endian length followed by its bytes,
+// and string values are addresses of the length. Strings are aligned to 4 bytes.
+//
+// Variables are stored in 8-byte slots, and arrays and records are stored in blocks of 8-byte aligned memory.
+// Global variables are stored after strings, and local variables are stored on the stack
+// which is located at the end of memory and grows down to the end of strings and global variables.
+type linearMemory struct {
+	data      []byte
+	strings   map[string]int
+	charTable *int
+	used      bool
+	// stack is a size of the stack (0 if the stack isn't used).
+	stack int
+}
+
+func newLinearMemory() *linearMemory {
+	m := &linearMemory{
+		strings: map[string]int{},
+	}
+
+	m.store("")
+	return m
+}
+
+// AddString stores the string in memory and returns its address.
+// Equal strings are stored only once.
+func (m *linearMemory) AddString(s string) int {
+	m.used = true
+
+	if addr, ok := m.strings[s]; ok {
+		return addr
+	}
+
+	addr := m.store(s)
+	m.strings[s] = addr
+
+	return addr
+}
+
+// CharTable returns an address of the table of 1-character strings for all byte values.
+// Char is converted to a string by computing its address in the table.
+func (m *linearMemory) CharTable() int {
+	m.used = true
+
+	if m.charTable != nil {
+		return *m.charTable
+	}
+
+	addr := len(m.data)
+	for c := 0; c < 256; c++ {
+		entry := make([]byte, charTableEntrySize)
+		binary.LittleEndian.PutUint32(entry, 1)
+		entry[4] = byte(c)
+
+		m.data = append(m.data, entry...)
+	}
+
+	m.charTable = &addr
+	return addr
+}
+
+// AddSlot reserves a zeroed slot for a variable and returns its address.
+func (m *linearMemory) AddSlot() int {
+	return m.AddBlock(slotSize)
+}
+
+// AddBlock reserves zeroed memory of the given size and returns its address.
+// Blocks are aligned to the slot size, so they can store values of all types.
+func (m *linearMemory) AddBlock(size int) int {
+	m.used = true
+
+	for len(m.data)%slotSize != 0 {
+		m.data = append(m.data, 0)
+	}
+
+	addr := len(m.data)
+	m.data = append(m.data, make([]byte, size)...)
+
+	return addr
+}
+
+// UseStack reserves memory for the stack.
+// Stack grows by pages until stackFrames frames of the given size fit in it.
+func (m *linearMemory) UseStack(frameSize int) {
+	m.used = true
+
+	for m.stack < stackSize || m.stack < stackFrames*frameSize {
+		m.stack += pageSize
+	}
+}
+
+// StackTop returns an address of the end of the stack, or 0 if the stack isn't used.
+// It must be called after all strings and slots are added.
+func (m *linearMemory) StackTop() int {
+	if m.stack == 0 {
+		return 0
+	}
+
+	return m.pages() * pageSize
+}
+
+// StackLimit returns an address of the end of strings and global variables.
+// Stack must not grow below it, as it would overwrite them.
+// It must be called after all strings and slots are added.
+func (m *linearMemory) StackLimit() int {
+	return alignTo(len(m.data), slotSize)
+}
+
+// Use marks memory as used, so it's declared in the module even if no strings were added.
+func (m *linearMemory) Use() {
+	m.used = true
+}
+
+// Memory returns the memory declaration, or nil if memory isn't used.
+func (m *linearMemory) Memory() *Memory {
+	if !m.used {
+		return nil
+	}
+
+	return &Memory{
+		Pages:  m.pages(),
+		Export: "memory",
+	}
+}
+
+// Data returns the data segment with all stored strings, or nil if memory isn't used.
+// Memory is zero-initialized, so trailing zeros (for example, of global arrays) aren't included in the segment.
+func (m *linearMemory) Data() []Data {
+	if !m.used {
+		return nil
+	}
+
+	end := len(m.data)
+	for end > 0 && m.data[end-1] == 0 {
+		end--
+	}
+
+	return []Data{
+		{
+			Offset: 0,
+			Bytes:  m.data[:end],
+		},
+	}
+}
+
+func (m *linearMemory) pages() int {
+	size := len(m.data) + m.stack
+
+	return (size + pageSize - 1) / pageSize
+}
+
+func (m *linearMemory) store(s string) int {
+	addr := len(m.data)
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(s)))
+
+	m.data = append(m.data, length[:]...)
+	m.data = append(m.data, s...)
+
+	for len(m.data)%4 != 0 {
+		m.data = append(m.data, 0)
+	}
+
+	return addr
+}
@@ -6,15 +6,17 @@ import (
 
 type Module struct {
 	Imports []Import
+	Memory  *Memory
 	Globals []Global
 	Funcs   []Func
+	Data    []Data
 }
 
 func (m Module) String() string {
 	var s strings.Builder
 
 	s.WriteString("(module")
-	if len(m.Imports)+len(m.Globals)+len(m.Funcs) > 0 {
+	if len(m.Imports)+len(m.Globals)+len(m.Funcs)+len(m.Data) > 0 || m.Memory != nil {
 		s.WriteString("\n")
 	}
 
@@ -25,6 +27,11 @@ func (m Module) String() string {
 		}
 	}
 
+	if m.Memory != nil {
+		s.WriteString(m.Memory.StringIndent(1))
+		s.WriteString("\n")
+	}
+
 	if len(m.Globals) > 0 {
 		for _, g := range m.Globals {
 			s.WriteString(g.StringIndent(1))
@@ -39,6 +46,13 @@ func (m Module) String() string {
 		}
 	}
 
+	if len(m.Data) > 0 {
+		for _, d := range m.Data {
+			s.WriteString(d.StringIndent(1))
+			s.WriteString("\n")
+		}
+	}
+
 	s.WriteString(")")
 	return s.String()
 }
@@ -65,6 +65,110 @@ func TestModule_String(t *testing.T) {
 				Funcs: nil,
 			},
 		},
+		{
+			name: "module with functions",
+			expected: `(module
+  (func $main (export "main")
+    (drop (call $square (i32.const 2)))
+  )
+  (func $square (param $x i32) (result i32)
+    (local $square i32)
+    (local.set $square (i32.mul (local.get $x) (local.get $x)))
+    (return (local.get $square))
+  )
+)`,
+			module: Module{
+				Imports: nil,
+				Globals: nil,
+				Funcs: []Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []Statement{
+							&Drop{Expr: &FuncCall{Name: "square", Args: []Expr{&Const{"i32", "2"}}}},
+						},
+					},
+					{
+						Name:   "square",
+						Params: []Param{{"x", "i32"}},
+						Locals: []Local{{"square", "i32"}},
+						Return: &Return{Type: "i32"},
+						Body: []Statement{
+							&LocalSet{
+								Name: "square",
+								Expr: &BinaryOp{"i32", OpMul, &LocalGet{"x"}, &LocalGet{"x"}},
+							},
+							&FuncReturn{Expr: &LocalGet{"square"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "module with blocks",
+			expected: `(module
+  (func $main (export "main")
+    (local $i i32)
+    (block
+      (br_if 0 (i32.eqz (local.get $i)))
+      (loop
+        (local.set $i (i32.sub (local.get $i) (i32.const 1)))
+        (br_if 1 (i32.eqz (local.get $i)))
+        (br 0)
+      )
+    )
+  )
+)`,
+			module: Module{
+				Funcs: []Func{
+					{
+						Name:   "main",
+						Export: true,
+						Locals: []Local{{"i", "i32"}},
+						Body: []Statement{
+							&Block{
+								Body: []Statement{
+									&BrIf{Label: 0, Cond: &UnaryOp{"i32", OpEqz, &LocalGet{"i"}}},
+									&Loop{
+										Body: []Statement{
+											&LocalSet{
+												Name: "i",
+												Expr: &BinaryOp{"i32", OpSub, &LocalGet{"i"}, &Const{"i32", "1"}},
+											},
+											&BrIf{Label: 1, Cond: &UnaryOp{"i32", OpEqz, &LocalGet{"i"}}},
+											&Br{Label: 0},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "module with memory",
+			expected: `(module
+  (memory (export "memory") 1)
+  (func $main (export "main")
+    (drop (i32.load (i32.const 0)))
+  )
+  (data (i32.const 0) "\02\00\00\00\22a\5c")
+)`,
+			module: Module{
+				Memory: &Memory{Pages: 1, Export: "memory"},
+				Funcs: []Func{
+					{
+						Name:   "main",
+						Export: true,
+						Body: []Statement{
+							&Drop{Expr: &Load{"i32", &Const{"i32", "0"}}},
+						},
+					},
+				},
+				Data: []Data{{Offset: 0, Bytes: []byte{2, 0, 0, 0, '"', 'a', '\\'}}},
+			},
+		},
 	}
 
 	for _, test := range tests {
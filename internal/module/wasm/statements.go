@@ -22,23 +22,8 @@ func (i *Import) StringIndent(level int) string {
 		pathStrs = append(pathStrs, fmt.Sprintf("%q", p))
 	}
 
-	var paramsStr string
-	if len(i.Params) > 0 {
-		// We assume there will be no more than 4-5 params,
-		// so it's easier and more efficient to use strings instead of string builder.
-		paramsStr = " "
-		for _, param := range i.Params {
-			paramsStr += fmt.Sprintf("(param $%s %s)", param.Name, param.Type)
-		}
-	}
-
-	if i.Return != nil {
-		return fmt.Sprintf("%s(import %s (func $%s%s (return %s)))",
-			strings.Repeat("  ", level), strings.Join(pathStrs, " "), i.Name, paramsStr, i.Return.Type)
-	} else {
-		return fmt.Sprintf("%s(import %s (func $%s%s))",
-			strings.Repeat("  ", level), strings.Join(pathStrs, " "), i.Name, paramsStr)
-	}
+	return fmt.Sprintf("%s(import %s (func $%s%s%s))",
+		strings.Repeat("  ", level), strings.Join(pathStrs, " "), i.Name, paramsString(i.Params), resultString(i.Return))
 }
 
 type Param struct {
@@ -50,6 +35,11 @@ type Return struct {
 	Type Type
 }
 
+type Local struct {
+	Name string
+	Type Type
+}
+
 type Global struct {
 	Name    string
 	Type    Type
@@ -67,20 +57,61 @@ func (g *Global) StringIndent(level int) string {
 	}
 }
 
+// Memory is a linear memory of the module.
+type Memory struct {
+	// Pages is an initial size of the memory in 64 KiB pages.
+	Pages int
+	// Export is a name the memory is exported with (empty if the memory isn't exported).
+	Export string
+}
+
+func (m *Memory) StringIndent(level int) string {
+	var exportStr string
+	if m.Export != "" {
+		exportStr = fmt.Sprintf(" (export %q)", m.Export)
+	}
+
+	return fmt.Sprintf("%s(memory%s %d)", strings.Repeat("  ", level), exportStr, m.Pages)
+}
+
+// Data initializes linear memory with bytes starting at the offset.
+type Data struct {
+	Offset int
+	Bytes  []byte
+}
+
+func (d *Data) StringIndent(level int) string {
+	// Printable characters are written as is, and all other bytes are escaped with hex codes.
+	var bytesStr strings.Builder
+	for _, c := range d.Bytes {
+		if c >= 0x20 && c < 0x7F && c != '"' && c != '\\' {
+			bytesStr.WriteByte(c)
+		} else {
+			fmt.Fprintf(&bytesStr, "\\%02x", c)
+		}
+	}
+
+	return fmt.Sprintf("%s(data (i32.const %d) \"%s\")", strings.Repeat("  ", level), d.Offset, bytesStr.String())
+}
+
 type Func struct {
 	Name   string
+	Export bool
 	Params []Param
+	Locals []Local
 	Return *Return
 	Body   []Statement
 }
 
 func (f *Func) StringIndent(level int) string {
-	var paramsStr string
-	if len(f.Params) > 0 {
-		paramsStr = " "
-		for _, param := range f.Params {
-			paramsStr += fmt.Sprintf("(param $%s %s)", param.Name, param.Type)
-		}
+	var exportStr string
+	if f.Export {
+		exportStr = fmt.Sprintf(" (export %q)", f.Name)
+	}
+
+	var localsStr string
+	for _, local := range f.Locals {
+		localsStr += fmt.Sprintf("%s(local $%s %s)\n", strings.Repeat("  ", level+1), local.Name, local.Type)
 	}
 
 	var bodyStr string
@@ -88,13 +119,8 @@ func (f *Func) StringIndent(level int) string {
 		bodyStr += stmt.StringIndent(level+1) + "\n"
 	}
 
-	if f.Return != nil {
-		return fmt.Sprintf(`%s(func (export "%s")%s (return %s)
-%s%[1]s)`, strings.Repeat("  ", level), f.Name, paramsStr, f.Return.Type, bodyStr)
-	} else {
-		return fmt.Sprintf(`%s(func (export "%s")%s
-%s%[1]s)`, strings.Repeat("  ", level), f.Name, paramsStr, bodyStr)
-	}
+	return fmt.Sprintf(`%s(func $%s%s%s%s
+%s%s%[1]s)`, strings.Repeat("  ", level), f.Name, exportStr, paramsString(f.Params), resultString(f.Return), localsStr, bodyStr)
 }
 
 type If struct {
@@ -133,6 +159,8 @@ func (i *If) StringIndent(level int) string {
 	}
 }
 
+// Loop executes its body and repeats while branch condition is true.
+// If branch condition is nil, the loop must be continued or exited explicitly with Br or BrIf.
 type Loop struct {
 	BranchCond Expr
 	Body       []Statement
@@ -144,26 +172,100 @@ func (l *Loop) StringIndent(level int) string {
 		bodyStr += stmt.StringIndent(level+1) + "\n"
 	}
 
+	if l.BranchCond == nil {
+		return fmt.Sprintf(`%s(loop
+%s%[1]s)`, strings.Repeat("  ", level), bodyStr)
+	}
+
 	return fmt.Sprintf(`%s(loop
 %[3]s  %[1]s(br_if 0 %[2]s)
 %[1]s)`, strings.Repeat("  ", level), l.BranchCond.String(), bodyStr)
 }
 
+// Block is a sequence of statements which can be exited with Br or BrIf.
+type Block struct {
+	Body []Statement
+}
+
+func (b *Block) StringIndent(level int) string {
+	var bodyStr string
+	for _, stmt := range b.Body {
+		bodyStr += stmt.StringIndent(level+1) + "\n"
+	}
+
+	return fmt.Sprintf(`%s(block
+%s%[1]s)`, strings.Repeat("  ", level), bodyStr)
+}
+
+// Br jumps to the end of the enclosing block or to the start of the enclosing loop.
+// Label is a relative depth of the target block or loop (0 is the innermost one).
+type Br struct {
+	Label int
+}
+
+func (b *Br) StringIndent(level int) string {
+	return fmt.Sprintf("%s(br %d)", strings.Repeat("  ", level), b.Label)
+}
+
+// BrIf is the same as Br, but jumps only if condition is true.
+type BrIf struct {
+	Label int
+	Cond  Expr
+}
+
+func (b *BrIf) StringIndent(level int) string {
+	return fmt.Sprintf("%s(br_if %d %s)", strings.Repeat("  ", level), b.Label, b.Cond.String())
+}
+
+// BrTable jumps to the block with the label at the index given by the expression,
+// or to the default label if the index is out of range.
+type BrTable struct {
+	Labels  []int
+	Default int
+	Index   Expr
+}
+
+func (b *BrTable) StringIndent(level int) string {
+	var labelsStr string
+	for _, label := range b.Labels {
+		labelsStr += fmt.Sprintf(" %d", label)
+	}
+
+	return fmt.Sprintf("%s(br_table%s %d %s)", strings.Repeat("  ", level), labelsStr, b.Default, b.Index.String())
+}
+
 type FuncCall struct {
 	Name string
 	Args []Expr
 }
 
-func (f *FuncCall) StringIndent(level int) string {
+func (f *FuncCall) String() string {
 	var argsStr string
-	if len(f.Args) > 0 {
-		argsStr = " "
-		for _, arg := range f.Args {
-			argsStr += arg.String()
-		}
+	for _, arg := range f.Args {
+		argsStr += " " + arg.String()
 	}
 
-	return fmt.Sprintf("%s(call $%s%s)", strings.Repeat("  ", level), f.Name, argsStr)
+	return fmt.Sprintf("(call $%s%s)", f.Name, argsStr)
+}
+
+func (f *FuncCall) StringIndent(level int) string {
+	return strings.Repeat("  ", level) + f.String()
+}
+
+type FuncReturn struct {
+	Expr Expr
+}
+
+func (r *FuncReturn) StringIndent(level int) string {
+	return fmt.Sprintf("%s(return %s)", strings.Repeat("  ", level), r.Expr.String())
+}
+
+type Drop struct {
+	Expr Expr
+}
+
+func (d *Drop) StringIndent(level int) string {
+	return fmt.Sprintf("%s(drop %s)", strings.Repeat("  ", level), d.Expr.String())
 }
 
 type LocalSet struct {
@@ -185,3 +287,41 @@ func (s *GlobalSet) StringIndent(level int) string {
 	return fmt.Sprintf("%s(global.set $%s %s)",
 		strings.Repeat("  ", level), s.Name, s.Expr.String())
 }
+
+// Store writes a value of the given type to linear memory.
+type Store struct {
+	Type  Type
+	Addr  Expr
+	Value Expr
+}
+
+func (s *Store) StringIndent(level int) string {
+	return fmt.Sprintf("%s(%s.store %s %s)",
+		strings.Repeat("  ", level), s.Type, s.Addr.String(), s.Value.String())
+}
+
+// Unreachable traps, so execution of the module is aborted.
+type Unreachable struct{}
+
+func (u *Unreachable) StringIndent(level int) string {
+	return fmt.Sprintf("%s(unreachable)", strings.Repeat("  ", level))
+}
+
+func paramsString(params []Param) string {
+	// We assume there will be no more than 4-5 params,
+	// so it's easier and more efficient to use strings instead of string builder.
+	var paramsStr string
+	for _, param := range params {
+		paramsStr += fmt.Sprintf(" (param $%s %s)", param.Name, param.Type)
+	}
+
+	return paramsStr
+}
+
+func resultString(r *Return) string {
+	if r == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(" (result %s)", r.Type)
+}
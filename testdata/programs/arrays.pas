@@ -0,0 +1,55 @@
+program arrays;
+const
+  n = 5;
+type
+  vector = array[1..n] of integer;
+  matrix = array[1..3, 1..3] of real;
+var
+  v: vector;
+  m: matrix;
+  flags: array[-2..2] of boolean;
+  names: array[0..1] of string;
+  i, j: integer;
+procedure fill(var a: vector; k: integer);
+var
+  i: integer;
+begin
+  for i := 1 to n do
+    a[i] := i * k;
+end
+function total(var a: vector): integer;
+var
+  i: integer;
+  reversed: vector;
+begin
+  for i := 1 to n do
+    reversed[n - i + 1] := a[i];
+  total := 0;
+  for i := 1 to n do
+    total := total * 10 + reversed[i] mod 10;
+end
+procedure inc(var x: integer);
+begin
+  x := x + 1;
+end
+begin
+  fill(v, 3);
+  writeln(v[1]);
+  writeln(v[n]);
+  writeln(total(v));
+  inc(v[2]);
+  writeln(v[2]);
+  for i := 1 to 3 do
+    for j := 1 to 3 do
+      m[i, j] := i * 10 + j / 2.0;
+  writeln(m[2][3]);
+  writeln(m[3, 1]);
+  flags[-2] := true;
+  writeln(flags[-2]);
+  writeln(flags[2]);
+  names[0] := 'hello';
+  writeln(names[0]);
+  writeln(names[1]);
+  v[v[1] - 1] := 100;
+  writeln(v[2]);
+end.
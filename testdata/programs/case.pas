@@ -0,0 +1,67 @@
+program cases;
+const
+  top = 10;
+var
+  i, total: integer;
+  ch: char;
+  flag: boolean;
+procedure classify(n: integer);
+begin
+  case n of
+    -1: writeln('minus one');
+    0: writeln('zero');
+    1, 2: writeln('one or two');
+    3..5: writeln('three to five');
+    top: writeln('top');
+  else
+    writeln('other');
+  end;
+end
+procedure sparse(n: integer);
+begin
+  case n of
+    1: writeln(1);
+    100..199: writeln(100);
+    1000, 5000: writeln(1000);
+  otherwise
+    writeln(0);
+  end;
+end
+begin
+  for i := -2 to 11 do
+  begin
+    classify(i);
+  end;
+  sparse(1);
+  sparse(150);
+  sparse(5000);
+  sparse(7);
+  ch := 'b';
+  case ch of
+    'a': writeln('a');
+    'b'..'d': writeln('b to d');
+  end;
+  flag := true;
+  case flag of
+    true: writeln('yes');
+    false: writeln('no');
+  end;
+  total := 0;
+  for i := 1 to 6 do
+  begin
+    case i mod 3 of
+      0: total := total + 100;
+      1:
+        case i of
+          1: total := total + 1;
+        else
+          total := total + 10;
+        end;
+      2: ;
+    end;
+  end;
+  writeln(total);
+  case 42 of
+    1: writeln('never');
+  end;
+end.
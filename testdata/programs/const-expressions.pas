@@ -0,0 +1,30 @@
+program consts;
+const
+  PI = 3.14159;
+  TWO_PI = 2 * PI;
+  R = 10;
+  N = -R;
+  AREA = PI * R * R;
+  BIG = 1000 * 1000;
+  HALF = R div 2 + R mod 3;
+  ON = not false and (R > 5);
+  LETTER = 'x';
+  GREETING = 'it''s';
+  SAME = HALF = 6;
+  NEG = -(R - 3) * 2;
+var
+  a: array[N..R] of integer;
+begin
+  writeln(TWO_PI);
+  writeln(N);
+  writeln(AREA);
+  writeln(BIG);
+  writeln(HALF);
+  writeln(ON);
+  writeln(LETTER);
+  writeln(GREETING);
+  writeln(SAME);
+  writeln(NEG);
+  a[N] := 5;
+  writeln(a[-10]);
+end.
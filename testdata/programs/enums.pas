@@ -0,0 +1,60 @@
+program enums;
+type
+  color = (red, green, blue);
+  digit = 0..9;
+  letter = 'a'..'z';
+  warm = red..green;
+var
+  paint: color;
+  d: digit;
+  l: letter;
+  w: warm;
+  i, total: integer;
+  day: (mon, tue, wed);
+  digits: array[1..3] of digit;
+procedure show(x: color);
+begin
+  case x of
+    red: writeln('red');
+    green: writeln('green');
+  else
+    writeln('blue');
+  end;
+end
+begin
+  paint := green;
+  writeln(ord(paint));
+  writeln(ord(succ(paint)));
+  writeln(ord(pred(paint)));
+  writeln(paint < blue);
+  writeln(paint = red);
+  for paint := red to blue do
+  begin
+    show(paint);
+  end;
+  for paint := blue downto green do
+  begin
+    writeln(ord(paint));
+  end;
+  d := 7;
+  total := 0;
+  for d := 0 to 9 do
+  begin
+    total := total + d;
+  end;
+  writeln(total);
+  l := 'q';
+  writeln(ord(l));
+  writeln(succ(l));
+  w := succ(red);
+  writeln(ord(w));
+  day := tue;
+  writeln(ord(day));
+  writeln(ord(true));
+  writeln(succ(41));
+  for i := 1 to 3 do
+  begin
+    digits[i] := i * 3;
+  end;
+  writeln(digits[1] + digits[2] + digits[3]);
+end.
@@ -1,9 +1,19 @@
 program p;
-var i, count: int;
+var i, count: integer;
 begin
   count := 0;
   for i := 1 to 10 do
   begin
     count := count + 1;
-  end
+  end;
+  writeln(count);
+  for i := 3 downto 1 do
+  begin
+    writeln(i);
+  end;
+  for i := 1 to 0 do
+  begin
+    count := count + 1;
+  end;
+  writeln(count);
 end.
@@ -0,0 +1,17 @@
+program p;
+const LIMIT = 3;
+var n, total: integer;
+function sum(a, b: integer): integer;
+const ONE = 1;
+      OK = true;
+var n: integer;
+begin
+  n := a + b + ONE;
+  if OK then
+    sum := n + LIMIT;
+end
+begin
+  n := 5;
+  total := sum(n, 2);
+  writeln(total);
+end.
@@ -3,8 +3,38 @@ package programs
 import _ "embed"
 
 var (
+	//go:embed arrays.pas
+	Arrays string
 	//go:embed assignments.pas
 	Assignments string
+	//go:embed case.pas
+	Case string
+	//go:embed const-expressions.pas
+	ConstExpressions string
 	//go:embed constants.pas
 	Constants string
+	//go:embed enums.pas
+	Enums string
+	//go:embed for.pas
+	For string
+	//go:embed if.pas
+	If string
+	//go:embed locals.pas
+	Locals string
+	//go:embed math.pas
+	Math string
+	//go:embed procedures.pas
+	Procedures string
+	//go:embed records.pas
+	Records string
+	//go:embed recursion.pas
+	Recursion string
+	//go:embed repeat.pas
+	Repeat string
+	//go:embed strings.pas
+	Strings string
+	//go:embed var-params.pas
+	VarParams string
+	//go:embed while.pas
+	While string
 )
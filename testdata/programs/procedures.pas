@@ -0,0 +1,27 @@
+program procedures;
+var
+  total: integer;
+procedure add(x: integer);
+begin
+  total := total + x;
+end
+procedure report;
+begin
+  writeln(total);
+end
+function twice(x: integer): integer;
+procedure log(v: integer);
+begin
+  writeln(v);
+end
+begin
+  log(x);
+  twice := x * 2;
+end
+begin
+  total := 0;
+  add(1);
+  add(twice(2));
+  report;
+  report();
+end.
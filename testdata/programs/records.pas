@@ -0,0 +1,70 @@
+program records;
+type
+  point = record
+    x, y: real;
+  end;
+  edge = record
+    id: integer;
+    ends: array[1..2] of point;
+    visible: boolean
+  end;
+var
+  p: point;
+  s: edge;
+  shape: array[1..3] of edge;
+  counter: record
+    title: string;
+    total: integer;
+  end;
+  i: integer;
+procedure move(var q: point; dx, dy: real);
+begin
+  q.x := q.x + dx;
+  q.y := q.y + dy;
+end
+function length2(var q: point): real;
+begin
+  length2 := q.x * q.x + q.y * q.y;
+end
+procedure inc(var x: integer);
+begin
+  x := x + 1;
+end
+begin
+  p.x := 3;
+  p.y := 4;
+  writeln(length2(p));
+  move(p, 1, -1);
+  writeln(p.x);
+  writeln(p.y);
+  s.id := 7;
+  s.ends[2].x := 2.5;
+  move(s.ends[2], 1, 1);
+  writeln(s.ends[2].x);
+  writeln(s.ends[1].y);
+  writeln(s.visible);
+  for i := 1 to 3 do
+    with shape[i] do
+    begin
+      id := i * 100;
+      visible := i mod 2 = 1;
+      with ends[1] do
+      begin
+        x := id;
+        y := x / 2;
+      end;
+    end;
+  writeln(shape[2].id);
+  writeln(shape[3].ends[1].y);
+  writeln(shape[3].visible);
+  with counter, p do
+  begin
+    title := 'points';
+    total := 0;
+    inc(total);
+    x := total;
+  end;
+  writeln(counter.title);
+  writeln(counter.total);
+  writeln(p.x);
+end.
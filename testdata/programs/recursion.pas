@@ -0,0 +1,13 @@
+program p;
+var n: integer;
+function factorial(n: integer): integer;
+begin
+  factorial := 1;
+  if n > 1 then
+    factorial := n * factorial(n - 1);
+end
+begin
+  n := 5;
+  writeln(factorial(n));
+  writeln(-factorial(3) + 10 - 2 - 1);
+end.
@@ -1,11 +1,19 @@
 program p;
-var i, count: int;
+var i, count: integer;
 begin
   count := 0;
+  i := 1;
   repeat
   begin
     count := count + 1;
     i := i * 2;
   end
+  until i >= 100;
+  writeln(count);
+  repeat
+  begin
+    count := count + 1;
+  end
   until true;
+  writeln(count);
 end.
@@ -0,0 +1,24 @@
+program strings;
+const
+  greeting = 'Hello';
+  bang = '!';
+var
+  s: string;
+  ch: char;
+function first(x: string): char;
+begin
+  first := 'F';
+end
+begin
+  writeln(greeting);
+  writeln('it''s');
+  s := 'Hello';
+  writeln(s);
+  ch := bang;
+  writeln(ch);
+  s := ch;
+  writeln(s);
+  writeln(first('abc'));
+  writeln('a' < 'b');
+  writeln('');
+end.
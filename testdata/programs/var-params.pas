@@ -0,0 +1,67 @@
+program varparams;
+var
+  a, b: integer;
+  x: real;
+procedure swap(var l: integer; var r: integer);
+var
+  t: integer;
+begin
+  t := l;
+  l := r;
+  r := t;
+end
+procedure addTo(var target: integer; v: integer);
+begin
+  target := target + v;
+  a := a * 10;
+end
+procedure scale(var v: real);
+begin
+  v := v * 2;
+end
+procedure twice(var v: integer);
+begin
+  addTo(v, v);
+end
+function sum(n: integer): integer;
+var
+  acc: integer;
+begin
+  acc := 0;
+  while n > 0 do
+  begin
+    addTo(acc, n);
+    n := n - 1;
+  end;
+  if n = 0 then
+    twice(acc);
+  sum := acc;
+end
+function fact(n: integer): integer;
+var
+  res: integer;
+begin
+  res := 1;
+  if n > 1 then
+  begin
+    res := fact(n - 1);
+    addTo(res, res * (n - 1));
+  end;
+  fact := res;
+end
+begin
+  a := 1;
+  b := 2;
+  swap(a, b);
+  writeln(a);
+  writeln(b);
+  addTo(a, 5);
+  writeln(a);
+  x := 1.5;
+  scale(x);
+  writeln(x);
+  twice(b);
+  writeln(b);
+  writeln(sum(3));
+  writeln(fact(5));
+end.